@@ -38,21 +38,64 @@ func main() {
 		Handler: engine,
 	}
 
-	db, err := config.NewDbConnection()
+	backend, err := config.NewBackend()
 	if err != nil {
-		glog.Fatalf("couldn't connect to db", err.Error())
-		panic(err)
+		glog.Fatalf("invalid repository configuration: %s", err)
+	}
+	var db *sqlx.DB
+	var deckRepo repo.DeckRepo
+	var gameRepo repo.GameRepo
+	if backend == config.MemoryBackend {
+		glog.Warningf("decks and games are kept in memory, they are lost when the server stops")
+		deckRepo, gameRepo = repo.NewMemoryRepos()
+	} else {
+		db, err = config.NewDbConnection()
+		if err != nil {
+			glog.Fatalf("couldn't connect to db", err.Error())
+			panic(err)
+		}
+		deckRepo, gameRepo = repo.NewDeckRepo(db), repo.NewGameRepo(db)
 	}
 
-	deckRepo := repo.NewDeckRepo(db)
-	deckService := service.NewDeckService(deckRepo)
-	deckHandler := handler.NewDeckHandler(deckService)
+	deckService := service.NewDeckService(deckRepo, service.NewCryptoSource())
+	undoDepth, err := config.NewUndoDepth(service.DefaultUndoDepth)
+	if err != nil {
+		glog.Fatalf("invalid undo configuration: %s", err)
+	}
+	deckService = deckService.WithUndoDepth(undoDepth)
+	// the games deal from deckService, the deck endpoints can't reach their decks
+	deckHandler := handler.NewDeckHandler(deckService.WithoutGameDecks(gameRepo))
 	deckHandler.InitRoutes(engine)
 
-	listenAndServe(server, db)
+	blackjackService := service.NewBlackjackService(gameRepo, deckService)
+	blackjackHandler := handler.NewBlackjackHandler(blackjackService)
+	blackjackHandler.InitRoutes(engine)
+
+	holdemService := service.NewHoldemService(gameRepo, deckService)
+	holdemHandler := handler.NewHoldemHandler(holdemService)
+	holdemHandler.InitRoutes(engine)
+
+	warService := service.NewWarService(gameRepo, deckService)
+	warHandler := handler.NewWarHandler(warService)
+	warHandler.InitRoutes(engine)
+
+	klondikeService := service.NewKlondikeService(gameRepo, deckService)
+	klondikeHandler := handler.NewKlondikeHandler(klondikeService)
+	klondikeHandler.InitRoutes(engine)
+
+	reaperConf, err := config.NewReaperConfig()
+	if err != nil {
+		glog.Fatalf("invalid reaper configuration: %s", err)
+	}
+	var reaper *service.DeckReaper
+	if reaperConf.Interval > 0 {
+		reaper = service.NewDeckReaper(deckRepo, reaperConf.Interval, reaperConf.MaxIdle, reaperConf.BatchSize)
+	}
+
+	listenAndServe(server, db, reaper)
 }
 
-func listenAndServe(server *http.Server, db *sqlx.DB) {
+func listenAndServe(server *http.Server, db *sqlx.DB, reaper *service.DeckReaper) {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 
 	glog.Infoln("initializing server")
@@ -63,6 +106,15 @@ func listenAndServe(server *http.Server, db *sqlx.DB) {
 		}
 	}()
 
+	reaperCtx, stopReaper := context.WithCancel(context.Background())
+	reaperDone := make(chan struct{})
+	go func() {
+		defer close(reaperDone)
+		if reaper != nil {
+			reaper.Run(reaperCtx)
+		}
+	}()
+
 	<-ctx.Done()
 	stop()
 	glog.Infof("received signal, closing")
@@ -72,6 +124,12 @@ func listenAndServe(server *http.Server, db *sqlx.DB) {
 	if err := server.Shutdown(ctx); err != nil {
 		glog.Fatalf("server forced to shutdown: %s", err)
 	}
+	// the reaper finishes its current batch before the database is closed under it
+	stopReaper()
+	<-reaperDone
+	if db == nil {
+		return
+	}
 	if err := db.Close(); err != nil {
 		glog.Fatalf("couldn't close db: %s", err)
 	}
@@ -0,0 +1 @@
+alter table decks drop column if exists version;
@@ -0,0 +1 @@
+alter table decks add column if not exists version int default 0 not null;
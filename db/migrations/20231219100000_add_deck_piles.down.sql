@@ -0,0 +1,3 @@
+alter table decks
+    drop column if exists drawn,
+    drop column if exists discarded;
@@ -0,0 +1,3 @@
+alter table decks
+    add column if not exists drawn text[] default '{}' not null,
+    add column if not exists discarded text[] default '{}' not null;
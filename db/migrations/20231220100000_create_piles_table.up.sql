@@ -0,0 +1,8 @@
+create table if not exists piles (
+    deck_id varchar(50) not null references decks (id) on delete cascade,
+    name varchar(50) not null,
+    cards text[] default '{}' not null,
+    created_at timestamp default current_timestamp not null,
+    updated_at timestamp default current_timestamp not null,
+    primary key (deck_id, name)
+)
@@ -0,0 +1,4 @@
+alter table decks
+    drop column if exists decks_count,
+    drop column if exists cut_card,
+    drop column if exists size;
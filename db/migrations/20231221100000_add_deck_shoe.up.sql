@@ -0,0 +1,6 @@
+alter table decks
+    add column if not exists decks_count int default 1 not null,
+    add column if not exists cut_card int default 0 not null,
+    add column if not exists size int default 0 not null;
+
+update decks set size = remaining + cardinality(drawn) + cardinality(discarded) where size = 0;
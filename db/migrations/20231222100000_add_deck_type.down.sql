@@ -0,0 +1,3 @@
+alter table decks
+    drop column if exists deck_type,
+    drop column if exists jokers;
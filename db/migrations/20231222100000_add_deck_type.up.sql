@@ -0,0 +1,3 @@
+alter table decks
+    add column if not exists deck_type varchar(20) default 'standard' not null,
+    add column if not exists jokers bool default false not null;
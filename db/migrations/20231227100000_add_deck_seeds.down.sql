@@ -0,0 +1,4 @@
+alter table decks
+    drop column if exists server_seed,
+    drop column if exists client_seed,
+    drop column if exists initial_cards;
@@ -0,0 +1,4 @@
+alter table decks
+    add column if not exists server_seed varchar(64) default '' not null,
+    add column if not exists client_seed varchar(64) default '' not null,
+    add column if not exists initial_cards text[] default '{}' not null;
@@ -0,0 +1 @@
+alter table decks add column if not exists seeded bool default false not null;
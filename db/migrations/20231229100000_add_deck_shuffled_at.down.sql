@@ -0,0 +1 @@
+alter table decks drop column if exists shuffled_at;
@@ -0,0 +1,3 @@
+alter table decks add column if not exists shuffled_at timestamp;
+
+update decks set shuffled_at = created_at where shuffled;
@@ -0,0 +1,11 @@
+create table if not exists games (
+    id varchar(50) primary key,
+    game_type varchar(20) not null,
+    deck_id varchar(50) not null references decks (id) on delete cascade,
+    state jsonb not null,
+    version int default 0 not null,
+    created_at timestamp default current_timestamp not null,
+    updated_at timestamp default current_timestamp not null
+);
+
+create index if not exists games_deck_id_idx on games (deck_id);
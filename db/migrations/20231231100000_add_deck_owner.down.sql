@@ -0,0 +1,5 @@
+drop index if exists decks_owner_created_at_id_idx;
+drop index if exists decks_updated_at_idx;
+drop index if exists decks_created_at_id_idx;
+
+alter table decks drop column if exists owner;
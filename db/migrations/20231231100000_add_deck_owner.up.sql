@@ -0,0 +1,5 @@
+alter table decks add column if not exists owner varchar(100) default '' not null;
+
+create index if not exists decks_created_at_id_idx on decks (created_at, id);
+create index if not exists decks_updated_at_idx on decks (updated_at);
+create index if not exists decks_owner_created_at_id_idx on decks (owner, created_at, id);
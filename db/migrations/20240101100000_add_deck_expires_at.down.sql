@@ -0,0 +1,4 @@
+drop index if exists games_deck_id_updated_at_idx;
+drop index if exists decks_expires_at_idx;
+
+alter table decks drop column if exists expires_at;
@@ -0,0 +1,4 @@
+alter table decks add column if not exists expires_at timestamp;
+
+create index if not exists decks_expires_at_idx on decks (expires_at) where expires_at is not null;
+create index if not exists games_deck_id_updated_at_idx on games (deck_id, updated_at);
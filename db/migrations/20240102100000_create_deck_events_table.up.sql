@@ -0,0 +1,26 @@
+create table if not exists deck_events (
+    deck_id varchar(50) not null references decks (id) on delete cascade,
+    number int not null,
+    event_type varchar(20) not null,
+    actor varchar(100) default '' not null,
+    payload jsonb default '{}' not null,
+    changes jsonb default '{}' not null,
+    created_at timestamp default current_timestamp not null,
+    primary key (deck_id, number)
+);
+
+-- the history of existing decks starts with their current state
+insert into deck_events (deck_id, number, event_type, actor, payload, changes, created_at)
+select d.id, d.version + 1, 'import', '', '{}',
+       jsonb_strip_nulls(jsonb_build_object(
+           'cards', to_jsonb(d.cards),
+           'drawn', to_jsonb(d.drawn),
+           'discarded', to_jsonb(d.discarded),
+           'remaining', d.remaining,
+           'size', d.size,
+           'shuffled', d.shuffled,
+           'shuffled_at', to_char(d.shuffled_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+           'piles', (select jsonb_object_agg(p.name, to_jsonb(p.cards)) from piles p where p.deck_id = d.id))),
+       d.updated_at
+from decks d
+on conflict do nothing;
@@ -0,0 +1,4 @@
+drop table if exists deck_snapshots;
+
+drop index if exists decks_parent_id_idx;
+alter table decks drop column if exists parent_id;
@@ -0,0 +1,13 @@
+-- parent_id isn't a foreign key, the lineage of a clone outlives its parent
+alter table decks add column if not exists parent_id varchar(50);
+create index if not exists decks_parent_id_idx on decks (parent_id) where parent_id is not null;
+
+create table if not exists deck_snapshots (
+    deck_id varchar(50) not null references decks (id) on delete cascade,
+    name varchar(50) not null,
+    version int not null,
+    actor varchar(100) default '' not null,
+    state jsonb default '{}' not null,
+    created_at timestamp default current_timestamp not null,
+    primary key (deck_id, name)
+);
@@ -0,0 +1,302 @@
+package blackjack
+
+import (
+	"errors"
+	"fmt"
+	"github.com/deck/internal/app/repo"
+	"strconv"
+)
+
+type Status string
+
+const (
+	// Insurance waits for the player to take or decline insurance, the dealer shows an ace
+	Insurance  Status = "insurance"
+	PlayerTurn Status = "player_turn"
+	Settled    Status = "settled"
+)
+
+type Action string
+
+const (
+	Hit              Action = "hit"
+	Stand            Action = "stand"
+	Double           Action = "double"
+	Split            Action = "split"
+	TakeInsurance    Action = "insurance"
+	DeclineInsurance Action = "no_insurance"
+)
+
+type Outcome string
+
+const (
+	Win       Outcome = "win"
+	Lose      Outcome = "lose"
+	Push      Outcome = "push"
+	Blackjack Outcome = "blackjack"
+)
+
+const (
+	// MaxHands is the most hands a player can have by splitting
+	MaxHands = 4
+	// dealerStands is the total the dealer stands on, a soft one only when the dealer doesn't hit soft 17
+	dealerStands = 17
+)
+
+var ErrGameOver = errors.New("the game is already settled")
+
+// Rules the dealer plays by. With DealerHitsSoft17 (H17) the dealer draws to a soft 17, otherwise stands on it (S17).
+type Rules struct {
+	DealerHitsSoft17 bool `json:"dealer_hits_soft17"`
+}
+
+// Hand is one hand of the player, there is more than one after splitting. Payout is the net amount won, negative when
+// the hand lost.
+type Hand struct {
+	Cards     []string `json:"cards"`
+	Bet       int      `json:"bet"`
+	Doubled   bool     `json:"doubled"`
+	FromSplit bool     `json:"from_split"`
+	Done      bool     `json:"done"`
+	Outcome   Outcome  `json:"outcome,omitempty"`
+	Payout    int      `json:"payout"`
+}
+
+// Game is a single round of blackjack between the dealer and one player. The dealer's second card is the hole card,
+// it stays hidden until the game is settled.
+type Game struct {
+	Rules           Rules    `json:"rules"`
+	Status          Status   `json:"status"`
+	Hands           []Hand   `json:"hands"`
+	ActiveHand      int      `json:"active_hand"`
+	Dealer          []string `json:"dealer"`
+	Insurance       int      `json:"insurance"`
+	InsurancePayout int      `json:"insurance_payout"`
+	Net             int      `json:"net"`
+}
+
+// Draw deals count cards from the top of the shoe
+type Draw func(count int) ([]string, error)
+
+// Deal starts a game: two cards to the player and the dealer, alternating. Blackjacks settle the game right away,
+// unless the dealer shows an ace and insurance is offered first.
+func Deal(rules Rules, bet int, draw Draw) (*Game, error) {
+	if bet <= 0 {
+		return nil, fmt.Errorf("bet must be positive")
+	}
+	cards, err := draw(4)
+	if err != nil {
+		return nil, err
+	}
+	g := &Game{
+		Rules:  rules,
+		Hands:  []Hand{{Cards: []string{cards[0], cards[2]}, Bet: bet}},
+		Dealer: []string{cards[1], cards[3]},
+	}
+	if cardValue(g.Dealer[0]) == 1 {
+		g.Status = Insurance
+		return g, nil
+	}
+	g.checkBlackjacks()
+	return g, nil
+}
+
+// Play applies the player's action to the active hand. Once every hand is done the dealer plays and the game settles.
+func (g *Game) Play(action Action, draw Draw) error {
+	if !g.allowed(action) {
+		if g.Status == Settled {
+			return ErrGameOver
+		}
+		return fmt.Errorf("%s isn't allowed now", action)
+	}
+	hand := &g.Hands[g.ActiveHand]
+	switch action {
+	case TakeInsurance:
+		g.Insurance = g.Hands[0].Bet / 2
+		g.checkBlackjacks()
+		return nil
+	case DeclineInsurance:
+		g.checkBlackjacks()
+		return nil
+	case Hit:
+		cards, err := draw(1)
+		if err != nil {
+			return err
+		}
+		hand.Cards = append(hand.Cards, cards...)
+	case Stand:
+		hand.Done = true
+	case Double:
+		cards, err := draw(1)
+		if err != nil {
+			return err
+		}
+		hand.Cards = append(hand.Cards, cards...)
+		hand.Bet *= 2
+		hand.Doubled = true
+		hand.Done = true
+	case Split:
+		cards, err := draw(2)
+		if err != nil {
+			return err
+		}
+		aces := cardValue(hand.Cards[0]) == 1
+		first := Hand{Cards: []string{hand.Cards[0], cards[0]}, Bet: hand.Bet, FromSplit: true, Done: aces}
+		second := Hand{Cards: []string{hand.Cards[1], cards[1]}, Bet: hand.Bet, FromSplit: true, Done: aces}
+		hands := append([]Hand{}, g.Hands[:g.ActiveHand]...)
+		hands = append(hands, first, second)
+		g.Hands = append(hands, g.Hands[g.ActiveHand+1:]...)
+	}
+	return g.advance(draw)
+}
+
+// Actions lists what the player can do now
+func (g *Game) Actions() []Action {
+	var actions []Action
+	for _, a := range []Action{Hit, Stand, Double, Split, TakeInsurance, DeclineInsurance} {
+		if g.allowed(a) {
+			actions = append(actions, a)
+		}
+	}
+	return actions
+}
+
+func (g *Game) allowed(action Action) bool {
+	switch g.Status {
+	case Insurance:
+		return action == TakeInsurance || action == DeclineInsurance
+	case PlayerTurn:
+		hand := g.Hands[g.ActiveHand]
+		switch action {
+		case Hit, Stand:
+			return true
+		case Double:
+			return len(hand.Cards) == 2
+		case Split:
+			return len(hand.Cards) == 2 && len(g.Hands) < MaxHands && cardValue(hand.Cards[0]) == cardValue(hand.Cards[1])
+		}
+	}
+	return false
+}
+
+// checkBlackjacks is the dealer's peek: a blackjack of the dealer or of the player ends the game before anybody draws
+func (g *Game) checkBlackjacks() {
+	if isBlackjack(g.Dealer) || g.Hands[0].isBlackjack() {
+		g.Hands[0].Done = true
+		g.settle()
+		return
+	}
+	g.Status = PlayerTurn
+}
+
+// advance moves on to the next hand that isn't done, a hand reaching 21 or more is done by itself
+func (g *Game) advance(draw Draw) error {
+	for ; g.ActiveHand < len(g.Hands); g.ActiveHand++ {
+		hand := &g.Hands[g.ActiveHand]
+		if total, _ := Value(hand.Cards); total >= 21 {
+			hand.Done = true
+		}
+		if !hand.Done {
+			return nil
+		}
+	}
+	g.ActiveHand = len(g.Hands) - 1
+	return g.playDealer(draw)
+}
+
+// playDealer draws for the dealer by the rules, unless every hand is busted already
+func (g *Game) playDealer(draw Draw) error {
+	busted := true
+	for _, h := range g.Hands {
+		if total, _ := Value(h.Cards); total <= 21 {
+			busted = false
+		}
+	}
+	for !busted {
+		total, soft := Value(g.Dealer)
+		if total > dealerStands || (total == dealerStands && (!soft || !g.Rules.DealerHitsSoft17)) {
+			break
+		}
+		cards, err := draw(1)
+		if err != nil {
+			return err
+		}
+		g.Dealer = append(g.Dealer, cards...)
+	}
+	g.settle()
+	return nil
+}
+
+// settle pays out every hand and the insurance. A blackjack pays 3:2, rounded down.
+func (g *Game) settle() {
+	dealerTotal, _ := Value(g.Dealer)
+	dealerBlackjack := isBlackjack(g.Dealer)
+	g.Net = 0
+	for i := range g.Hands {
+		h := &g.Hands[i]
+		total, _ := Value(h.Cards)
+		switch {
+		case h.isBlackjack() && dealerBlackjack:
+			h.Outcome, h.Payout = Push, 0
+		case dealerBlackjack:
+			h.Outcome, h.Payout = Lose, -h.Bet
+		case h.isBlackjack():
+			h.Outcome, h.Payout = Blackjack, h.Bet*3/2
+		case total > 21:
+			h.Outcome, h.Payout = Lose, -h.Bet
+		case dealerTotal > 21 || total > dealerTotal:
+			h.Outcome, h.Payout = Win, h.Bet
+		case total < dealerTotal:
+			h.Outcome, h.Payout = Lose, -h.Bet
+		default:
+			h.Outcome, h.Payout = Push, 0
+		}
+		g.Net += h.Payout
+	}
+	if g.Insurance > 0 {
+		g.InsurancePayout = -g.Insurance
+		if dealerBlackjack {
+			g.InsurancePayout = 2 * g.Insurance
+		}
+		g.Net += g.InsurancePayout
+	}
+	g.Status = Settled
+}
+
+func (h Hand) isBlackjack() bool {
+	return !h.FromSplit && isBlackjack(h.Cards)
+}
+
+func isBlackjack(cards []string) bool {
+	total, _ := Value(cards)
+	return len(cards) == 2 && total == 21
+}
+
+// Value is the best total of the cards. It's soft when an ace counts as 11.
+func Value(cards []string) (int, bool) {
+	total, aces := 0, 0
+	for _, c := range cards {
+		v := cardValue(c)
+		if v == 1 {
+			aces++
+		}
+		total += v
+	}
+	if aces > 0 && total+10 <= 21 {
+		return total + 10, true
+	}
+	return total, false
+}
+
+// cardValue counts an ace as 1 and the pictures as 10
+func cardValue(code string) int {
+	switch value := repo.CardCode(code[:len(code)-1]); value {
+	case repo.Ace:
+		return 1
+	case repo.Ten, repo.Jack, repo.Queen, repo.King:
+		return 10
+	default:
+		v, _ := strconv.Atoi(string(value))
+		return v
+	}
+}
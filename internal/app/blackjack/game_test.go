@@ -0,0 +1,212 @@
+package blackjack
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// shoe deals the given cards in order, the first four are the player, dealer, player and dealer hole card
+func shoe(codes string) Draw {
+	cards := strings.Split(codes, ",")
+	return func(count int) ([]string, error) {
+		if count > len(cards) {
+			return nil, errors.New("shoe is empty")
+		}
+		drawn := cards[:count]
+		cards = cards[count:]
+		return drawn, nil
+	}
+}
+
+func TestValue(t *testing.T) {
+	tests := []struct {
+		cards string
+		total int
+		soft  bool
+	}{
+		{"AS,KD", 21, true},
+		{"AS,6D", 17, true},
+		{"AS,6D,10C", 17, false},
+		{"AS,AD,9C", 21, true},
+		{"AS,AD,AC,AH", 14, true},
+		{"KS,QD,2C", 22, false},
+		{"5S,4D", 9, false},
+	}
+	for _, test := range tests {
+		total, soft := Value(strings.Split(test.cards, ","))
+		assert.Equal(t, test.total, total, test.cards)
+		assert.Equal(t, test.soft, soft, test.cards)
+	}
+}
+
+func TestDeal(t *testing.T) {
+	// Test case: a regular deal waits for the player
+	g, err := Deal(Rules{}, 10, shoe("10S,9D,7C,8H"))
+	assert.NoError(t, err)
+	assert.Equal(t, PlayerTurn, g.Status)
+	assert.Equal(t, []string{"10S", "7C"}, g.Hands[0].Cards)
+	assert.Equal(t, []string{"9D", "8H"}, g.Dealer)
+	assert.Equal(t, []Action{Hit, Stand, Double}, g.Actions())
+
+	// Test case: the player's blackjack pays 3:2 right away
+	g, err = Deal(Rules{}, 10, shoe("AS,9D,KC,8H"))
+	assert.NoError(t, err)
+	assert.Equal(t, Settled, g.Status)
+	assert.Equal(t, Blackjack, g.Hands[0].Outcome)
+	assert.Equal(t, 15, g.Net)
+	assert.Empty(t, g.Actions())
+
+	// Test case: the dealer's blackjack behind a ten beats the player
+	g, err = Deal(Rules{}, 10, shoe("10S,KD,7C,AH"))
+	assert.NoError(t, err)
+	assert.Equal(t, Settled, g.Status)
+	assert.Equal(t, Lose, g.Hands[0].Outcome)
+	assert.Equal(t, -10, g.Net)
+
+	// Test case: both have blackjack
+	g, err = Deal(Rules{}, 10, shoe("AS,KD,QC,AH"))
+	assert.NoError(t, err)
+	assert.Equal(t, Push, g.Hands[0].Outcome)
+	assert.Equal(t, 0, g.Net)
+
+	// Test case: invalid bet and an empty shoe
+	_, err = Deal(Rules{}, 0, shoe("AS,KD,QC,AH"))
+	assert.EqualError(t, err, "bet must be positive")
+	_, err = Deal(Rules{}, 10, shoe("AS,KD"))
+	assert.EqualError(t, err, "shoe is empty")
+}
+
+func TestInsurance(t *testing.T) {
+	// Test case: insurance against the dealer's blackjack pays 2:1 and covers the bet
+	g, err := Deal(Rules{}, 10, shoe("10S,AD,7C,KH"))
+	assert.NoError(t, err)
+	assert.Equal(t, Insurance, g.Status)
+	assert.Equal(t, []Action{TakeInsurance, DeclineInsurance}, g.Actions())
+	assert.EqualError(t, g.Play(Hit, nil), "hit isn't allowed now")
+
+	assert.Nil(t, g.Play(TakeInsurance, nil))
+	assert.Equal(t, Settled, g.Status)
+	assert.Equal(t, 5, g.Insurance)
+	assert.Equal(t, 10, g.InsurancePayout)
+	assert.Equal(t, 0, g.Net)
+
+	// Test case: insurance is lost when the dealer has no blackjack
+	draw := shoe("10S,AD,9C,5H,KC,2D")
+	g, err = Deal(Rules{}, 10, draw)
+	assert.NoError(t, err)
+	assert.Nil(t, g.Play(TakeInsurance, draw))
+	assert.Equal(t, PlayerTurn, g.Status)
+	assert.Nil(t, g.Play(Stand, draw))
+	// dealer: A + 5 is a soft 16, the K makes it a hard 16 and the 2 makes 18
+	assert.Equal(t, []string{"AD", "5H", "KC", "2D"}, g.Dealer)
+	assert.Equal(t, Win, g.Hands[0].Outcome)
+	assert.Equal(t, -5, g.InsurancePayout)
+	assert.Equal(t, 5, g.Net)
+	assert.Equal(t, ErrGameOver, g.Play(Stand, draw))
+
+	// Test case: declining insurance
+	draw = shoe("10S,AD,9C,6H")
+	g, err = Deal(Rules{}, 10, draw)
+	assert.NoError(t, err)
+	assert.Nil(t, g.Play(DeclineInsurance, draw))
+	assert.Nil(t, g.Play(Stand, draw))
+	// dealer stands on soft 17
+	assert.Equal(t, Settled, g.Status)
+	assert.Equal(t, Win, g.Hands[0].Outcome)
+	assert.Equal(t, 10, g.Net)
+}
+
+func TestPlayerActions(t *testing.T) {
+	// Test case: hitting to a bust loses without the dealer drawing
+	draw := shoe("10S,9D,6C,8H,KC")
+	g, _ := Deal(Rules{}, 10, draw)
+	assert.Nil(t, g.Play(Hit, draw))
+	assert.Equal(t, Settled, g.Status)
+	assert.Equal(t, Lose, g.Hands[0].Outcome)
+	assert.Equal(t, []string{"9D", "8H"}, g.Dealer)
+
+	// Test case: doubling takes one card and doubles the bet
+	draw = shoe("6S,9D,5C,7H,KC,5D")
+	g, _ = Deal(Rules{}, 10, draw)
+	assert.Nil(t, g.Play(Double, draw))
+	// player 21, dealer 16 draws 5 to 21
+	assert.Equal(t, 20, g.Hands[0].Bet)
+	assert.True(t, g.Hands[0].Doubled)
+	assert.Equal(t, Push, g.Hands[0].Outcome)
+
+	// Test case: doubling after a hit isn't allowed
+	draw = shoe("2S,9D,3C,7H,4C")
+	g, _ = Deal(Rules{}, 10, draw)
+	assert.Nil(t, g.Play(Hit, draw))
+	assert.EqualError(t, g.Play(Double, draw), "double isn't allowed now")
+
+	// Test case: the dealer busts
+	draw = shoe("10S,10D,8C,6H,KC")
+	g, _ = Deal(Rules{}, 10, draw)
+	assert.Nil(t, g.Play(Stand, draw))
+	assert.Equal(t, Win, g.Hands[0].Outcome)
+	assert.Equal(t, 10, g.Net)
+}
+
+func TestSplit(t *testing.T) {
+	// Test case: both split hands are played one after the other
+	draw := shoe("8S,10D,8C,7H,3D,KS,10C,5H")
+	g, _ := Deal(Rules{}, 10, draw)
+	assert.Contains(t, g.Actions(), Split)
+	assert.Nil(t, g.Play(Split, draw))
+	assert.Len(t, g.Hands, 2)
+	assert.Equal(t, []string{"8S", "3D"}, g.Hands[0].Cards)
+	assert.Equal(t, []string{"8C", "KS"}, g.Hands[1].Cards)
+	assert.Equal(t, 0, g.ActiveHand)
+
+	assert.Nil(t, g.Play(Hit, draw))
+	// 8 + 3 + 10 is 21, so the first hand is done
+	assert.Equal(t, 1, g.ActiveHand)
+	assert.Nil(t, g.Play(Stand, draw))
+	assert.Equal(t, Settled, g.Status)
+	// dealer stands on 17 with the 5 left in the shoe
+	assert.Equal(t, Win, g.Hands[0].Outcome)
+	assert.Equal(t, Win, g.Hands[1].Outcome)
+	assert.Equal(t, 20, g.Net)
+
+	// Test case: split aces get one card each, and 21 isn't a blackjack
+	draw = shoe("AS,9D,AC,8H,KD,5C")
+	g, _ = Deal(Rules{}, 10, draw)
+	assert.Nil(t, g.Play(Split, draw))
+	assert.Equal(t, Settled, g.Status)
+	assert.Equal(t, Win, g.Hands[0].Outcome)
+	assert.Equal(t, 10, g.Hands[0].Payout)
+	assert.Equal(t, Lose, g.Hands[1].Outcome)
+
+	// Test case: cards of different value can't be split, at most MaxHands hands
+	draw = shoe("8S,10D,9C,7H")
+	g, _ = Deal(Rules{}, 10, draw)
+	assert.EqualError(t, g.Play(Split, draw), "split isn't allowed now")
+	draw = shoe("8S,10D,8C,7H,8D,8H,8S,8C,2S,3S")
+	g, _ = Deal(Rules{}, 10, draw)
+	assert.Nil(t, g.Play(Split, draw))
+	assert.Nil(t, g.Play(Split, draw))
+	assert.Nil(t, g.Play(Split, draw))
+	assert.Len(t, g.Hands, MaxHands)
+	assert.NotContains(t, g.Actions(), Split)
+}
+
+func TestDealerSoft17(t *testing.T) {
+	// Test case: S17, the dealer stands on a soft 17
+	draw := shoe("10S,AD,8C,6H,5C")
+	g, _ := Deal(Rules{DealerHitsSoft17: false}, 10, draw)
+	assert.Nil(t, g.Play(DeclineInsurance, draw))
+	assert.Nil(t, g.Play(Stand, draw))
+	assert.Equal(t, []string{"AD", "6H"}, g.Dealer)
+	assert.Equal(t, Win, g.Hands[0].Outcome)
+
+	// Test case: H17, the dealer draws to a soft 17
+	draw = shoe("10S,AD,8C,6H,3C")
+	g, _ = Deal(Rules{DealerHitsSoft17: true}, 10, draw)
+	assert.Nil(t, g.Play(DeclineInsurance, draw))
+	assert.Nil(t, g.Play(Stand, draw))
+	assert.Equal(t, []string{"AD", "6H", "3C"}, g.Dealer)
+	assert.Equal(t, Lose, g.Hands[0].Outcome)
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultReaperInterval  = 10 * time.Minute
+	defaultReaperBatchSize = 500
+)
+
+// Reaper configures the deletion of expired decks. A zero Interval turns it off, a zero MaxIdle keeps idle decks
+// until their ttl passes.
+type Reaper struct {
+	Interval  time.Duration
+	MaxIdle   time.Duration
+	BatchSize int
+}
+
+// NewReaperConfig reads REAPER_INTERVAL and DECK_MAX_IDLE as durations like 10m or 720h, and REAPER_BATCH_SIZE
+func NewReaperConfig() (Reaper, error) {
+	conf := Reaper{Interval: defaultReaperInterval, BatchSize: defaultReaperBatchSize}
+	var err error
+	if interval := os.Getenv("REAPER_INTERVAL"); len(interval) > 0 {
+		if conf.Interval, err = time.ParseDuration(interval); err != nil || conf.Interval < 0 {
+			return conf, fmt.Errorf("REAPER_INTERVAL must be a positive duration, got %s", interval)
+		}
+	}
+	if maxIdle := os.Getenv("DECK_MAX_IDLE"); len(maxIdle) > 0 {
+		if conf.MaxIdle, err = time.ParseDuration(maxIdle); err != nil || conf.MaxIdle < 0 {
+			return conf, fmt.Errorf("DECK_MAX_IDLE must be a positive duration, got %s", maxIdle)
+		}
+	}
+	if batchSize := os.Getenv("REAPER_BATCH_SIZE"); len(batchSize) > 0 {
+		if conf.BatchSize, err = strconv.Atoi(batchSize); err != nil || conf.BatchSize < 1 {
+			return conf, fmt.Errorf("REAPER_BATCH_SIZE must be a positive number, got %s", batchSize)
+		}
+	}
+	return conf, nil
+}
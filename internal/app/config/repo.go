@@ -0,0 +1,27 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// Backend is where decks and games are stored
+type Backend string
+
+const (
+	PostgresBackend Backend = "postgres"
+	// MemoryBackend keeps everything in the memory of the process, for local development, demos and tests
+	MemoryBackend Backend = "memory"
+)
+
+// NewBackend reads REPO_BACKEND, decks are stored in Postgres when it's unset
+func NewBackend() (Backend, error) {
+	backend := Backend(os.Getenv("REPO_BACKEND"))
+	switch backend {
+	case "":
+		return PostgresBackend, nil
+	case PostgresBackend, MemoryBackend:
+		return backend, nil
+	}
+	return "", fmt.Errorf("REPO_BACKEND must be %s or %s, got %s", PostgresBackend, MemoryBackend, backend)
+}
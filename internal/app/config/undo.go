@@ -0,0 +1,21 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// NewUndoDepth reads UNDO_DEPTH, the number of operations of a deck that can be undone in a row. It's defaultDepth
+// when unset, 0 turns undo off.
+func NewUndoDepth(defaultDepth int) (int, error) {
+	depth := os.Getenv("UNDO_DEPTH")
+	if len(depth) == 0 {
+		return defaultDepth, nil
+	}
+	value, err := strconv.Atoi(depth)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("UNDO_DEPTH must be 0 or a positive number, got %s", depth)
+	}
+	return value, nil
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	custErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+type BlackjackHandler struct {
+	service service.BlackjackService
+}
+
+func NewBlackjackHandler(service service.BlackjackService) *BlackjackHandler {
+	return &BlackjackHandler{service: service}
+}
+
+func (h *BlackjackHandler) CreateGame(ctx *gin.Context) {
+	bet, err := strconv.Atoi(ctx.Query("bet"))
+	if err != nil {
+		serveHttpError(ctx, custErr.New(http.StatusBadRequest, "bet must be a number"))
+		return
+	}
+	decksCount, err := intQuery(ctx, "decks_count")
+	if err != nil {
+		serveHttpError(ctx, custErr.New(http.StatusBadRequest, "decks_count must be a number"))
+		return
+	}
+	hitsSoft17 := false
+	if hitsSoft17Param := ctx.Query("dealer_hits_soft17"); len(hitsSoft17Param) > 0 {
+		hitsSoft17, err = strconv.ParseBool(hitsSoft17Param)
+		if err != nil {
+			serveHttpError(ctx, custErr.New(http.StatusBadRequest, "dealer_hits_soft17 must be boolean"))
+			return
+		}
+	}
+
+	req := model.CreateBlackjackRequest{
+		Bet:              bet,
+		DeckId:           ctx.Query("deck_id"),
+		DecksCount:       decksCount,
+		DealerHitsSoft17: hitsSoft17,
+	}
+	game, err := h.service.CreateGame(req)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, game)
+}
+
+func (h *BlackjackHandler) GetGame(ctx *gin.Context) {
+	game, err := h.service.GetGame(ctx.Param("id"))
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, game)
+}
+
+func (h *BlackjackHandler) PlayAction(ctx *gin.Context) {
+	game, err := h.service.PlayAction(ctx.Param("id"), ctx.Param("action"))
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, game)
+}
+
+func (h *BlackjackHandler) InitRoutes(engine *gin.Engine) {
+	engine.POST("/blackjack", h.CreateGame)
+	engine.GET("/blackjack/:id", h.GetGame)
+	engine.POST("/blackjack/:id/:action", h.PlayAction)
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"encoding/json"
+	custErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+type MockBlackjackService struct {
+	GameError error
+}
+
+func (m *MockBlackjackService) CreateGame(req model.CreateBlackjackRequest) (*model.BlackjackGameResponse, error) {
+	if m.GameError != nil {
+		return nil, m.GameError
+	}
+	return &model.BlackjackGameResponse{GameId: "game-id", DeckId: "shoe-id", Status: "player_turn",
+		DealerHitsSoft17: req.DealerHitsSoft17}, nil
+}
+func (m *MockBlackjackService) GetGame(id string) (*model.BlackjackGameResponse, error) {
+	if m.GameError != nil {
+		return nil, m.GameError
+	}
+	return &model.BlackjackGameResponse{GameId: id, Status: "player_turn"}, nil
+}
+func (m *MockBlackjackService) PlayAction(id string, action string) (*model.BlackjackGameResponse, error) {
+	if m.GameError != nil {
+		return nil, m.GameError
+	}
+	return &model.BlackjackGameResponse{GameId: id, Status: "settled"}, nil
+}
+
+var blackjackRouter *gin.Engine
+var mockBlackjackService *MockBlackjackService
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	mockBlackjackService = &MockBlackjackService{}
+	blackjackRouter = gin.Default()
+	NewBlackjackHandler(mockBlackjackService).InitRoutes(blackjackRouter)
+}
+
+func TestCreateBlackjackGameHandler(t *testing.T) {
+	mockBlackjackService.GameError = nil
+
+	// Test case: Deal a game with H17
+	w := performRequest(blackjackRouter, "POST", "/blackjack?bet=10&decks_count=6&dealer_hits_soft17=true", "")
+	var game model.BlackjackGameResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &game))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.True(t, game.DealerHitsSoft17)
+
+	// Test case: Invalid parameters
+	w = performRequest(blackjackRouter, "POST", "/blackjack", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performRequest(blackjackRouter, "POST", "/blackjack?bet=10&decks_count=six", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performRequest(blackjackRouter, "POST", "/blackjack?bet=10&dealer_hits_soft17=maybe", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case: Shoe wasn't found
+	mockBlackjackService.GameError = custErr.New(http.StatusNotFound, "deck with id shoe-id wasn't found")
+	w = performRequest(blackjackRouter, "POST", "/blackjack?bet=10&deck_id=shoe-id", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockBlackjackService.GameError = nil
+}
+
+func TestPlayBlackjackHandlers(t *testing.T) {
+	mockBlackjackService.GameError = nil
+
+	// Test case: Get a game
+	w := performRequest(blackjackRouter, "GET", "/blackjack/game-id", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Test case: Play an action
+	w = performRequest(blackjackRouter, "POST", "/blackjack/game-id/stand", "")
+	var game model.BlackjackGameResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &game))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "settled", game.Status)
+
+	// Test case: Action isn't allowed
+	mockBlackjackService.GameError = custErr.New(http.StatusBadRequest, "split isn't allowed now")
+	w = performRequest(blackjackRouter, "POST", "/blackjack/game-id/split", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockBlackjackService.GameError = nil
+}
@@ -1,12 +1,15 @@
 package handler
 
 import (
+	"fmt"
 	custErr "github.com/deck/internal/app/error"
 	"github.com/deck/internal/app/model"
 	"github.com/deck/internal/app/service"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type DeckHandler struct {
@@ -17,6 +20,23 @@ func NewDeckHandler(service service.DeckService) *DeckHandler {
 	return &DeckHandler{service: service}
 }
 
+// maxActorLength fits the actor column of the deck's history
+const maxActorLength = 100
+
+// decks records the X-Actor header of the request, e.g. the player, as the actor of the operations on the deck
+func (h *DeckHandler) decks(ctx *gin.Context) service.DeckService {
+	return h.service.WithActor(ctx.GetHeader("X-Actor"))
+}
+
+// checkActor rejects the requests whose X-Actor header is too long to be recorded
+func (h *DeckHandler) checkActor(ctx *gin.Context) {
+	if len(ctx.GetHeader("X-Actor")) > maxActorLength {
+		serveHttpError(ctx, custErr.New(http.StatusBadRequest,
+			fmt.Sprintf("X-Actor must be at most %d characters", maxActorLength)))
+		ctx.Abort()
+	}
+}
+
 func (h *DeckHandler) CreateDeck(ctx *gin.Context) {
 	var err error
 	cards := ctx.Query("cards")
@@ -30,11 +50,43 @@ func (h *DeckHandler) CreateDeck(ctx *gin.Context) {
 		}
 	}
 
+	jokers := false
+	if jokersParam := ctx.Query("jokers"); len(jokersParam) > 0 {
+		jokers, err = strconv.ParseBool(jokersParam)
+		if err != nil {
+			serveHttpError(ctx, custErr.New(http.StatusBadRequest, "jokers must be boolean"))
+			return
+		}
+	}
+	decksCount, err := intQuery(ctx, "decks_count")
+	if err != nil {
+		serveHttpError(ctx, custErr.New(http.StatusBadRequest, "decks_count must be a number"))
+		return
+	}
+	cutCard, err := intQuery(ctx, "cut_card")
+	if err != nil {
+		serveHttpError(ctx, custErr.New(http.StatusBadRequest, "cut_card must be a number"))
+		return
+	}
+	ttl, err := ttlQuery(ctx)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+
 	req := model.CreateDeckRequest{
-		Shuffled: shuffled,
-		Cards:    cards,
+		Shuffled:   shuffled,
+		Seed:       ctx.Query("seed"),
+		ClientSeed: ctx.Query("client_seed"),
+		Cards:      cards,
+		DeckType:   ctx.Query("deck_type"),
+		Jokers:     jokers,
+		DecksCount: decksCount,
+		CutCard:    cutCard,
+		Owner:      ctx.Query("owner"),
+		TTL:        ttl,
 	}
-	deck, err := h.service.CreateDeck(req)
+	deck, err := h.decks(ctx).CreateDeck(req)
 	if err != nil {
 		serveHttpError(ctx, err)
 		return
@@ -43,25 +95,388 @@ func (h *DeckHandler) CreateDeck(ctx *gin.Context) {
 	ctx.JSON(http.StatusCreated, deck)
 }
 
+// ListDecks pages through the decks, the time ranges are given in RFC 3339
+func (h *DeckHandler) ListDecks(ctx *gin.Context) {
+	req := model.ListDecksRequest{Owner: ctx.Query("owner"), ParentId: ctx.Query("parent_id"), Cursor: ctx.Query("cursor")}
+	var err error
+	for key, value := range map[string]**time.Time{
+		"created_from": &req.CreatedFrom,
+		"created_to":   &req.CreatedTo,
+		"updated_from": &req.UpdatedFrom,
+		"updated_to":   &req.UpdatedTo,
+	} {
+		if *value, err = timeQuery(ctx, key); err != nil {
+			serveHttpError(ctx, err)
+			return
+		}
+	}
+	if req.Shuffled, err = boolQuery(ctx, "shuffled"); err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	if req.Empty, err = boolQuery(ctx, "empty"); err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	if req.Limit, err = intQuery(ctx, "limit"); err != nil {
+		serveHttpError(ctx, custErr.New(http.StatusBadRequest, "limit must be a number"))
+		return
+	}
+	decks, err := h.decks(ctx).ListDecks(req)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, decks)
+}
+
 func (h *DeckHandler) GetDeckById(ctx *gin.Context) {
 	id := ctx.Param("id")
-	deck, err := h.service.GetDeckById(id)
+	includeSeed := false
+	if includeSeedParam := ctx.Query("include_seed"); len(includeSeedParam) > 0 {
+		var err error
+		includeSeed, err = strconv.ParseBool(includeSeedParam)
+		if err != nil {
+			serveHttpError(ctx, custErr.New(http.StatusBadRequest, "include_seed must be boolean"))
+			return
+		}
+	}
+	aceHigh, err := aceHighQuery(ctx)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	deck, err := h.decks(ctx).WithAceHigh(aceHigh).GetDeckById(id)
 	if err != nil {
 		serveHttpError(ctx, err)
 		return
 	}
+	if includeSeed {
+		seed, err := h.decks(ctx).GetDeckSeed(id)
+		if err != nil {
+			serveHttpError(ctx, err)
+			return
+		}
+		deck.Seed = seed.Seed
+	}
 	ctx.JSON(http.StatusOK, deck)
 }
 
+func (h *DeckHandler) DeleteDeck(ctx *gin.Context) {
+	if err := h.decks(ctx).DeleteDeck(ctx.Param("id")); err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// CloneDeck copies the deck into a new one, taking the owner and ttl of the clone like CreateDeck does
+func (h *DeckHandler) CloneDeck(ctx *gin.Context) {
+	ttl, err := ttlQuery(ctx)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	clone, err := h.decks(ctx).CloneDeck(ctx.Param("id"), model.CloneDeckRequest{Owner: ctx.Query("owner"), TTL: ttl})
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, clone)
+}
+
+func (h *DeckHandler) ListSnapshots(ctx *gin.Context) {
+	snapshots, err := h.decks(ctx).ListSnapshots(ctx.Param("id"))
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, snapshots)
+}
+
+func (h *DeckHandler) SaveSnapshot(ctx *gin.Context) {
+	snapshot, err := h.decks(ctx).SaveSnapshot(ctx.Param("id"), ctx.Param("name"))
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, snapshot)
+}
+
+func (h *DeckHandler) RestoreSnapshot(ctx *gin.Context) {
+	state, err := h.decks(ctx).RestoreSnapshot(ctx.Param("id"), ctx.Param("name"))
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, state)
+}
+
+func (h *DeckHandler) DeleteSnapshot(ctx *gin.Context) {
+	if err := h.decks(ctx).DeleteSnapshot(ctx.Param("id"), ctx.Param("name")); err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *DeckHandler) GetDeckHistory(ctx *gin.Context) {
+	after, err := intQuery(ctx, "after")
+	if err != nil {
+		serveHttpError(ctx, custErr.New(http.StatusBadRequest, "after must be a number"))
+		return
+	}
+	limit, err := intQuery(ctx, "limit")
+	if err != nil {
+		serveHttpError(ctx, custErr.New(http.StatusBadRequest, "limit must be a number"))
+		return
+	}
+	history, err := h.decks(ctx).GetDeckHistory(ctx.Param("id"), after, limit)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, history)
+}
+
+func (h *DeckHandler) GetDeckAt(ctx *gin.Context) {
+	number, err := strconv.Atoi(ctx.Param("number"))
+	if err != nil {
+		serveHttpError(ctx, custErr.New(http.StatusBadRequest, "event number must be a number"))
+		return
+	}
+	state, err := h.decks(ctx).GetDeckAt(ctx.Param("id"), number)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, state)
+}
+
+func (h *DeckHandler) Undo(ctx *gin.Context) {
+	var req model.UndoRequest
+	if versionParam := ctx.Query("version"); len(versionParam) > 0 {
+		version, err := strconv.Atoi(versionParam)
+		if err != nil {
+			serveHttpError(ctx, custErr.New(http.StatusBadRequest, "version must be a number"))
+			return
+		}
+		req.Version = &version
+	}
+	state, err := h.decks(ctx).Undo(ctx.Param("id"), req)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, state)
+}
+
+func (h *DeckHandler) GetDeckSeed(ctx *gin.Context) {
+	seed, err := h.decks(ctx).GetDeckSeed(ctx.Param("id"))
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, seed)
+}
+
 func (h *DeckHandler) DrawCards(ctx *gin.Context) {
 	id := ctx.Param("id")
+	aceHigh, err := aceHighQuery(ctx)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	decks := h.decks(ctx).WithAceHigh(aceHigh)
+	if codes := parseCards(ctx.Query("cards")); len(codes) > 0 {
+		cards, err := decks.DrawSpecificCards(id, codes)
+		if err != nil {
+			serveHttpError(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusCreated, cards)
+		return
+	}
+
 	countParam := ctx.Query("count")
 	count, err := strconv.Atoi(countParam)
 	if err != nil {
 		serveHttpError(ctx, custErr.New(http.StatusBadRequest, "count must be a number"))
 		return
 	}
-	cards, err := h.service.DrawCards(id, count)
+	from := model.Position(ctx.DefaultQuery("from", string(model.Top)))
+	cards, err := decks.DrawCardsFrom(id, count, from)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, cards)
+}
+
+func (h *DeckHandler) ShuffleDeck(ctx *gin.Context) {
+	returnCards := false
+	if returnParam := ctx.Query("return_cards"); len(returnParam) > 0 {
+		var err error
+		returnCards, err = strconv.ParseBool(returnParam)
+		if err != nil {
+			serveHttpError(ctx, custErr.New(http.StatusBadRequest, "return_cards must be boolean"))
+			return
+		}
+	}
+	deck, err := h.decks(ctx).ShuffleDeck(ctx.Param("id"), returnCards)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, deck)
+}
+
+func (h *DeckHandler) PeekCards(ctx *gin.Context) {
+	count, err := strconv.Atoi(ctx.Query("count"))
+	if err != nil {
+		serveHttpError(ctx, custErr.New(http.StatusBadRequest, "count must be a number"))
+		return
+	}
+	res, err := h.decks(ctx).PeekCards(ctx.Param("id"), count)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *DeckHandler) CutDeck(ctx *gin.Context) {
+	position, err := intQuery(ctx, "position")
+	if err != nil {
+		serveHttpError(ctx, custErr.New(http.StatusBadRequest, "position must be a number"))
+		return
+	}
+	res, err := h.decks(ctx).CutDeck(ctx.Param("id"), position)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *DeckHandler) InsertCard(ctx *gin.Context) {
+	index, err := intQuery(ctx, "index")
+	if err != nil {
+		serveHttpError(ctx, custErr.New(http.StatusBadRequest, "index must be a number"))
+		return
+	}
+	res, err := h.decks(ctx).InsertCard(ctx.Param("id"), ctx.Query("card"), index)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *DeckHandler) VerifyDeck(ctx *gin.Context) {
+	res, err := h.decks(ctx).VerifyDeck(ctx.Param("id"))
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *DeckHandler) DiscardCards(ctx *gin.Context) {
+	id := ctx.Param("id")
+	deck, err := h.decks(ctx).DiscardCards(id, parseCards(ctx.Query("cards")))
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, deck)
+}
+
+func (h *DeckHandler) ReturnCards(ctx *gin.Context) {
+	var err error
+	id := ctx.Param("id")
+	shuffleParam := ctx.Query("shuffle")
+	shuffle := false
+	if len(shuffleParam) > 0 {
+		shuffle, err = strconv.ParseBool(shuffleParam)
+		if err != nil {
+			serveHttpError(ctx, custErr.New(http.StatusBadRequest, "shuffle must be boolean"))
+			return
+		}
+	}
+
+	req := model.ReturnCardsRequest{
+		Cards:    parseCards(ctx.Query("cards")),
+		Position: model.Position(ctx.DefaultQuery("position", string(model.Top))),
+		Shuffle:  shuffle,
+	}
+	deck, err := h.decks(ctx).ReturnCards(id, req)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, deck)
+}
+
+func (h *DeckHandler) CreatePile(ctx *gin.Context) {
+	pile, err := h.decks(ctx).CreatePile(ctx.Param("id"), ctx.Param("name"))
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, pile)
+}
+
+func (h *DeckHandler) GetPile(ctx *gin.Context) {
+	pile, err := h.decks(ctx).GetPile(ctx.Param("id"), ctx.Param("name"))
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, pile)
+}
+
+func (h *DeckHandler) MoveCards(ctx *gin.Context) {
+	count, err := intQuery(ctx, "count")
+	if err != nil {
+		serveHttpError(ctx, custErr.New(http.StatusBadRequest, "count must be a number"))
+		return
+	}
+
+	req := model.MoveCardsRequest{
+		From:  ctx.Query("from"),
+		Count: count,
+		Cards: parseCards(ctx.Query("cards")),
+	}
+	pile, err := h.decks(ctx).MoveCards(ctx.Param("id"), ctx.Param("name"), req)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, pile)
+}
+
+func (h *DeckHandler) ShufflePile(ctx *gin.Context) {
+	pile, err := h.decks(ctx).ShufflePile(ctx.Param("id"), ctx.Param("name"))
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, pile)
+}
+
+func (h *DeckHandler) DrawFromPile(ctx *gin.Context) {
+	count, err := strconv.Atoi(ctx.Query("count"))
+	if err != nil {
+		serveHttpError(ctx, custErr.New(http.StatusBadRequest, "count must be a number"))
+		return
+	}
+	aceHigh, err := aceHighQuery(ctx)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	cards, err := h.decks(ctx).WithAceHigh(aceHigh).DrawFromPile(ctx.Param("id"), ctx.Param("name"), count)
 	if err != nil {
 		serveHttpError(ctx, err)
 		return
@@ -69,8 +484,203 @@ func (h *DeckHandler) DrawCards(ctx *gin.Context) {
 	ctx.JSON(http.StatusCreated, cards)
 }
 
+func (h *DeckHandler) SortCards(ctx *gin.Context) {
+	req, err := sortQuery(ctx)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	cards, err := h.decks(ctx).SortCards(parseCards(ctx.Query("cards")), req)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, cards)
+}
+
+func (h *DeckHandler) SortPile(ctx *gin.Context) {
+	req, err := sortQuery(ctx)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	pile, err := h.decks(ctx).SortPile(ctx.Param("id"), ctx.Param("name"), req)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, pile)
+}
+
+func (h *DeckHandler) EvaluateHand(ctx *gin.Context) {
+	hand, err := h.decks(ctx).EvaluateHand(parseCards(ctx.Query("cards")))
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, hand)
+}
+
+func (h *DeckHandler) EvaluatePile(ctx *gin.Context) {
+	hand, err := h.decks(ctx).EvaluatePile(ctx.Param("id"), ctx.Param("name"))
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, hand)
+}
+
+func (h *DeckHandler) Showdown(ctx *gin.Context) {
+	var req model.ShowdownRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		serveHttpError(ctx, custErr.New(http.StatusBadRequest, "body must be a showdown with players and board"))
+		return
+	}
+	res, err := h.decks(ctx).Showdown(req)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *DeckHandler) DeckShowdown(ctx *gin.Context) {
+	var players []string
+	if playersParam := ctx.Query("players"); len(playersParam) > 0 {
+		players = strings.Split(playersParam, ",")
+	}
+	res, err := h.decks(ctx).DeckShowdown(ctx.Param("id"), players, ctx.Query("board"))
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *DeckHandler) CalculateEquity(ctx *gin.Context) {
+	var req model.EquityRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		serveHttpError(ctx, custErr.New(http.StatusBadRequest, "body must be an equity request with players and board"))
+		return
+	}
+	res, err := h.decks(ctx).CalculateEquity(req)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
 func (h *DeckHandler) InitRoutes(engine *gin.Engine) {
-	engine.POST("/decks", h.CreateDeck)
-	engine.GET("/decks/:id", h.GetDeckById)
-	engine.PUT("/decks/:id/cards", h.DrawCards)
+	routes := engine.Group("", h.checkActor)
+	routes.POST("/decks", h.CreateDeck)
+	routes.GET("/decks", h.ListDecks)
+	routes.GET("/decks/:id", h.GetDeckById)
+	routes.DELETE("/decks/:id", h.DeleteDeck)
+	routes.PUT("/decks/:id/cards", h.DrawCards)
+	routes.POST("/decks/:id/shuffle", h.ShuffleDeck)
+	routes.GET("/decks/:id/peek", h.PeekCards)
+	routes.POST("/decks/:id/cut", h.CutDeck)
+	routes.POST("/decks/:id/insert", h.InsertCard)
+	routes.GET("/decks/:id/seed", h.GetDeckSeed)
+	routes.GET("/decks/:id/history", h.GetDeckHistory)
+	routes.GET("/decks/:id/history/:number", h.GetDeckAt)
+	routes.POST("/decks/:id/undo", h.Undo)
+	routes.POST("/decks/:id/clone", h.CloneDeck)
+	routes.GET("/decks/:id/snapshots", h.ListSnapshots)
+	routes.PUT("/decks/:id/snapshots/:name", h.SaveSnapshot)
+	routes.POST("/decks/:id/snapshots/:name/restore", h.RestoreSnapshot)
+	routes.DELETE("/decks/:id/snapshots/:name", h.DeleteSnapshot)
+	routes.GET("/decks/:id/verify", h.VerifyDeck)
+	routes.POST("/decks/:id/discard", h.DiscardCards)
+	routes.POST("/decks/:id/return", h.ReturnCards)
+	routes.POST("/decks/:id/piles/:name", h.CreatePile)
+	routes.GET("/decks/:id/piles/:name", h.GetPile)
+	routes.POST("/decks/:id/piles/:name/add", h.MoveCards)
+	routes.POST("/decks/:id/piles/:name/shuffle", h.ShufflePile)
+	routes.PUT("/decks/:id/piles/:name/cards", h.DrawFromPile)
+	routes.POST("/decks/:id/piles/:name/sort", h.SortPile)
+	routes.GET("/decks/:id/piles/:name/hand", h.EvaluatePile)
+	routes.GET("/decks/:id/showdown", h.DeckShowdown)
+	routes.GET("/cards/sort", h.SortCards)
+	routes.GET("/poker/hands", h.EvaluateHand)
+	routes.POST("/poker/showdown", h.Showdown)
+	routes.POST("/poker/equity", h.CalculateEquity)
+}
+
+// intQuery parses an optional numeric query parameter, a missing one is 0
+func intQuery(ctx *gin.Context, key string) (int, error) {
+	param := ctx.Query(key)
+	if len(param) == 0 {
+		return 0, nil
+	}
+	return strconv.Atoi(param)
+}
+
+// boolQuery reads an optional boolean, nil when it's not given
+func boolQuery(ctx *gin.Context, key string) (*bool, error) {
+	param := ctx.Query(key)
+	if len(param) == 0 {
+		return nil, nil
+	}
+	value, err := strconv.ParseBool(param)
+	if err != nil {
+		return nil, custErr.New(http.StatusBadRequest, fmt.Sprintf("%s must be boolean", key))
+	}
+	return &value, nil
+}
+
+// timeQuery reads an optional RFC 3339 time in UTC, nil when it's not given. The timestamp columns drop the offset of
+// a time, so one given in another zone would be compared by its wall clock.
+func timeQuery(ctx *gin.Context, key string) (*time.Time, error) {
+	param := ctx.Query(key)
+	if len(param) == 0 {
+		return nil, nil
+	}
+	value, err := time.Parse(time.RFC3339, param)
+	if err != nil {
+		return nil, custErr.New(http.StatusBadRequest, fmt.Sprintf("%s must be an RFC 3339 time", key))
+	}
+	value = value.UTC()
+	return &value, nil
+}
+
+// ttlQuery reads how long until a deck expires, 0 when it's not given
+func ttlQuery(ctx *gin.Context) (time.Duration, error) {
+	param := ctx.Query("ttl")
+	if len(param) == 0 {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(param)
+	if err != nil {
+		return 0, custErr.New(http.StatusBadRequest, "ttl must be a duration like 30m or 24h")
+	}
+	return ttl, nil
+}
+
+// sortQuery reads the order to sort cards in, by suit or rank and with aces high or low
+func sortQuery(ctx *gin.Context) (model.SortRequest, error) {
+	aceHigh, err := aceHighQuery(ctx)
+	return model.SortRequest{By: model.SortOrder(strings.ToLower(ctx.Query("by"))), AceHigh: aceHigh}, err
+}
+
+// aceHighQuery reads whether the aces of the cards rank above the kings, they rank below the twos by default
+func aceHighQuery(ctx *gin.Context) (bool, error) {
+	param := ctx.Query("ace_high")
+	if len(param) == 0 {
+		return false, nil
+	}
+	aceHigh, err := strconv.ParseBool(param)
+	if err != nil {
+		return false, custErr.New(http.StatusBadRequest, "ace_high must be boolean")
+	}
+	return aceHigh, nil
+}
+
+// parseCards splits a comma separated list of card codes, an empty list gives no cards
+func parseCards(param string) []string {
+	if len(param) == 0 {
+		return nil
+	}
+	return strings.Split(strings.ToUpper(param), ",")
 }
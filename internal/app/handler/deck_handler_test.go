@@ -5,17 +5,23 @@ import (
 	custErr "github.com/deck/internal/app/error"
 	"github.com/deck/internal/app/model"
 	"github.com/deck/internal/app/repo"
+	"github.com/deck/internal/app/service"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 type MockService struct {
 	Decks     map[string]repo.Deck
 	DeckError error
+	Actor     string
+	AceHigh   bool
+	// Listed is the last request to list decks
+	Listed model.ListDecksRequest
 }
 
 func (m *MockService) CreateDeck(req model.CreateDeckRequest) (*model.CreateDeckResponse, error) {
@@ -40,8 +46,226 @@ func (m *MockService) GetDeckById(id string) (*model.OpenDeckResponse, error) {
 	}, nil
 }
 func (m *MockService) DrawCards(id string, count int) ([]model.Card, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return []model.Card{{Value: "A", Suit: "Spades", Code: "AS"}}, nil
+}
+func (m *MockService) DrawCardsFrom(id string, count int, from model.Position) ([]model.Card, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
 	return []model.Card{{Value: "A", Suit: "Spades", Code: "AS"}}, nil
 }
+func (m *MockService) DrawSpecificCards(id string, codes []string) ([]model.Card, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return []model.Card{{Value: "7", Suit: "HEARTS", Code: "7H"}}, nil
+}
+func (m *MockService) DiscardCards(id string, codes []string) (*model.OpenDeckResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.OpenDeckResponse{DeckId: id}, nil
+}
+func (m *MockService) ReturnCards(id string, req model.ReturnCardsRequest) (*model.OpenDeckResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.OpenDeckResponse{DeckId: id}, nil
+}
+func (m *MockService) CreatePile(id, name string) (*model.PileResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.PileResponse{DeckId: id, Name: name}, nil
+}
+func (m *MockService) GetPile(id, name string) (*model.PileResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.PileResponse{DeckId: id, Name: name}, nil
+}
+func (m *MockService) MoveCards(id, name string, req model.MoveCardsRequest) (*model.PileResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.PileResponse{DeckId: id, Name: name, Remaining: req.Count}, nil
+}
+func (m *MockService) ShufflePile(id, name string) (*model.PileResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.PileResponse{DeckId: id, Name: name}, nil
+}
+func (m *MockService) DrawFromPile(id, name string, count int) ([]model.Card, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return []model.Card{{Value: "ACE", Suit: "SPADES", Code: "AS"}}, nil
+}
+func (m *MockService) ShuffleDeck(id string, returnCards bool) (*model.OpenDeckResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.OpenDeckResponse{DeckId: id, Shuffled: true}, nil
+}
+func (m *MockService) PeekCards(id string, count int) (*model.DeckOperationResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.DeckOperationResponse{DeckId: id, Remaining: 52}, nil
+}
+func (m *MockService) CutDeck(id string, position int) (*model.DeckOperationResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.DeckOperationResponse{DeckId: id, Remaining: 52, Position: position}, nil
+}
+func (m *MockService) InsertCard(id, code string, index int) (*model.DeckOperationResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.DeckOperationResponse{DeckId: id, Remaining: 53, Position: index}, nil
+}
+func (m *MockService) GetDeckSeed(id string) (*model.DeckSeedResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.DeckSeedResponse{DeckId: id, Seed: "replay-seed", Seeded: true}, nil
+}
+func (m *MockService) VerifyDeck(id string) (*model.VerifyDeckResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.VerifyDeckResponse{DeckId: id}, nil
+}
+func (m *MockService) WithActor(actor string) service.DeckService {
+	m.Actor = actor
+	return m
+}
+func (m *MockService) GetDeckHistory(id string, after, limit int) (*model.DeckHistoryResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.DeckHistoryResponse{DeckId: id, Events: []model.DeckEventResponse{
+		{Number: after + 1, Type: "draw", Actor: "alice", Payload: json.RawMessage(`{"count":1}`)},
+	}}, nil
+}
+func (m *MockService) GetDeckAt(id string, number int) (*model.DeckStateResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.DeckStateResponse{Event: model.DeckEventResponse{Number: number}, Deck: model.OpenDeckResponse{DeckId: id}}, nil
+}
+func (m *MockService) WithUndoDepth(depth int) service.DeckService {
+	return m
+}
+func (m *MockService) WithoutGameDecks(games repo.GameRepo) service.DeckService {
+	return m
+}
+func (m *MockService) WithAceHigh(aceHigh bool) service.DeckService {
+	m.AceHigh = aceHigh
+	return m
+}
+func (m *MockService) Undo(id string, req model.UndoRequest) (*model.DeckStateResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	version := 1
+	if req.Version != nil {
+		version = *req.Version + 1
+	}
+	return &model.DeckStateResponse{Event: model.DeckEventResponse{Type: "draw"}, Deck: model.OpenDeckResponse{DeckId: id, Version: version}}, nil
+}
+func (m *MockService) CloneDeck(id string, req model.CloneDeckRequest) (*model.CreateDeckResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.CreateDeckResponse{DeckId: "clone-deck-id", ParentId: &id, Owner: req.Owner}, nil
+}
+func (m *MockService) SaveSnapshot(id, name string) (*model.SnapshotResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.SnapshotResponse{DeckId: id, Name: name, Actor: m.Actor}, nil
+}
+func (m *MockService) ListSnapshots(id string) (*model.ListSnapshotsResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.ListSnapshotsResponse{DeckId: id, Snapshots: []model.SnapshotResponse{{DeckId: id, Name: "start"}}}, nil
+}
+func (m *MockService) RestoreSnapshot(id, name string) (*model.DeckStateResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.DeckStateResponse{Event: model.DeckEventResponse{Type: "restore"}, Deck: model.OpenDeckResponse{DeckId: id}},
+		nil
+}
+func (m *MockService) DeleteSnapshot(id, name string) error {
+	return m.DeckError
+}
+func (m *MockService) DeleteDeck(id string) error {
+	return m.DeckError
+}
+func (m *MockService) ListDecks(req model.ListDecksRequest) (*model.ListDecksResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	m.Listed = req
+	return &model.ListDecksResponse{
+		Decks:      []model.DeckSummary{{DeckId: "valid-deck-id", Owner: req.Owner, Remaining: 52}},
+		NextCursor: "next",
+	}, nil
+}
+func (m *MockService) SortCards(codes []string, req model.SortRequest) ([]model.Card, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	rank := 1
+	if req.AceHigh {
+		rank = 14
+	}
+	return []model.Card{{Value: "2", Suit: "SPADES", Code: "2S", Rank: 2}, {Value: "ACE", Suit: "SPADES", Code: "AS", Rank: rank}}, nil
+}
+func (m *MockService) SortPile(id, name string, req model.SortRequest) (*model.PileResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.PileResponse{DeckId: id, Name: name}, nil
+}
+func (m *MockService) EvaluateHand(codes []string) (*model.HandResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.HandResponse{Category: "ROYAL_FLUSH", Score: 9 << 20}, nil
+}
+func (m *MockService) EvaluatePile(id, name string) (*model.HandResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.HandResponse{Category: "ONE_PAIR", Score: 1 << 20}, nil
+}
+func (m *MockService) Showdown(req model.ShowdownRequest) (*model.ShowdownResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.ShowdownResponse{Winners: []string{req.Players[0].Name}}, nil
+}
+func (m *MockService) DeckShowdown(id string, players []string, board string) (*model.ShowdownResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.ShowdownResponse{DeckId: id, Winners: players}, nil
+}
+func (m *MockService) CalculateEquity(req model.EquityRequest) (*model.EquityResponse, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return &model.EquityResponse{Exact: true, Runouts: 44}, nil
+}
 
 var router *gin.Engine
 var mockService *MockService
@@ -74,6 +298,207 @@ func TestCreateDeckHandler(t *testing.T) {
 	// Test case: Create a deck with invalid shuffled parameter
 	w = performRequest(router, "POST", "/decks?shuffled=invalid", "")
 	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case: Create a six deck shoe with a cut card
+	w = performRequest(router, "POST", "/decks?decks_count=6&cut_card=234", "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Test case: Create a piquet deck with jokers
+	w = performRequest(router, "POST", "/decks?deck_type=piquet&jokers=true", "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Test case: Create a deck with invalid jokers parameter
+	w = performRequest(router, "POST", "/decks?jokers=maybe", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case: Create a deck with invalid decks_count parameter
+	w = performRequest(router, "POST", "/decks?decks_count=six", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case: Create a deck expiring after a day
+	w = performRequest(router, "POST", "/decks?ttl=24h", "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Test case: Create a deck with invalid ttl parameter
+	w = performRequest(router, "POST", "/decks?ttl=tomorrow", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDeleteDeckHandler(t *testing.T) {
+	mockService.DeckError = nil
+
+	// Test case: Delete a deck
+	w := performRequest(router, "DELETE", "/decks/valid-deck-id", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// Test case: Delete a deck which doesn't exist
+	mockService.DeckError = custErr.New(http.StatusNotFound, "not found")
+	w = performRequest(router, "DELETE", "/decks/invalid-deck-id", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.DeckError = nil
+}
+
+func TestListDecksHandler(t *testing.T) {
+	mockService.DeckError = nil
+
+	// Test case: List decks with every filter
+	w := performRequest(router, "GET", "/decks?owner=alice&shuffled=true&empty=false&limit=10&cursor=abc"+
+		"&created_from=2023-12-01T00:00:00Z&created_to=2024-01-01T00:00:00Z&updated_from=2023-12-31T10:00:00%2B01:00", "")
+	var page model.ListDecksResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "alice", page.Decks[0].Owner)
+	assert.Equal(t, "next", page.NextCursor)
+
+	// Test case: a time with an offset is passed on in UTC
+	w = performRequest(router, "GET", "/decks?created_from=2024-01-02T10:00:00%2B02:00", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2024-01-02T08:00:00Z", mockService.Listed.CreatedFrom.Format(time.RFC3339))
+	assert.Equal(t, time.UTC, mockService.Listed.CreatedFrom.Location())
+
+	// Test case: Invalid parameters
+	w = performRequest(router, "GET", "/decks?created_from=yesterday", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performRequest(router, "GET", "/decks?empty=maybe", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performRequest(router, "GET", "/decks?limit=ten", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.DeckError = custErr.New(http.StatusBadRequest, "cursor is invalid")
+	w = performRequest(router, "GET", "/decks?cursor=abc", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.DeckError = nil
+}
+
+func TestDeckHistoryHandlers(t *testing.T) {
+	mockService.DeckError = nil
+
+	// Test case: Get a page of the history
+	w := performRequest(router, "GET", "/decks/valid-deck-id/history?after=4&limit=10", "")
+	var history model.DeckHistoryResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 5, history.Events[0].Number)
+	assert.JSONEq(t, `{"count":1}`, string(history.Events[0].Payload))
+
+	// Test case: Rebuild the deck after an event
+	w = performRequest(router, "GET", "/decks/valid-deck-id/history/3", "")
+	var state model.DeckStateResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &state))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 3, state.Event.Number)
+
+	// Test case: Invalid parameters
+	w = performRequest(router, "GET", "/decks/valid-deck-id/history?after=first", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performRequest(router, "GET", "/decks/valid-deck-id/history/last", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.DeckError = custErr.New(http.StatusNotFound, "event 9 of deck valid-deck-id wasn't found")
+	w = performRequest(router, "GET", "/decks/valid-deck-id/history/9", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.DeckError = nil
+}
+
+func TestUndoHandler(t *testing.T) {
+	mockService.DeckError = nil
+
+	// Test case: Undo the last operation of a deck at a given version
+	w := performRequest(router, "POST", "/decks/valid-deck-id/undo?version=4", "")
+	var state model.DeckStateResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &state))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "draw", state.Event.Type)
+	assert.Equal(t, 5, state.Deck.Version)
+
+	// Test case: Invalid version parameter
+	w = performRequest(router, "POST", "/decks/valid-deck-id/undo?version=latest", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case: The deck was modified meanwhile
+	mockService.DeckError = custErr.New(http.StatusConflict, "deck is at version 5, not 4")
+	w = performRequest(router, "POST", "/decks/valid-deck-id/undo?version=4", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	mockService.DeckError = nil
+}
+
+func TestCloneDeckHandler(t *testing.T) {
+	mockService.DeckError = nil
+
+	// Test case: Clone a deck for another owner
+	w := performRequest(router, "POST", "/decks/valid-deck-id/clone?owner=bob&ttl=2h", "")
+	var clone model.CreateDeckResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &clone))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "valid-deck-id", *clone.ParentId)
+	assert.Equal(t, "bob", clone.Owner)
+
+	// Test case: Invalid ttl parameter
+	w = performRequest(router, "POST", "/decks/valid-deck-id/clone?ttl=soon", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case: Unknown deck
+	mockService.DeckError = custErr.New(http.StatusNotFound, "deck with id unknown wasn't found")
+	w = performRequest(router, "POST", "/decks/unknown/clone", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.DeckError = nil
+}
+
+func TestSnapshotHandlers(t *testing.T) {
+	mockService.DeckError = nil
+
+	// Test case: Save a snapshot
+	w := performRequest(router, "PUT", "/decks/valid-deck-id/snapshots/start", "")
+	var snapshot model.SnapshotResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &snapshot))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "start", snapshot.Name)
+
+	// Test case: List the snapshots
+	w = performRequest(router, "GET", "/decks/valid-deck-id/snapshots", "")
+	var list model.ListSnapshotsResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, list.Snapshots, 1)
+
+	// Test case: Restore a snapshot
+	w = performRequest(router, "POST", "/decks/valid-deck-id/snapshots/start/restore", "")
+	var state model.DeckStateResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &state))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "restore", state.Event.Type)
+
+	// Test case: Delete a snapshot
+	w = performRequest(router, "DELETE", "/decks/valid-deck-id/snapshots/start", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// Test case: Unknown snapshot
+	mockService.DeckError = custErr.New(http.StatusNotFound, "snapshot start wasn't found")
+	w = performRequest(router, "POST", "/decks/valid-deck-id/snapshots/start/restore", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performRequest(router, "DELETE", "/decks/valid-deck-id/snapshots/start", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.DeckError = nil
+}
+
+func TestActorHeader(t *testing.T) {
+	mockService.DeckError = nil
+
+	// Test case: The actor of an operation comes from the X-Actor header
+	req, _ := http.NewRequest("PUT", "/decks/valid-deck-id/cards?count=1", nil)
+	req.Header.Set("X-Actor", "alice")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "alice", mockService.Actor)
+
+	// Test case: An actor too long to be recorded is rejected
+	mockService.Actor = ""
+	req, _ = http.NewRequest("PUT", "/decks/valid-deck-id/cards?count=1", nil)
+	req.Header.Set("X-Actor", strings.Repeat("a", 101))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"X-Actor must be at most 100 characters"}`, w.Body.String())
+	assert.Empty(t, mockService.Actor)
 }
 
 func TestGetDeckByIdHandler(t *testing.T) {
@@ -88,6 +513,15 @@ func TestGetDeckByIdHandler(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Equal(t, "valid-deck-id", actualResult.DeckId)
 
+	// Test case: Get a deck with the aces ranked high
+	w = performRequest(router, "GET", "/decks/valid-deck-id?ace_high=true", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, mockService.AceHigh)
+	w = performRequest(router, "GET", "/decks/valid-deck-id", "")
+	assert.False(t, mockService.AceHigh)
+	w = performRequest(router, "GET", "/decks/valid-deck-id?ace_high=maybe", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
 	// Test case: Get a deck by invalid ID
 	mockService.DeckError = custErr.New(http.StatusNotFound, "not found")
 	w = performRequest(router, "GET", "/decks/invalid-deck-id", "")
@@ -95,6 +529,8 @@ func TestGetDeckByIdHandler(t *testing.T) {
 }
 
 func TestDrawCardsHandler(t *testing.T) {
+	mockService.DeckError = nil
+
 	// Test case: Draw cards with a valid count
 	w := performRequest(router, "PUT", "/decks/valid-deck-id/cards?count=3", "")
 	assert.Equal(t, http.StatusCreated, w.Code)
@@ -102,6 +538,275 @@ func TestDrawCardsHandler(t *testing.T) {
 	// Test case 2: Draw cards with an invalid count
 	w = performRequest(router, "PUT", "/decks/valid-deck-id/cards?count=invalid", "")
 	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case: Draw cards from the bottom
+	w = performRequest(router, "PUT", "/decks/valid-deck-id/cards?count=2&from=bottom", "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Test case: Draw a named card without count
+	w = performRequest(router, "PUT", "/decks/valid-deck-id/cards?cards=7H", "")
+	var cards []model.Card
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &cards))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "7H", cards[0].Code)
+
+	// Test case: Draw cards with the aces ranked high
+	w = performRequest(router, "PUT", "/decks/valid-deck-id/cards?count=1&ace_high=true", "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.True(t, mockService.AceHigh)
+	w = performRequest(router, "PUT", "/decks/valid-deck-id/cards?count=1&ace_high=1x", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case: Draw cards while another draw modified the deck
+	mockService.DeckError = custErr.New(http.StatusConflict, "deck was modified by another request, please retry")
+	w = performRequest(router, "PUT", "/decks/valid-deck-id/cards?count=3", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	mockService.DeckError = nil
+}
+
+func TestShuffleDeckHandler(t *testing.T) {
+	mockService.DeckError = nil
+
+	// Test case: Reshuffle a deck with its drawn and discarded cards
+	w := performRequest(router, "POST", "/decks/valid-deck-id/shuffle?return_cards=true", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Test case: Reshuffle with invalid return_cards parameter
+	w = performRequest(router, "POST", "/decks/valid-deck-id/shuffle?return_cards=all", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case: Reshuffle while a draw modified the deck
+	mockService.DeckError = custErr.New(http.StatusConflict, "deck was modified by another request, please retry")
+	w = performRequest(router, "POST", "/decks/valid-deck-id/shuffle", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	mockService.DeckError = nil
+}
+
+func TestDeckOperationHandlers(t *testing.T) {
+	mockService.DeckError = nil
+
+	// Test case: Peek at the top cards
+	w := performRequest(router, "GET", "/decks/valid-deck-id/peek?count=3", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Test case: Peek with an invalid count
+	w = performRequest(router, "GET", "/decks/valid-deck-id/peek", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case: Cut the deck at a position
+	w = performRequest(router, "POST", "/decks/valid-deck-id/cut?position=20", "")
+	var res model.DeckOperationResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 20, res.Position)
+
+	// Test case: Cut with an invalid position
+	w = performRequest(router, "POST", "/decks/valid-deck-id/cut?position=middle", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case: Insert a card
+	w = performRequest(router, "POST", "/decks/valid-deck-id/insert?card=AS&index=5", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Test case: Insert a card which is already in the deck
+	mockService.DeckError = custErr.New(http.StatusConflict, "card AS is already in the deck")
+	w = performRequest(router, "POST", "/decks/valid-deck-id/insert?card=AS", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	mockService.DeckError = nil
+}
+
+func TestDeckSeedHandlers(t *testing.T) {
+	mockService.DeckError = nil
+
+	// Test case: the seed is hidden by default
+	w := performRequest(router, "GET", "/decks/valid-deck-id", "")
+	var deck model.OpenDeckResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &deck))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, deck.Seed)
+
+	// Test case: the seed is included when asked for
+	w = performRequest(router, "GET", "/decks/valid-deck-id?include_seed=true", "")
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &deck))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "replay-seed", deck.Seed)
+
+	// Test case: invalid include_seed parameter
+	w = performRequest(router, "GET", "/decks/valid-deck-id?include_seed=yes-please", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case: report the seed of a deck
+	w = performRequest(router, "GET", "/decks/valid-deck-id/seed", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestVerifyDeckHandler(t *testing.T) {
+	mockService.DeckError = nil
+
+	// Test case: Verify a finished deck
+	w := performRequest(router, "GET", "/decks/valid-deck-id/verify", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Test case: Verify a deck which still has cards
+	mockService.DeckError = custErr.New(http.StatusConflict, "server seed is revealed once the deck is finished")
+	w = performRequest(router, "GET", "/decks/valid-deck-id/verify", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	mockService.DeckError = nil
+}
+
+func TestDiscardCardsHandler(t *testing.T) {
+	mockService.DeckError = nil
+
+	// Test case: Discard drawn cards
+	w := performRequest(router, "POST", "/decks/valid-deck-id/discard?cards=AS,KD", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Test case: Discard a card that wasn't drawn
+	mockService.DeckError = custErr.New(http.StatusNotFound, "card AS wasn't drawn from the deck")
+	w = performRequest(router, "POST", "/decks/valid-deck-id/discard?cards=AS", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.DeckError = nil
+}
+
+func TestReturnCardsHandler(t *testing.T) {
+	mockService.DeckError = nil
+
+	// Test case: Return the discard pile to the bottom and reshuffle
+	w := performRequest(router, "POST", "/decks/valid-deck-id/return?position=bottom&shuffle=true", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Test case: Return with invalid shuffle parameter
+	w = performRequest(router, "POST", "/decks/valid-deck-id/return?shuffle=invalid", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestPileHandlers(t *testing.T) {
+	mockService.DeckError = nil
+
+	// Test case: Create a pile
+	w := performRequest(router, "POST", "/decks/valid-deck-id/piles/hand", "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Test case: Move cards into the pile
+	w = performRequest(router, "POST", "/decks/valid-deck-id/piles/hand/add?count=2", "")
+	var pile model.PileResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &pile))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, pile.Remaining)
+
+	// Test case: Move cards with an invalid count
+	w = performRequest(router, "POST", "/decks/valid-deck-id/piles/hand/add?count=two", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case: List, shuffle and draw from the pile
+	w = performRequest(router, "GET", "/decks/valid-deck-id/piles/hand", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "POST", "/decks/valid-deck-id/piles/hand/shuffle", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "PUT", "/decks/valid-deck-id/piles/hand/cards?count=1", "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Test case: Pile wasn't found
+	mockService.DeckError = custErr.New(http.StatusNotFound, "pile hand wasn't found")
+	w = performRequest(router, "GET", "/decks/valid-deck-id/piles/hand", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.DeckError = nil
+}
+
+func TestSortHandlers(t *testing.T) {
+	mockService.DeckError = nil
+
+	// Test case: Sort card codes with aces high
+	w := performRequest(router, "GET", "/cards/sort?cards=as,2s&by=rank&ace_high=true", "")
+	var cards []model.Card
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &cards))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 14, cards[1].Rank)
+
+	// Test case: Sort a pile
+	w = performRequest(router, "POST", "/decks/valid-deck-id/piles/hand/sort?by=suit", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Test case: Invalid parameters
+	w = performRequest(router, "GET", "/cards/sort?cards=AS&ace_high=sometimes", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.DeckError = custErr.New(http.StatusBadRequest, "cards can be sorted by suit or rank")
+	w = performRequest(router, "POST", "/decks/valid-deck-id/piles/hand/sort?by=color", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.DeckError = nil
+}
+
+func TestEvaluateHandHandlers(t *testing.T) {
+	mockService.DeckError = nil
+
+	// Test case: Evaluate card codes
+	w := performRequest(router, "GET", "/poker/hands?cards=AS,KS,QS,JS,10S", "")
+	var hand model.HandResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &hand))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ROYAL_FLUSH", hand.Category)
+
+	// Test case: Evaluate a pile
+	w = performRequest(router, "GET", "/decks/valid-deck-id/piles/hand/hand", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Test case: Invalid cards
+	mockService.DeckError = custErr.New(http.StatusBadRequest, "a hand needs 5 - 7 cards, got 2")
+	w = performRequest(router, "GET", "/poker/hands?cards=AS,KS", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.DeckError = nil
+}
+
+func TestShowdownHandlers(t *testing.T) {
+	mockService.DeckError = nil
+
+	// Test case: Showdown of the posted hands
+	body := `{"players": [{"name": "alice", "cards": ["AS", "KS"]}, {"name": "bob", "cards": ["2C", "7D"]}],
+		"board": ["QS", "JS", "10S", "3H", "4D"]}`
+	w := performRequest(router, "POST", "/poker/showdown", body)
+	var res model.ShowdownResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"alice"}, res.Winners)
+
+	// Test case: Showdown with an invalid body
+	w = performRequest(router, "POST", "/poker/showdown", "players")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case: Showdown of the piles of a deck
+	w = performRequest(router, "GET", "/decks/valid-deck-id/showdown?players=alice,bob&board=board", "")
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"alice", "bob"}, res.Winners)
+
+	// Test case: Pile wasn't found
+	mockService.DeckError = custErr.New(http.StatusNotFound, "pile bob wasn't found")
+	w = performRequest(router, "GET", "/decks/valid-deck-id/showdown?players=alice,bob", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.DeckError = nil
+}
+
+func TestCalculateEquityHandler(t *testing.T) {
+	mockService.DeckError = nil
+
+	// Test case: Calculate the equity of the posted hands
+	body := `{"players": [{"name": "alice", "cards": ["AS", "AD"]}, {"name": "bob", "range": "QQ+,AKs"}],
+		"board": ["2C", "7H", "9D"], "iterations": 1000, "seed": "replay"}`
+	w := performRequest(router, "POST", "/poker/equity", body)
+	var res model.EquityResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 44, res.Runouts)
+
+	// Test case: Invalid body
+	w = performRequest(router, "POST", "/poker/equity", `{"iterations": "many"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case: Invalid request
+	mockService.DeckError = custErr.New(http.StatusBadRequest, "equity needs at least 2 players")
+	w = performRequest(router, "POST", "/poker/equity", `{"players": []}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.DeckError = nil
 }
 
 // performRequest is a helper function to send a request to the Gin router and return the response recorder.
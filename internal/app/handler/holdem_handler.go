@@ -0,0 +1,81 @@
+package handler
+
+import (
+	custErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type HoldemHandler struct {
+	service service.HoldemService
+}
+
+func NewHoldemHandler(service service.HoldemService) *HoldemHandler {
+	return &HoldemHandler{service: service}
+}
+
+func (h *HoldemHandler) CreateTable(ctx *gin.Context) {
+	var req model.CreateHoldemRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		serveHttpError(ctx, custErr.New(http.StatusBadRequest, "body must be a table with blinds and players"))
+		return
+	}
+	table, err := h.service.CreateTable(req)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, table)
+}
+
+func (h *HoldemHandler) GetTable(ctx *gin.Context) {
+	table, err := h.service.GetTable(ctx.Param("id"), playerToken(ctx))
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, table)
+}
+
+func (h *HoldemHandler) StartHand(ctx *gin.Context) {
+	table, err := h.service.StartHand(ctx.Param("id"), playerToken(ctx))
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, table)
+}
+
+func (h *HoldemHandler) Act(ctx *gin.Context) {
+	amount, err := intQuery(ctx, "amount")
+	if err != nil {
+		serveHttpError(ctx, custErr.New(http.StatusBadRequest, "amount must be a number"))
+		return
+	}
+	req := model.HoldemActionRequest{
+		Player: ctx.Query("player"),
+		Token:  playerToken(ctx),
+		Action: ctx.Query("action"),
+		Amount: amount,
+	}
+	table, err := h.service.Act(ctx.Param("id"), req)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, table)
+}
+
+// playerToken is the token the player got when the table was created
+func playerToken(ctx *gin.Context) string {
+	return ctx.GetHeader("X-Player-Token")
+}
+
+func (h *HoldemHandler) InitRoutes(engine *gin.Engine) {
+	engine.POST("/holdem", h.CreateTable)
+	engine.GET("/holdem/:id", h.GetTable)
+	engine.POST("/holdem/:id/hands", h.StartHand)
+	engine.POST("/holdem/:id/actions", h.Act)
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"encoding/json"
+	custErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type MockHoldemService struct {
+	TableError error
+}
+
+func (m *MockHoldemService) CreateTable(req model.CreateHoldemRequest) (*model.HoldemTableResponse, error) {
+	if m.TableError != nil {
+		return nil, m.TableError
+	}
+	return &model.HoldemTableResponse{TableId: "table-id", SmallBlind: req.SmallBlind, BigBlind: req.BigBlind, HandNumber: 1}, nil
+}
+func (m *MockHoldemService) GetTable(id string, token string) (*model.HoldemTableResponse, error) {
+	if m.TableError != nil {
+		return nil, m.TableError
+	}
+	if token != "alice-token" {
+		return nil, custErr.New(http.StatusForbidden, "the token doesn't belong to a player at the table")
+	}
+	return &model.HoldemTableResponse{TableId: id, ToAct: "alice"}, nil
+}
+func (m *MockHoldemService) StartHand(id string, token string) (*model.HoldemTableResponse, error) {
+	if m.TableError != nil {
+		return nil, m.TableError
+	}
+	return &model.HoldemTableResponse{TableId: id, HandNumber: 2}, nil
+}
+func (m *MockHoldemService) Act(id string, req model.HoldemActionRequest) (*model.HoldemTableResponse, error) {
+	if m.TableError != nil {
+		return nil, m.TableError
+	}
+	if req.Token != "alice-token" || req.Player != "alice" {
+		return nil, custErr.New(http.StatusForbidden, "acting for "+req.Player+" needs their token")
+	}
+	return &model.HoldemTableResponse{TableId: id, CurrentBet: req.Amount, ToAct: req.Player}, nil
+}
+
+var holdemRouter *gin.Engine
+var mockHoldemService *MockHoldemService
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	mockHoldemService = &MockHoldemService{}
+	holdemRouter = gin.Default()
+	NewHoldemHandler(mockHoldemService).InitRoutes(holdemRouter)
+}
+
+func TestCreateHoldemTableHandler(t *testing.T) {
+	mockHoldemService.TableError = nil
+
+	// Test case: Create a table
+	body := `{"small_blind": 1, "big_blind": 2, "players": [{"name": "alice", "stack": 100}, {"name": "bob", "stack": 100}]}`
+	w := performRequest(holdemRouter, "POST", "/holdem", body)
+	var table model.HoldemTableResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &table))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 2, table.BigBlind)
+
+	// Test case: Invalid body
+	w = performRequest(holdemRouter, "POST", "/holdem", `{"players": "alice"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case: Invalid table
+	mockHoldemService.TableError = custErr.New(http.StatusBadRequest, "a table needs 2 - 10 players")
+	w = performRequest(holdemRouter, "POST", "/holdem", `{"small_blind": 1, "big_blind": 2}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockHoldemService.TableError = nil
+}
+
+func TestPlayHoldemHandlers(t *testing.T) {
+	mockHoldemService.TableError = nil
+
+	// Test case: Get the table as the player of the X-Player-Token header
+	w := performPlayerRequest("GET", "/holdem/table-id", "alice-token")
+	var table model.HoldemTableResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &table))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "alice", table.ToAct)
+
+	// Test case: Someone else's token
+	w = performPlayerRequest("GET", "/holdem/table-id", "bob-token")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Test case: Raise
+	w = performPlayerRequest("POST", "/holdem/table-id/actions?player=alice&action=raise&amount=6", "alice-token")
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &table))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 6, table.CurrentBet)
+
+	// Test case: Act without the player's token
+	w = performRequest(holdemRouter, "POST", "/holdem/table-id/actions?player=alice&action=raise&amount=6", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Test case: Invalid amount
+	w = performRequest(holdemRouter, "POST", "/holdem/table-id/actions?player=alice&action=raise&amount=six", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case: Start the next hand
+	w = performRequest(holdemRouter, "POST", "/holdem/table-id/hands", "")
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &table))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 2, table.HandNumber)
+
+	// Test case: The hand isn't finished yet
+	mockHoldemService.TableError = custErr.New(http.StatusConflict, "hand 1 isn't finished yet")
+	w = performRequest(holdemRouter, "POST", "/holdem/table-id/hands", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	mockHoldemService.TableError = nil
+}
+
+// performPlayerRequest sends a request with a player token to the Hold'em router
+func performPlayerRequest(method, path, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	req.Header.Set("X-Player-Token", token)
+	w := httptest.NewRecorder()
+	holdemRouter.ServeHTTP(w, req)
+	return w
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	custErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+type KlondikeHandler struct {
+	service service.KlondikeService
+}
+
+func NewKlondikeHandler(service service.KlondikeService) *KlondikeHandler {
+	return &KlondikeHandler{service: service}
+}
+
+func (h *KlondikeHandler) CreateGame(ctx *gin.Context) {
+	drawCount, err := intQuery(ctx, "draw")
+	if err != nil {
+		serveHttpError(ctx, custErr.New(http.StatusBadRequest, "draw must be a number"))
+		return
+	}
+	game, err := h.service.CreateGame(model.CreateKlondikeRequest{DrawCount: drawCount, Seed: ctx.Query("seed")})
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, game)
+}
+
+func (h *KlondikeHandler) GetGame(ctx *gin.Context) {
+	game, err := h.service.GetGame(ctx.Param("id"))
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, game)
+}
+
+func (h *KlondikeHandler) Draw(ctx *gin.Context) {
+	game, err := h.service.Draw(ctx.Param("id"))
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, game)
+}
+
+// Move moves count cards, 1 by default, between the from and to piles
+func (h *KlondikeHandler) Move(ctx *gin.Context) {
+	count := 1
+	if countParam := ctx.Query("count"); len(countParam) > 0 {
+		var err error
+		count, err = strconv.Atoi(countParam)
+		if err != nil {
+			serveHttpError(ctx, custErr.New(http.StatusBadRequest, "count must be a number"))
+			return
+		}
+	}
+	req := model.KlondikeMoveRequest{From: ctx.Query("from"), To: ctx.Query("to"), Count: count}
+	game, err := h.service.Move(ctx.Param("id"), req)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, game)
+}
+
+func (h *KlondikeHandler) Undo(ctx *gin.Context) {
+	game, err := h.service.Undo(ctx.Param("id"))
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, game)
+}
+
+func (h *KlondikeHandler) InitRoutes(engine *gin.Engine) {
+	engine.POST("/klondike", h.CreateGame)
+	engine.GET("/klondike/:id", h.GetGame)
+	engine.POST("/klondike/:id/draw", h.Draw)
+	engine.POST("/klondike/:id/moves", h.Move)
+	engine.POST("/klondike/:id/undo", h.Undo)
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"encoding/json"
+	custErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+type MockKlondikeService struct {
+	GameError error
+}
+
+func (m *MockKlondikeService) CreateGame(req model.CreateKlondikeRequest) (*model.KlondikeGameResponse, error) {
+	if m.GameError != nil {
+		return nil, m.GameError
+	}
+	return &model.KlondikeGameResponse{GameId: "game-id", DrawCount: req.DrawCount, Stock: 24}, nil
+}
+func (m *MockKlondikeService) GetGame(id string) (*model.KlondikeGameResponse, error) {
+	if m.GameError != nil {
+		return nil, m.GameError
+	}
+	return &model.KlondikeGameResponse{GameId: id}, nil
+}
+func (m *MockKlondikeService) Draw(id string) (*model.KlondikeGameResponse, error) {
+	if m.GameError != nil {
+		return nil, m.GameError
+	}
+	return &model.KlondikeGameResponse{GameId: id, Stock: 23, WasteCount: 1}, nil
+}
+func (m *MockKlondikeService) Move(id string, req model.KlondikeMoveRequest) (*model.KlondikeGameResponse, error) {
+	if m.GameError != nil {
+		return nil, m.GameError
+	}
+	return &model.KlondikeGameResponse{GameId: id, Moves: req.Count}, nil
+}
+func (m *MockKlondikeService) Undo(id string) (*model.KlondikeGameResponse, error) {
+	if m.GameError != nil {
+		return nil, m.GameError
+	}
+	return &model.KlondikeGameResponse{GameId: id}, nil
+}
+
+var klondikeRouter *gin.Engine
+var mockKlondikeService *MockKlondikeService
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	mockKlondikeService = &MockKlondikeService{}
+	klondikeRouter = gin.Default()
+	NewKlondikeHandler(mockKlondikeService).InitRoutes(klondikeRouter)
+}
+
+func TestCreateKlondikeGameHandler(t *testing.T) {
+	mockKlondikeService.GameError = nil
+
+	// Test case: Deal a draw 3 game
+	w := performRequest(klondikeRouter, "POST", "/klondike?draw=3&seed=replay", "")
+	var game model.KlondikeGameResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &game))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 3, game.DrawCount)
+
+	// Test case: Invalid draw count
+	w = performRequest(klondikeRouter, "POST", "/klondike?draw=three", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestPlayKlondikeHandlers(t *testing.T) {
+	mockKlondikeService.GameError = nil
+
+	// Test case: Draw from the stock
+	w := performRequest(klondikeRouter, "POST", "/klondike/game-id/draw", "")
+	var game model.KlondikeGameResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &game))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 23, game.Stock)
+
+	// Test case: Move cards, one by default
+	w = performRequest(klondikeRouter, "POST", "/klondike/game-id/moves?from=waste&to=tableau1", "")
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &game))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, game.Moves)
+	w = performRequest(klondikeRouter, "POST", "/klondike/game-id/moves?from=tableau1&to=tableau2&count=two", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case: Undo and get the game
+	w = performRequest(klondikeRouter, "POST", "/klondike/game-id/undo", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(klondikeRouter, "GET", "/klondike/game-id", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Test case: The move isn't allowed
+	mockKlondikeService.GameError = custErr.New(http.StatusBadRequest, "QS can't be moved onto 5S")
+	w = performRequest(klondikeRouter, "POST", "/klondike/game-id/moves?from=tableau2&to=tableau1", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockKlondikeService.GameError = nil
+}
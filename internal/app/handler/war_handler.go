@@ -0,0 +1,90 @@
+package handler
+
+import (
+	custErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+type WarHandler struct {
+	service service.WarService
+}
+
+func NewWarHandler(service service.WarService) *WarHandler {
+	return &WarHandler{service: service}
+}
+
+func (h *WarHandler) CreateGame(ctx *gin.Context) {
+	maxRounds, err := intQuery(ctx, "max_rounds")
+	if err != nil {
+		serveHttpError(ctx, custErr.New(http.StatusBadRequest, "max_rounds must be a number"))
+		return
+	}
+	game, err := h.service.CreateGame(model.CreateWarRequest{MaxRounds: maxRounds, Seed: ctx.Query("seed")})
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, game)
+}
+
+func (h *WarHandler) GetGame(ctx *gin.Context) {
+	game, err := h.service.GetGame(ctx.Param("id"))
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, game)
+}
+
+func (h *WarHandler) GetRound(ctx *gin.Context) {
+	number, err := strconv.Atoi(ctx.Param("number"))
+	if err != nil {
+		serveHttpError(ctx, custErr.New(http.StatusBadRequest, "round number must be a number"))
+		return
+	}
+	round, err := h.service.GetRound(ctx.Param("id"), number)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, round)
+}
+
+// PlayRounds plays count rounds, 1 by default, or with finish=true the rounds left as far as a request goes
+func (h *WarHandler) PlayRounds(ctx *gin.Context) {
+	count := 1
+	if countParam := ctx.Query("count"); len(countParam) > 0 {
+		var err error
+		count, err = strconv.Atoi(countParam)
+		if err != nil {
+			serveHttpError(ctx, custErr.New(http.StatusBadRequest, "count must be a number"))
+			return
+		}
+	}
+	finish := false
+	if finishParam := ctx.Query("finish"); len(finishParam) > 0 {
+		var err error
+		finish, err = strconv.ParseBool(finishParam)
+		if err != nil {
+			serveHttpError(ctx, custErr.New(http.StatusBadRequest, "finish must be boolean"))
+			return
+		}
+	}
+	game, err := h.service.PlayRounds(ctx.Param("id"), count, finish)
+	if err != nil {
+		serveHttpError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, game)
+}
+
+func (h *WarHandler) InitRoutes(engine *gin.Engine) {
+	engine.POST("/war", h.CreateGame)
+	engine.GET("/war/:id", h.GetGame)
+	engine.POST("/war/:id/rounds", h.PlayRounds)
+	engine.GET("/war/:id/rounds/:number", h.GetRound)
+}
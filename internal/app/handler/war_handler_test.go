@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"encoding/json"
+	custErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+type MockWarService struct {
+	GameError error
+}
+
+func (m *MockWarService) CreateGame(req model.CreateWarRequest) (*model.WarGameResponse, error) {
+	if m.GameError != nil {
+		return nil, m.GameError
+	}
+	return &model.WarGameResponse{GameId: "game-id", DeckId: "deck-id", MaxRounds: req.MaxRounds}, nil
+}
+func (m *MockWarService) GetGame(id string) (*model.WarGameResponse, error) {
+	if m.GameError != nil {
+		return nil, m.GameError
+	}
+	return &model.WarGameResponse{GameId: id}, nil
+}
+func (m *MockWarService) GetRound(id string, number int) (*model.WarRoundResponse, error) {
+	if m.GameError != nil {
+		return nil, m.GameError
+	}
+	return &model.WarRoundResponse{Number: number}, nil
+}
+func (m *MockWarService) PlayRounds(id string, count int, finish bool) (*model.WarGameResponse, error) {
+	if m.GameError != nil {
+		return nil, m.GameError
+	}
+	return &model.WarGameResponse{GameId: id, Round: count, Finished: finish}, nil
+}
+
+var warRouter *gin.Engine
+var mockWarService *MockWarService
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	mockWarService = &MockWarService{}
+	warRouter = gin.Default()
+	NewWarHandler(mockWarService).InitRoutes(warRouter)
+}
+
+func TestCreateWarGameHandler(t *testing.T) {
+	mockWarService.GameError = nil
+
+	// Test case: Create a game with a round cap
+	w := performRequest(warRouter, "POST", "/war?max_rounds=500&seed=replay", "")
+	var game model.WarGameResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &game))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 500, game.MaxRounds)
+
+	// Test case: Invalid round cap
+	w = performRequest(warRouter, "POST", "/war?max_rounds=many", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestPlayWarHandlers(t *testing.T) {
+	mockWarService.GameError = nil
+
+	// Test case: Play one round by default
+	w := performRequest(warRouter, "POST", "/war/game-id/rounds", "")
+	var game model.WarGameResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &game))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, game.Round)
+
+	// Test case: Play to the end
+	w = performRequest(warRouter, "POST", "/war/game-id/rounds?finish=true", "")
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &game))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, game.Finished)
+
+	// Test case: Invalid parameters
+	w = performRequest(warRouter, "POST", "/war/game-id/rounds?count=ten", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performRequest(warRouter, "POST", "/war/game-id/rounds?finish=maybe", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performRequest(warRouter, "GET", "/war/game-id/rounds/last", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test case: Get a round
+	w = performRequest(warRouter, "GET", "/war/game-id/rounds/3", "")
+	var round model.WarRoundResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &round))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 3, round.Number)
+
+	// Test case: The game is over
+	mockWarService.GameError = custErr.New(http.StatusConflict, "the game is already finished")
+	w = performRequest(warRouter, "POST", "/war/game-id/rounds", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	mockWarService.GameError = nil
+}
@@ -0,0 +1,503 @@
+package holdem
+
+import (
+	"errors"
+	"fmt"
+	"github.com/deck/internal/app/poker"
+	"sort"
+)
+
+type Street string
+
+const (
+	Preflop  Street = "preflop"
+	Flop     Street = "flop"
+	Turn     Street = "turn"
+	River    Street = "river"
+	Showdown Street = "showdown"
+)
+
+type Action string
+
+const (
+	Fold  Action = "fold"
+	Check Action = "check"
+	Call  Action = "call"
+	// Raise takes the total the player bets on this street, not the amount added to the current bet
+	Raise Action = "raise"
+	AllIn Action = "all_in"
+)
+
+// MaxSeats is the most players at a table
+const MaxSeats = 10
+
+var ErrNoHand = errors.New("no hand is being played")
+
+// Draw deals count cards from the top of the hand's deck
+type Draw func(count int) ([]string, error)
+
+// Player is seated at the table. TokenHash identifies the player's requests, the table never learns the token itself.
+type Player struct {
+	Name      string `json:"name"`
+	Stack     int    `json:"stack"`
+	TokenHash string `json:"token_hash,omitempty"`
+}
+
+// Table is a Texas Hold'em table. Players keep their seat between hands, Hand is the current or the last played hand.
+type Table struct {
+	SmallBlind int      `json:"small_blind"`
+	BigBlind   int      `json:"big_blind"`
+	Players    []Player `json:"players"`
+	Button     int      `json:"button"`
+	Hand       *Hand    `json:"hand"`
+	History    []Event  `json:"history"`
+}
+
+// Hand is one deal at the table. Seats are the players dealt in, from the left of the button with the button last.
+// Bets are per street, Committed is everything a seat put into the pot during the hand.
+type Hand struct {
+	Number     int      `json:"number"`
+	DeckId     string   `json:"deck_id"`
+	Street     Street   `json:"street"`
+	Board      []string `json:"board"`
+	Burned     []string `json:"burned"`
+	Seats      []Seat   `json:"seats"`
+	ToAct      int      `json:"to_act"`
+	CurrentBet int      `json:"current_bet"`
+	MinRaise   int      `json:"min_raise"`
+	Pots       []Pot    `json:"pots"`
+	Finished   bool     `json:"finished"`
+}
+
+type Seat struct {
+	Player    int      `json:"player"`
+	Name      string   `json:"name"`
+	Cards     []string `json:"cards"`
+	Bet       int      `json:"bet"`
+	Committed int      `json:"committed"`
+	Folded    bool     `json:"folded"`
+	AllIn     bool     `json:"all_in"`
+	Acted     bool     `json:"acted"`
+}
+
+// Pot is the main pot or a side pot, only the Eligible players put in enough chips to win it
+type Pot struct {
+	Amount   int      `json:"amount"`
+	Eligible []string `json:"eligible"`
+	Winners  []string `json:"winners"`
+	Hand     string   `json:"hand,omitempty"`
+}
+
+// Event is an entry of the table's history: a blind, an action, cards dealt to the board, cards shown or a pot won
+type Event struct {
+	Hand   int      `json:"hand"`
+	Street Street   `json:"street"`
+	Player string   `json:"player,omitempty"`
+	Action string   `json:"action"`
+	Amount int      `json:"amount,omitempty"`
+	Cards  []string `json:"cards,omitempty"`
+}
+
+func NewTable(smallBlind, bigBlind int, players []Player) (*Table, error) {
+	if smallBlind <= 0 || bigBlind < smallBlind {
+		return nil, fmt.Errorf("blinds must be positive and the big blind at least the small blind")
+	}
+	if len(players) < 2 || len(players) > MaxSeats {
+		return nil, fmt.Errorf("a table needs 2 - %d players", MaxSeats)
+	}
+	names := make(map[string]bool, len(players))
+	for _, p := range players {
+		if len(p.Name) == 0 || names[p.Name] {
+			return nil, fmt.Errorf("every player needs a unique name")
+		}
+		if p.Stack <= 0 {
+			return nil, fmt.Errorf("player %s needs a positive stack", p.Name)
+		}
+		names[p.Name] = true
+	}
+	return &Table{SmallBlind: smallBlind, BigBlind: bigBlind, Players: players, Button: -1}, nil
+}
+
+// StartHand moves the button, posts the blinds and deals the hole cards from the hand's deck
+func (t *Table) StartHand(deckId string, draw Draw) error {
+	if t.Hand != nil && !t.Hand.Finished {
+		return fmt.Errorf("hand %d isn't finished yet", t.Hand.Number)
+	}
+	withChips := 0
+	for _, p := range t.Players {
+		if p.Stack > 0 {
+			withChips++
+		}
+	}
+	if withChips < 2 {
+		return fmt.Errorf("at least 2 players with chips are needed")
+	}
+
+	number := 1
+	if t.Hand != nil {
+		number = t.Hand.Number + 1
+	}
+	t.Button = t.nextWithChips(t.Button)
+	h := &Hand{Number: number, DeckId: deckId, Street: Preflop, MinRaise: t.BigBlind, CurrentBet: t.BigBlind}
+	for i := 1; i <= len(t.Players); i++ {
+		p := (t.Button + i) % len(t.Players)
+		if t.Players[p].Stack > 0 {
+			h.Seats = append(h.Seats, Seat{Player: p, Name: t.Players[p].Name})
+		}
+	}
+	t.Hand = h
+
+	// heads up the button posts the small blind and acts first before the flop
+	smallBlind, bigBlind, first := 0, 1, 2%len(h.Seats)
+	if len(h.Seats) == 2 {
+		smallBlind, bigBlind, first = 1, 0, 1
+	}
+	t.bet(&h.Seats[smallBlind], t.SmallBlind)
+	t.record(h.Seats[smallBlind].Name, "small_blind", h.Seats[smallBlind].Bet, nil)
+	t.bet(&h.Seats[bigBlind], t.BigBlind)
+	t.record(h.Seats[bigBlind].Name, "big_blind", h.Seats[bigBlind].Bet, nil)
+
+	cards, err := draw(2 * len(h.Seats))
+	if err != nil {
+		return err
+	}
+	for i := range h.Seats {
+		h.Seats[i].Cards = []string{cards[i], cards[i+len(h.Seats)]}
+	}
+	t.record("", "deal", 0, nil)
+
+	h.ToAct = first - 1
+	return t.afterAction(draw)
+}
+
+// Act plays the action of the player whose turn it is. When the betting round is over the next street is dealt, and
+// after the river the pots go to the best hands.
+func (t *Table) Act(player string, action Action, amount int, draw Draw) error {
+	h := t.Hand
+	if h == nil || h.Finished {
+		return ErrNoHand
+	}
+	seat := &h.Seats[h.ToAct]
+	if seat.Name != player {
+		return fmt.Errorf("it's %s's turn", seat.Name)
+	}
+	stack := t.Players[seat.Player].Stack
+	toCall := h.CurrentBet - seat.Bet
+
+	switch action {
+	case Fold:
+		seat.Folded = true
+	case Check:
+		if toCall > 0 {
+			return fmt.Errorf("can't check, %d to call", toCall)
+		}
+	case Call:
+		if toCall <= 0 {
+			return fmt.Errorf("nothing to call, check instead")
+		}
+		amount = minInt(toCall, stack)
+		t.bet(seat, amount)
+	case Raise:
+		added := amount - seat.Bet
+		if added > stack {
+			return fmt.Errorf("can't raise to %d with a stack of %d", amount, stack)
+		}
+		if amount <= h.CurrentBet || (amount-h.CurrentBet < h.MinRaise && added < stack) {
+			return fmt.Errorf("raise must be to at least %d", h.CurrentBet+h.MinRaise)
+		}
+		t.raise(seat, amount-h.CurrentBet)
+		t.bet(seat, added)
+	case AllIn:
+		amount = seat.Bet + stack
+		if amount > h.CurrentBet {
+			t.raise(seat, amount-h.CurrentBet)
+		}
+		t.bet(seat, stack)
+	default:
+		return fmt.Errorf("unknown action %s", action)
+	}
+	seat.Acted = true
+	if action == Fold || action == Check {
+		amount = 0
+	}
+	t.record(player, string(action), amount, nil)
+	return t.afterAction(draw)
+}
+
+// Actions lists what the player to act can do
+func (t *Table) Actions() []Action {
+	h := t.Hand
+	if h == nil || h.Finished {
+		return nil
+	}
+	seat := h.Seats[h.ToAct]
+	stack := t.Players[seat.Player].Stack
+	actions := []Action{Fold}
+	if seat.Bet >= h.CurrentBet {
+		actions = append(actions, Check)
+	} else {
+		actions = append(actions, Call)
+	}
+	if seat.Bet+stack >= h.CurrentBet+h.MinRaise {
+		actions = append(actions, Raise)
+	}
+	return append(actions, AllIn)
+}
+
+// raise raises the current bet, a full raise reopens the betting for everybody else while a short all in doesn't
+func (t *Table) raise(seat *Seat, by int) {
+	h := t.Hand
+	if by >= h.MinRaise {
+		h.MinRaise = by
+		for i := range h.Seats {
+			h.Seats[i].Acted = false
+		}
+	}
+	h.CurrentBet += by
+}
+
+func (t *Table) bet(seat *Seat, amount int) {
+	stack := &t.Players[seat.Player].Stack
+	amount = minInt(amount, *stack)
+	*stack -= amount
+	seat.Bet += amount
+	seat.Committed += amount
+	if *stack == 0 {
+		seat.AllIn = true
+	}
+}
+
+func (t *Table) afterAction(draw Draw) error {
+	h := t.Hand
+	if live := h.live(); len(live) == 1 {
+		t.award(h.Seats[live[0]])
+		return nil
+	}
+	if next := h.nextToAct(h.ToAct + 1); next >= 0 {
+		h.ToAct = next
+		return nil
+	}
+	return t.nextStreet(draw)
+}
+
+// nextStreet burns a card and turns the next street. When at most one player can still bet, the board is run out.
+func (t *Table) nextStreet(draw Draw) error {
+	h := t.Hand
+	for {
+		if h.Street == River {
+			return t.showdown()
+		}
+		street, count := Flop, 3
+		switch h.Street {
+		case Flop:
+			street, count = Turn, 1
+		case Turn:
+			street, count = River, 1
+		}
+		cards, err := draw(1 + count)
+		if err != nil {
+			return err
+		}
+		h.Burned = append(h.Burned, cards[0])
+		h.Board = append(h.Board, cards[1:]...)
+		h.Street = street
+		t.record("", string(street), 0, cards[1:])
+
+		h.CurrentBet, h.MinRaise = 0, t.BigBlind
+		canAct := 0
+		for i := range h.Seats {
+			h.Seats[i].Bet, h.Seats[i].Acted = 0, false
+			if !h.Seats[i].Folded && !h.Seats[i].AllIn {
+				canAct++
+			}
+		}
+		if canAct >= 2 {
+			h.ToAct = h.nextToAct(0)
+			return nil
+		}
+	}
+}
+
+// award gives the pot to the last player who didn't fold, the cards aren't shown
+func (t *Table) award(winner Seat) {
+	h := t.Hand
+	t.returnUncalled()
+	total := 0
+	for _, s := range h.Seats {
+		total += s.Committed
+	}
+	t.Players[winner.Player].Stack += total
+	h.Pots = []Pot{{Amount: total, Eligible: []string{winner.Name}, Winners: []string{winner.Name}}}
+	t.record(winner.Name, "win", total, nil)
+	t.finish()
+}
+
+// showdown splits the chips into the main and side pots and gives each to the best hands eligible for it. Odd chips
+// of a split pot go to the winners closest to the left of the button.
+func (t *Table) showdown() error {
+	h := t.Hand
+	h.Street = Showdown
+	t.returnUncalled()
+	board, err := poker.ParseCards(h.Board)
+	if err != nil {
+		return err
+	}
+	for _, i := range h.live() {
+		t.record(h.Seats[i].Name, "show", 0, h.Seats[i].Cards)
+	}
+
+	h.Pots = nil
+	for _, pot := range h.sidePots() {
+		winners := pot.eligible
+		if len(pot.eligible) > 1 {
+			holeCards := make([][]poker.Card, len(pot.eligible))
+			for i, s := range pot.eligible {
+				if holeCards[i], err = poker.ParseCards(h.Seats[s].Cards); err != nil {
+					return err
+				}
+			}
+			hands, err := poker.Showdown(holeCards, board)
+			if err != nil {
+				return err
+			}
+			winners = nil
+			for _, w := range poker.Winners(hands) {
+				winners = append(winners, pot.eligible[w])
+			}
+			pot.Hand = hands[poker.Winners(hands)[0]].Description()
+		}
+
+		share, odd := pot.Amount/len(winners), pot.Amount%len(winners)
+		for i, w := range winners {
+			won := share
+			if i < odd {
+				won++
+			}
+			t.Players[h.Seats[w].Player].Stack += won
+			pot.Winners = append(pot.Winners, h.Seats[w].Name)
+			t.record(h.Seats[w].Name, "win", won, nil)
+		}
+		for _, s := range pot.eligible {
+			pot.Eligible = append(pot.Eligible, h.Seats[s].Name)
+		}
+		h.Pots = append(h.Pots, pot.Pot)
+	}
+	t.finish()
+	return nil
+}
+
+// returnUncalled gives the part of the biggest bet nobody else matched back to the player who made it
+func (t *Table) returnUncalled() {
+	h := t.Hand
+	top, second := 0, 0
+	for i := range h.Seats {
+		if h.Seats[i].Committed > h.Seats[top].Committed {
+			top = i
+		}
+	}
+	for i, s := range h.Seats {
+		if i != top && s.Committed > second {
+			second = s.Committed
+		}
+	}
+	seat := &h.Seats[top]
+	if uncalled := seat.Committed - second; uncalled > 0 {
+		seat.Committed -= uncalled
+		t.Players[seat.Player].Stack += uncalled
+		t.record(seat.Name, "return", uncalled, nil)
+	}
+}
+
+type sidePot struct {
+	Pot
+	eligible []int
+}
+
+// sidePots splits the committed chips by the all in amounts of the players still in the hand. Chips of folded players
+// above the highest of them go to the last pot.
+func (h *Hand) sidePots() []sidePot {
+	live := h.live()
+	var levels []int
+	for _, i := range live {
+		levels = append(levels, h.Seats[i].Committed)
+	}
+	sort.Ints(levels)
+
+	var pots []sidePot
+	previous, allocated, total := 0, 0, 0
+	for _, s := range h.Seats {
+		total += s.Committed
+	}
+	for _, level := range levels {
+		if level == previous {
+			continue
+		}
+		pot := sidePot{}
+		for i, s := range h.Seats {
+			pot.Amount += minInt(s.Committed, level) - minInt(s.Committed, previous)
+			if !s.Folded && s.Committed >= level {
+				pot.eligible = append(pot.eligible, i)
+			}
+		}
+		allocated += pot.Amount
+		pots = append(pots, pot)
+		previous = level
+	}
+	pots[len(pots)-1].Amount += total - allocated
+	return pots
+}
+
+func (t *Table) finish() {
+	t.Hand.Finished = true
+	t.Hand.ToAct = -1
+}
+
+func (t *Table) record(player, action string, amount int, cards []string) {
+	t.History = append(t.History, Event{
+		Hand:   t.Hand.Number,
+		Street: t.Hand.Street,
+		Player: player,
+		Action: action,
+		Amount: amount,
+		Cards:  cards,
+	})
+}
+
+func (t *Table) nextWithChips(from int) int {
+	for i := 1; i <= len(t.Players); i++ {
+		p := (from + i) % len(t.Players)
+		if t.Players[p].Stack > 0 {
+			return p
+		}
+	}
+	return from
+}
+
+// live returns the seats that haven't folded
+func (h *Hand) live() []int {
+	var live []int
+	for i, s := range h.Seats {
+		if !s.Folded {
+			live = append(live, i)
+		}
+	}
+	return live
+}
+
+// nextToAct returns the first seat from the given one on that still has to act in this betting round, or -1
+func (h *Hand) nextToAct(from int) int {
+	for i := 0; i < len(h.Seats); i++ {
+		s := h.Seats[(from+i)%len(h.Seats)]
+		if !s.Folded && !s.AllIn && (!s.Acted || s.Bet < h.CurrentBet) {
+			return (from + i) % len(h.Seats)
+		}
+	}
+	return -1
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
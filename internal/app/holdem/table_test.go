@@ -0,0 +1,187 @@
+package holdem
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// deck deals the given cards in order: the hole cards of the seats, then burn and board cards
+func deck(codes string) Draw {
+	cards := strings.Split(codes, ",")
+	return func(count int) ([]string, error) {
+		if count > len(cards) {
+			return nil, errors.New("deck is empty")
+		}
+		drawn := cards[:count]
+		cards = cards[count:]
+		return drawn, nil
+	}
+}
+
+func play(t *testing.T, table *Table, draw Draw, actions ...string) {
+	for _, a := range actions {
+		parts := strings.Split(a, " ")
+		amount := 0
+		if len(parts) == 3 {
+			for _, c := range parts[2] {
+				amount = amount*10 + int(c-'0')
+			}
+		}
+		assert.Nil(t, table.Act(parts[0], Action(parts[1]), amount, draw), a)
+	}
+}
+
+func TestHeadsUpHand(t *testing.T) {
+	table, err := NewTable(1, 2, []Player{{Name: "alice", Stack: 100}, {Name: "bob", Stack: 100}})
+	assert.NoError(t, err)
+	draw := deck("AS,KS,AD,KD,2C,7H,8D,9C,3C,JH,4C,2S")
+
+	// Test case: heads up the button posts the small blind and acts first
+	assert.Nil(t, table.StartHand("deck-1", draw))
+	h := table.Hand
+	assert.Equal(t, 0, table.Button)
+	assert.Equal(t, []string{"bob", "alice"}, []string{h.Seats[0].Name, h.Seats[1].Name})
+	assert.Equal(t, []string{"AS", "AD"}, h.Seats[0].Cards)
+	assert.Equal(t, []string{"KS", "KD"}, h.Seats[1].Cards)
+	assert.Equal(t, 99, table.Players[0].Stack)
+	assert.Equal(t, 98, table.Players[1].Stack)
+	assert.Equal(t, "alice", h.Seats[h.ToAct].Name)
+	assert.Equal(t, []Action{Fold, Call, Raise, AllIn}, table.Actions())
+
+	// Test case: the big blind gets the option after a call
+	play(t, table, draw, "alice call")
+	assert.Equal(t, "bob", h.Seats[h.ToAct].Name)
+	assert.Equal(t, []Action{Fold, Check, Raise, AllIn}, table.Actions())
+	play(t, table, draw, "bob check")
+
+	// Test case: after the flop the player left of the button acts first
+	assert.Equal(t, Flop, h.Street)
+	assert.Equal(t, []string{"7H", "8D", "9C"}, h.Board)
+	assert.Equal(t, []string{"2C"}, h.Burned)
+	assert.Equal(t, "bob", h.Seats[h.ToAct].Name)
+
+	play(t, table, draw, "bob check", "alice check", "bob check", "alice check", "bob check", "alice check")
+	assert.True(t, h.Finished)
+	assert.Equal(t, Showdown, h.Street)
+	assert.Equal(t, []string{"7H", "8D", "9C", "JH", "2S"}, h.Board)
+	assert.Equal(t, []string{"2C", "3C", "4C"}, h.Burned)
+	assert.Equal(t, []Pot{{Amount: 4, Eligible: []string{"bob", "alice"}, Winners: []string{"bob"}, Hand: "pair of aces, jack 9 8 kickers"}}, h.Pots)
+	assert.Equal(t, 98, table.Players[0].Stack)
+	assert.Equal(t, 102, table.Players[1].Stack)
+	assert.Nil(t, table.Actions())
+
+	// Test case: the history tells the whole hand
+	var actions []string
+	for _, e := range table.History {
+		actions = append(actions, e.Player+" "+e.Action)
+	}
+	assert.Equal(t, []string{"alice small_blind", "bob big_blind", " deal", "alice call", "bob check", " flop",
+		"bob check", "alice check", " turn", "bob check", "alice check", " river", "bob check", "alice check",
+		"bob show", "alice show", "bob win"}, actions)
+
+	// Test case: no more actions once the hand is finished
+	assert.Equal(t, ErrNoHand, table.Act("bob", Check, 0, draw))
+}
+
+func TestFoldAndRaises(t *testing.T) {
+	table, _ := NewTable(1, 2, []Player{{Name: "alice", Stack: 100}, {Name: "bob", Stack: 100}})
+	draw := deck("AS,KS,AD,KD")
+	assert.Nil(t, table.StartHand("deck-1", draw))
+
+	// Test case: invalid actions
+	assert.EqualError(t, table.Act("bob", Check, 0, draw), "it's alice's turn")
+	assert.EqualError(t, table.Act("alice", Check, 0, draw), "can't check, 1 to call")
+	assert.EqualError(t, table.Act("alice", Raise, 3, draw), "raise must be to at least 4")
+	assert.EqualError(t, table.Act("alice", Raise, 200, draw), "can't raise to 200 with a stack of 99")
+	assert.EqualError(t, table.Act("alice", "bluff", 0, draw), "unknown action bluff")
+
+	// Test case: a raise sets the minimum for re-raises and reopens the betting
+	play(t, table, draw, "alice raise 6")
+	assert.Equal(t, 4, table.Hand.MinRaise)
+	assert.EqualError(t, table.Act("bob", Raise, 8, draw), "raise must be to at least 10")
+	play(t, table, draw, "bob raise 10")
+	assert.Equal(t, "alice", table.Hand.Seats[table.Hand.ToAct].Name)
+	assert.EqualError(t, table.StartHand("deck-2", draw), "hand 1 isn't finished yet")
+
+	// Test case: the last player in the hand wins without showing, the uncalled part of the raise is returned
+	play(t, table, draw, "alice fold")
+	assert.True(t, table.Hand.Finished)
+	assert.Equal(t, Preflop, table.Hand.Street)
+	assert.Equal(t, []Pot{{Amount: 12, Eligible: []string{"bob"}, Winners: []string{"bob"}}}, table.Hand.Pots)
+	assert.Equal(t, Event{Hand: 1, Street: Preflop, Player: "bob", Action: "return", Amount: 4}, table.History[len(table.History)-2])
+	assert.Equal(t, 94, table.Players[0].Stack)
+	assert.Equal(t, 106, table.Players[1].Stack)
+
+	// Test case: the button moves with the next hand
+	assert.Nil(t, table.StartHand("deck-2", deck("AS,KS,AD,KD")))
+	assert.Equal(t, 1, table.Button)
+	assert.Equal(t, 2, table.Hand.Number)
+	assert.Equal(t, "deck-2", table.Hand.DeckId)
+}
+
+func TestSidePots(t *testing.T) {
+	table, _ := NewTable(1, 2, []Player{{Name: "alice", Stack: 100}, {Name: "bob", Stack: 50}, {Name: "carol", Stack: 20}})
+	draw := deck("KS,AS,2C,KH,AH,7D,5C,3S,8D,9H,6C,JC,10C,4D")
+	assert.Nil(t, table.StartHand("deck-1", draw))
+	assert.Equal(t, []string{"bob", "carol", "alice"}, []string{table.Hand.Seats[0].Name, table.Hand.Seats[1].Name, table.Hand.Seats[2].Name})
+
+	// Test case: everybody is all in, so the board is run out
+	play(t, table, draw, "alice all_in", "bob all_in", "carol all_in")
+	h := table.Hand
+	assert.True(t, h.Finished)
+	assert.Equal(t, []string{"3S", "8D", "9H", "JC", "4D"}, h.Board)
+
+	// Test case: carol's aces only win the main pot she could cover, bob's kings win the side pot and alice gets back
+	// what nobody called
+	assert.Equal(t, []Pot{
+		{Amount: 60, Eligible: []string{"bob", "carol", "alice"}, Winners: []string{"carol"}, Hand: "pair of aces, jack 9 8 kickers"},
+		{Amount: 60, Eligible: []string{"bob", "alice"}, Winners: []string{"bob"}, Hand: "pair of kings, jack 9 8 kickers"},
+	}, h.Pots)
+	assert.Equal(t, 50, h.Seats[2].Committed)
+	assert.Equal(t, []Player{{Name: "alice", Stack: 50}, {Name: "bob", Stack: 60}, {Name: "carol", Stack: 60}}, table.Players)
+}
+
+func TestSplitPotOddChip(t *testing.T) {
+	table, _ := NewTable(1, 2, []Player{{Name: "alice", Stack: 100}, {Name: "bob", Stack: 100}, {Name: "carol", Stack: 100}})
+	draw := deck("2S,3D,4S,5H,6S,7S,2C,10C,JH,QD,3C,KC,4C,AH")
+	assert.Nil(t, table.StartHand("deck-1", draw))
+
+	// Test case: both play the straight on the board, the odd chip goes to carol left of the button
+	play(t, table, draw, "alice call", "bob fold", "carol check",
+		"carol check", "alice check", "carol check", "alice check", "carol check", "alice check")
+	assert.Equal(t, []Pot{{Amount: 5, Eligible: []string{"carol", "alice"}, Winners: []string{"carol", "alice"}, Hand: "straight, ace high"}},
+		table.Hand.Pots)
+	assert.Equal(t, []Player{{Name: "alice", Stack: 100}, {Name: "bob", Stack: 99}, {Name: "carol", Stack: 101}}, table.Players)
+}
+
+func TestShortAllInDoesNotReopen(t *testing.T) {
+	table, _ := NewTable(5, 10, []Player{{Name: "alice", Stack: 100}, {Name: "bob", Stack: 100}, {Name: "carol", Stack: 25}})
+	draw := deck("2S,3D,4S,5H,6S,7S,2C,10C,JH,QD,3C,KC,4C,AH")
+	assert.Nil(t, table.StartHand("deck-1", draw))
+
+	// Test case: carol's all in for 25 raises by less than the minimum, so it doesn't reopen the betting
+	play(t, table, draw, "alice raise 20", "bob call", "carol all_in")
+	assert.Equal(t, 25, table.Hand.CurrentBet)
+	assert.Equal(t, 10, table.Hand.MinRaise)
+	assert.Equal(t, "alice", table.Hand.Seats[table.Hand.ToAct].Name)
+	play(t, table, draw, "alice call", "bob call")
+	assert.Equal(t, Flop, table.Hand.Street)
+}
+
+func TestNewTable(t *testing.T) {
+	_, err := NewTable(0, 2, []Player{{Name: "alice", Stack: 100}, {Name: "bob", Stack: 100}})
+	assert.EqualError(t, err, "blinds must be positive and the big blind at least the small blind")
+	_, err = NewTable(1, 2, []Player{{Name: "alice", Stack: 100}})
+	assert.EqualError(t, err, "a table needs 2 - 10 players")
+	_, err = NewTable(1, 2, []Player{{Name: "alice", Stack: 100}, {Name: "alice", Stack: 100}})
+	assert.EqualError(t, err, "every player needs a unique name")
+	_, err = NewTable(1, 2, []Player{{Name: "alice", Stack: 100}, {Name: "bob", Stack: 0}})
+	assert.EqualError(t, err, "player bob needs a positive stack")
+
+	// Test case: busted players sit out
+	table, _ := NewTable(1, 2, []Player{{Name: "alice", Stack: 100}, {Name: "bob", Stack: 100}})
+	table.Players[1].Stack = 0
+	assert.EqualError(t, table.StartHand("deck-1", deck("AS")), "at least 2 players with chips are needed")
+}
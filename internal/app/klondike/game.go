@@ -0,0 +1,273 @@
+package klondike
+
+import (
+	"errors"
+	"fmt"
+	"github.com/deck/internal/app/repo"
+	"strconv"
+	"strings"
+)
+
+const (
+	Stock      = "stock"
+	Waste      = "waste"
+	Foundation = "foundation"
+	Tableau    = "tableau"
+)
+
+const (
+	Columns     = 7
+	Foundations = 4
+	// DeckSize is the number of cards dealt, a standard deck without jokers
+	DeckSize = 52
+)
+
+// UndoDepth is the number of moves in a row that can be taken back
+const UndoDepth = 50
+
+var ErrGameWon = errors.New("the game is already won")
+
+// Column is a pile of the tableau, the last FaceUp of its Cards are turned face up
+type Column struct {
+	Cards  []string `json:"cards"`
+	FaceUp int      `json:"face_up"`
+}
+
+// Layout is where every card lies. The first card of the Stock is its top, the other piles have their top card last.
+type Layout struct {
+	Stock       []string              `json:"stock"`
+	Waste       []string              `json:"waste"`
+	Foundations [Foundations][]string `json:"foundations"`
+	Tableau     [Columns]Column       `json:"tableau"`
+}
+
+// Game is a game of Klondike solitaire. DrawCount cards are turned from the stock at once, History keeps the layout
+// before each of the last UndoDepth moves to undo them.
+type Game struct {
+	Layout
+	DrawCount int      `json:"draw_count"`
+	Moves     int      `json:"moves"`
+	History   []Layout `json:"history"`
+	Won       bool     `json:"won"`
+}
+
+// Deal lays out the cards: column n of the tableau gets n cards with the last one face up, the rest is the stock
+func Deal(cards []string, drawCount int) (*Game, error) {
+	if drawCount != 1 && drawCount != 3 {
+		return nil, fmt.Errorf("draw count must be 1 or 3")
+	}
+	if len(cards) != DeckSize {
+		return nil, fmt.Errorf("klondike needs %d cards, got %d", DeckSize, len(cards))
+	}
+	g := &Game{DrawCount: drawCount}
+	next := 0
+	for row := 0; row < Columns; row++ {
+		for col := row; col < Columns; col++ {
+			g.Tableau[col].Cards = append(g.Tableau[col].Cards, cards[next])
+			next++
+		}
+	}
+	for col := range g.Tableau {
+		g.Tableau[col].FaceUp = 1
+	}
+	g.Stock = append([]string{}, cards[next:]...)
+	return g, nil
+}
+
+// Draw turns DrawCount cards from the stock onto the waste, an empty stock is refilled by turning the waste over
+func (g *Game) Draw() error {
+	if g.Won {
+		return ErrGameWon
+	}
+	if len(g.Stock) == 0 && len(g.Waste) == 0 {
+		return fmt.Errorf("the stock and the waste are empty")
+	}
+	g.save()
+	if len(g.Stock) == 0 {
+		// turned over, the bottom card of the waste is the top of the stock
+		g.Stock, g.Waste = g.Waste, nil
+		return nil
+	}
+	count := g.DrawCount
+	if count > len(g.Stock) {
+		count = len(g.Stock)
+	}
+	g.Waste = append(g.Waste, g.Stock[:count]...)
+	g.Stock = g.Stock[count:]
+	return nil
+}
+
+// Move moves count cards from the waste, a foundation or a tableau column onto a foundation or a tableau column.
+// Piles are named waste, foundation1 - 4 and tableau1 - 7. Only a column moves more than one card.
+func (g *Game) Move(from, to string, count int) error {
+	if g.Won {
+		return ErrGameWon
+	}
+	if count < 1 {
+		return fmt.Errorf("count must be positive")
+	}
+	source, err := g.pile(from)
+	if err != nil {
+		return err
+	}
+	target, err := g.pile(to)
+	if err != nil {
+		return err
+	}
+	if from == to {
+		return fmt.Errorf("cards can't be moved to the same pile")
+	}
+	if to == Waste || to == Stock || from == Stock {
+		return fmt.Errorf("the stock and the waste only change by drawing")
+	}
+
+	available := len(*source)
+	if column := g.column(from); column != nil {
+		available = column.FaceUp
+	} else if count > 1 {
+		return fmt.Errorf("only one card can be moved from the %s", from)
+	}
+	if count > available {
+		return fmt.Errorf("%s has %d cards to move, got %d", from, available, count)
+	}
+	moved := (*source)[len(*source)-count:]
+
+	if strings.HasPrefix(to, Foundation) {
+		if count > 1 {
+			return fmt.Errorf("only one card can be moved to a foundation")
+		}
+		if err = canBuildFoundation(*target, moved[0]); err != nil {
+			return err
+		}
+	} else if err = canBuildTableau(*target, moved[0]); err != nil {
+		return err
+	}
+
+	g.save()
+	*target = append(*target, moved...)
+	*source = (*source)[:len(*source)-count]
+	if column := g.column(to); column != nil {
+		column.FaceUp += count
+	}
+	if column := g.column(from); column != nil {
+		column.FaceUp -= count
+		// the next card of the column is turned over
+		if column.FaceUp == 0 && len(column.Cards) > 0 {
+			column.FaceUp = 1
+		}
+	}
+	g.Won = true
+	for _, f := range g.Foundations {
+		if len(f) < len(repo.SequentialValues) {
+			g.Won = false
+		}
+	}
+	return nil
+}
+
+// Undo takes back the last draw or move
+func (g *Game) Undo() error {
+	if len(g.History) == 0 {
+		return fmt.Errorf("there is no move to undo")
+	}
+	g.Layout = g.History[len(g.History)-1]
+	g.History = g.History[:len(g.History)-1]
+	g.Moves--
+	g.Won = false
+	return nil
+}
+
+// save keeps a copy of the layout before a move
+func (g *Game) save() {
+	saved := Layout{
+		Stock: append([]string{}, g.Stock...),
+		Waste: append([]string{}, g.Waste...),
+	}
+	for i, f := range g.Foundations {
+		saved.Foundations[i] = append([]string{}, f...)
+	}
+	for i, c := range g.Tableau {
+		saved.Tableau[i] = Column{Cards: append([]string{}, c.Cards...), FaceUp: c.FaceUp}
+	}
+	g.History = append(g.History, saved)
+	if len(g.History) > UndoDepth {
+		g.History = g.History[len(g.History)-UndoDepth:]
+	}
+	g.Moves++
+}
+
+// pile finds the cards of a pile by its name
+func (g *Game) pile(name string) (*[]string, error) {
+	switch name {
+	case Stock:
+		return &g.Stock, nil
+	case Waste:
+		return &g.Waste, nil
+	}
+	if index, ok := pileIndex(name, Foundation, Foundations); ok {
+		return &g.Foundations[index], nil
+	}
+	if index, ok := pileIndex(name, Tableau, Columns); ok {
+		return &g.Tableau[index].Cards, nil
+	}
+	return nil, fmt.Errorf("unknown pile %s", name)
+}
+
+func (g *Game) column(name string) *Column {
+	if index, ok := pileIndex(name, Tableau, Columns); ok {
+		return &g.Tableau[index]
+	}
+	return nil
+}
+
+// pileIndex parses names like tableau3 into the index of the pile, 2
+func pileIndex(name, prefix string, count int) (int, bool) {
+	if !strings.HasPrefix(name, prefix) {
+		return 0, false
+	}
+	number, err := strconv.Atoi(strings.TrimPrefix(name, prefix))
+	if err != nil || number < 1 || number > count {
+		return 0, false
+	}
+	return number - 1, true
+}
+
+// canBuildFoundation tells if the card goes on the foundation: an ace on an empty one, otherwise the next card of
+// the same suit
+func canBuildFoundation(foundation []string, card string) error {
+	if len(foundation) == 0 {
+		if rank(card) != 1 {
+			return fmt.Errorf("only an ace can start a foundation, got %s", card)
+		}
+		return nil
+	}
+	top := foundation[len(foundation)-1]
+	if suit(card) != suit(top) || rank(card) != rank(top)+1 {
+		return fmt.Errorf("%s can't be moved onto %s", card, top)
+	}
+	return nil
+}
+
+// canBuildTableau tells if the card goes on the column: a king on an empty one, otherwise a card one lower and of the
+// other colour
+func canBuildTableau(column []string, card string) error {
+	if len(column) == 0 {
+		if rank(card) != len(repo.SequentialValues) {
+			return fmt.Errorf("only a king can go on an empty column, got %s", card)
+		}
+		return nil
+	}
+	top := column[len(column)-1]
+	if repo.SuitColors[suit(card)] == repo.SuitColors[suit(top)] || rank(card) != rank(top)-1 {
+		return fmt.Errorf("%s can't be moved onto %s", card, top)
+	}
+	return nil
+}
+
+func rank(card string) int {
+	return repo.Rank(repo.CardCode(card[:len(card)-1]))
+}
+
+func suit(card string) repo.SuitCode {
+	return repo.SuitCode(card[len(card)-1:])
+}
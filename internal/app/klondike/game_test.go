@@ -0,0 +1,144 @@
+package klondike
+
+import (
+	"github.com/deck/internal/app/repo"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func orderedDeck() []string {
+	var cards []string
+	for _, s := range repo.SequentialSuits {
+		for _, v := range repo.SequentialValues {
+			cards = append(cards, string(v)+string(s))
+		}
+	}
+	return cards
+}
+
+func TestDeal(t *testing.T) {
+	cards := orderedDeck()
+
+	// Test case: the tableau is dealt row by row, the rest goes to the stock
+	game, err := Deal(cards, 3)
+
+	assert.NoError(t, err)
+	for i, column := range game.Tableau {
+		assert.Len(t, column.Cards, i+1)
+		assert.Equal(t, 1, column.FaceUp)
+	}
+	assert.Equal(t, []string{"AS"}, game.Tableau[0].Cards)
+	assert.Equal(t, []string{"2S", "8S"}, game.Tableau[1].Cards)
+	assert.Equal(t, []string{"7S", "KS", "5D", "9D", "QD", "AC", "2C"}, game.Tableau[6].Cards)
+	assert.Equal(t, cards[28:], game.Stock)
+	assert.Empty(t, game.Waste)
+
+	// Test case: invalid deals
+	_, err = Deal(cards, 2)
+	assert.EqualError(t, err, "draw count must be 1 or 3")
+	_, err = Deal(cards[1:], 1)
+	assert.EqualError(t, err, "klondike needs 52 cards, got 51")
+}
+
+func TestMove(t *testing.T) {
+	game := &Game{DrawCount: 1, Layout: Layout{
+		Stock: []string{"AH", "2C"},
+		Tableau: [Columns]Column{
+			{Cards: []string{"5S", "KD"}, FaceUp: 1},
+			{Cards: []string{"QS"}, FaceUp: 1},
+			{Cards: []string{"JH"}, FaceUp: 1},
+			{Cards: []string{"10H"}, FaceUp: 1},
+		},
+	}}
+
+	// Test case: build down in alternating colours
+	assert.NoError(t, game.Move("tableau3", "tableau2", 1))
+	assert.Equal(t, Column{Cards: []string{"QS", "JH"}, FaceUp: 2}, game.Tableau[1])
+	assert.Equal(t, Column{Cards: []string{}, FaceUp: 0}, game.Tableau[2])
+	assert.EqualError(t, game.Move("tableau4", "tableau2", 1), "10H can't be moved onto JH")
+
+	// Test case: face up cards move together
+	assert.NoError(t, game.Move("tableau2", "tableau1", 2))
+	assert.Equal(t, Column{Cards: []string{"5S", "KD", "QS", "JH"}, FaceUp: 3}, game.Tableau[0])
+	assert.EqualError(t, game.Move("tableau1", "tableau3", 4), "tableau1 has 3 cards to move, got 4")
+
+	// Test case: only a king goes on an empty column, the card below is turned over
+	assert.EqualError(t, game.Move("tableau1", "tableau3", 2), "only a king can go on an empty column, got QS")
+	assert.NoError(t, game.Move("tableau1", "tableau3", 3))
+	assert.Equal(t, Column{Cards: []string{"5S"}, FaceUp: 1}, game.Tableau[0])
+
+	// Test case: foundations start with an ace and follow the suit
+	assert.NoError(t, game.Draw())
+	assert.Equal(t, []string{"AH"}, game.Waste)
+	assert.NoError(t, game.Move("waste", "foundation1", 1))
+	assert.Equal(t, []string{"AH"}, game.Foundations[0])
+	assert.EqualError(t, game.Move("tableau1", "foundation2", 1), "only an ace can start a foundation, got 5S")
+	assert.NoError(t, game.Draw())
+	assert.EqualError(t, game.Move("waste", "foundation1", 1), "2C can't be moved onto AH")
+
+	// Test case: invalid moves
+	assert.EqualError(t, game.Move("tableau8", "tableau1", 1), "unknown pile tableau8")
+	assert.EqualError(t, game.Move("waste", "stock", 1), "the stock and the waste only change by drawing")
+	assert.EqualError(t, game.Move("tableau3", "tableau3", 1), "cards can't be moved to the same pile")
+	assert.EqualError(t, game.Move("tableau3", "foundation2", 2), "only one card can be moved to a foundation")
+	assert.EqualError(t, game.Move("waste", "tableau2", 2), "only one card can be moved from the waste")
+	assert.EqualError(t, game.Move("waste", "tableau2", 0), "count must be positive")
+
+	// Test case: undo takes back the moves one by one
+	assert.Equal(t, 6, game.Moves)
+	assert.NoError(t, game.Undo())
+	assert.NoError(t, game.Undo())
+	assert.Equal(t, []string{"AH"}, game.Waste)
+	assert.Empty(t, game.Foundations[0])
+	assert.Equal(t, 4, game.Moves)
+}
+
+func TestDraw(t *testing.T) {
+	game := &Game{DrawCount: 3, Layout: Layout{Stock: []string{"AS", "2S", "3S", "4S", "5S"}}}
+
+	// Test case: three cards are turned at once, the last one is on top of the waste
+	assert.NoError(t, game.Draw())
+	assert.Equal(t, []string{"AS", "2S", "3S"}, game.Waste)
+	assert.NoError(t, game.Draw())
+	assert.Equal(t, []string{"AS", "2S", "3S", "4S", "5S"}, game.Waste)
+	assert.Empty(t, game.Stock)
+
+	// Test case: the waste is turned over into the stock
+	assert.NoError(t, game.Draw())
+	assert.Equal(t, []string{"AS", "2S", "3S", "4S", "5S"}, game.Stock)
+	assert.Empty(t, game.Waste)
+
+	// Test case: only the last moves can be undone
+	for i := 0; i < UndoDepth; i++ {
+		assert.NoError(t, game.Draw())
+	}
+	assert.Len(t, game.History, UndoDepth)
+	for i := 0; i < UndoDepth; i++ {
+		assert.NoError(t, game.Undo())
+	}
+	assert.EqualError(t, game.Undo(), "there is no move to undo")
+	assert.Equal(t, 3, game.Moves)
+
+	// Test case: nothing to draw
+	game = &Game{DrawCount: 1}
+	assert.EqualError(t, game.Draw(), "the stock and the waste are empty")
+	assert.EqualError(t, game.Undo(), "there is no move to undo")
+}
+
+func TestWin(t *testing.T) {
+	cards := orderedDeck()
+	game := &Game{DrawCount: 1, Layout: Layout{
+		Foundations: [Foundations][]string{cards[:13], cards[13:26], cards[26:39], cards[39:51]},
+		Tableau:     [Columns]Column{{Cards: []string{"KH"}, FaceUp: 1}},
+	}}
+
+	// Test case: the game is won once every card is on the foundations
+	assert.NoError(t, game.Move("tableau1", "foundation4", 1))
+	assert.True(t, game.Won)
+	assert.Equal(t, ErrGameWon, game.Draw())
+	assert.Equal(t, ErrGameWon, game.Move("foundation4", "tableau1", 1))
+
+	// Test case: a win can be taken back
+	assert.NoError(t, game.Undo())
+	assert.False(t, game.Won)
+}
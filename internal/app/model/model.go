@@ -1,24 +1,486 @@
 package model
 
+import (
+	"encoding/json"
+	"time"
+)
+
+// Position tells where cards are put into or taken from a deck
+type Position string
+
+const (
+	Top    Position = "top"
+	Bottom Position = "bottom"
+	Random Position = "random"
+)
+
+// CreateDeckRequest builds a shoe of DecksCount copies of the default deck or of Cards.
+// A positive CutCard places the cut card after that many cards.
+// DeckType picks the composition of the deck (standard, piquet, euchre, pinochle or stripped), Jokers adds a red and
+// a black joker to it. ClientSeed is mixed into the provably fair shuffle, Seed replaces its server seed to make the
+// shuffle reproducible. Owner tags the deck to find it again when listing decks. A positive TTL deletes the deck once
+// it has passed.
 type CreateDeckRequest struct {
-	Shuffled bool   `json:"shuffled"`
-	Cards    string `json:"cards"`
+	Shuffled   bool          `json:"shuffled"`
+	Seed       string        `json:"seed"`
+	ClientSeed string        `json:"client_seed"`
+	Cards      string        `json:"cards"`
+	DeckType   string        `json:"deck_type"`
+	Jokers     bool          `json:"jokers"`
+	DecksCount int           `json:"decks_count"`
+	CutCard    int           `json:"cut_card"`
+	Owner      string        `json:"owner"`
+	TTL        time.Duration `json:"ttl"`
 }
 
+// CloneDeckRequest copies a deck into a new one, owned by Owner or else by the owner of the deck. A positive TTL deletes
+// the clone once it has passed, the clone doesn't inherit the expiry of the deck.
+type CloneDeckRequest struct {
+	Owner string        `json:"owner"`
+	TTL   time.Duration `json:"ttl"`
+}
+
+// CreateDeckResponse commits to the server seed of a shuffled deck with its hash, before any card is dealt. ParentId
+// is the deck a clone was made from.
 type CreateDeckResponse struct {
-	DeckId    string `json:"deck_id"`
-	Shuffled  bool   `json:"shuffled"`
-	Remaining int    `json:"remaining"`
+	DeckId         string     `json:"deck_id"`
+	Shuffled       bool       `json:"shuffled"`
+	Remaining      int        `json:"remaining"`
+	DeckType       string     `json:"deck_type"`
+	Jokers         bool       `json:"jokers"`
+	DecksCount     int        `json:"decks_count"`
+	ServerSeedHash string     `json:"server_seed_hash,omitempty"`
+	ClientSeed     string     `json:"client_seed,omitempty"`
+	Owner          string     `json:"owner,omitempty"`
+	ParentId       *string    `json:"parent_id,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
 }
+
+// OpenDeckResponse reports the share of the shoe dealt so far as Penetration and whether the cut card was reached.
+// ServerSeed is only revealed once every card of the deck was dealt, Seed only when asked for. Version counts the
+// changes of the deck, ParentId is the deck a clone was made from.
 type OpenDeckResponse struct {
+	DeckId         string     `json:"deck_id"`
+	Shuffled       bool       `json:"shuffled"`
+	ShuffledAt     *time.Time `json:"shuffled_at,omitempty"`
+	Remaining      int        `json:"remaining"`
+	DeckType       string     `json:"deck_type"`
+	Jokers         bool       `json:"jokers"`
+	DecksCount     int        `json:"decks_count"`
+	CutCard        int        `json:"cut_card"`
+	Penetration    float64    `json:"penetration"`
+	CutCardReached bool       `json:"cut_card_reached"`
+	ServerSeedHash string     `json:"server_seed_hash,omitempty"`
+	ServerSeed     string     `json:"server_seed,omitempty"`
+	ClientSeed     string     `json:"client_seed,omitempty"`
+	Seed           string     `json:"seed,omitempty"`
+	Owner          string     `json:"owner,omitempty"`
+	ParentId       *string    `json:"parent_id,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	Version        int        `json:"version"`
+	Cards          []Card     `json:"cards"`
+	Drawn          []Card     `json:"drawn"`
+	Discarded      []Card     `json:"discarded"`
+}
+
+// ListDecksRequest filters the listed decks, unset fields don't filter. The time ranges include their start and exclude
+// their end, Empty lists the decks without remaining cards or, when false, the ones with some. ParentId lists the
+// clones of a deck. Cursor is the NextCursor of the previous page.
+type ListDecksRequest struct {
+	CreatedFrom *time.Time `json:"created_from"`
+	CreatedTo   *time.Time `json:"created_to"`
+	UpdatedFrom *time.Time `json:"updated_from"`
+	UpdatedTo   *time.Time `json:"updated_to"`
+	Shuffled    *bool      `json:"shuffled"`
+	Empty       *bool      `json:"empty"`
+	Owner       string     `json:"owner"`
+	ParentId    string     `json:"parent_id"`
+	Limit       int        `json:"limit"`
+	Cursor      string     `json:"cursor"`
+}
+
+// DeckSummary describes a deck without its cards
+type DeckSummary struct {
+	DeckId     string     `json:"deck_id"`
+	Owner      string     `json:"owner,omitempty"`
+	ParentId   *string    `json:"parent_id,omitempty"`
+	Shuffled   bool       `json:"shuffled"`
+	Remaining  int        `json:"remaining"`
+	Size       int        `json:"size"`
+	DeckType   string     `json:"deck_type"`
+	Jokers     bool       `json:"jokers"`
+	DecksCount int        `json:"decks_count"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// ListDecksResponse is a page of decks, oldest first. NextCursor is empty on the last page.
+type ListDecksResponse struct {
+	Decks      []DeckSummary `json:"decks"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// DeckEventResponse is an operation on a deck, Number counts them from 1. Payload holds its parameters and the cards
+// it touched.
+type DeckEventResponse struct {
+	Number    int             `json:"number"`
+	Type      string          `json:"type"`
+	Actor     string          `json:"actor"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// DeckHistoryResponse is a page of a deck's events, the next page follows the NextAfter event
+type DeckHistoryResponse struct {
+	DeckId    string              `json:"deck_id"`
+	Events    []DeckEventResponse `json:"events"`
+	NextAfter int                 `json:"next_after,omitempty"`
+}
+
+// DeckStateResponse is a deck with its piles as they were right after the Event
+type DeckStateResponse struct {
+	Event DeckEventResponse `json:"event"`
+	Deck  OpenDeckResponse  `json:"deck"`
+	Piles []PileResponse    `json:"piles"`
+}
+
+// SnapshotResponse is a named state of a deck, saved when the deck was at Version
+type SnapshotResponse struct {
+	DeckId    string    `json:"deck_id"`
+	Name      string    `json:"name"`
+	Version   int       `json:"version"`
+	Actor     string    `json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ListSnapshotsResponse lists the snapshots of a deck, oldest first
+type ListSnapshotsResponse struct {
+	DeckId    string             `json:"deck_id"`
+	Snapshots []SnapshotResponse `json:"snapshots"`
+}
+
+// UndoRequest reverts the last operation on a deck. With a Version the deck must still be at that version, so an
+// operation somebody else made meanwhile isn't undone instead.
+type UndoRequest struct {
+	Version *int `json:"version"`
+}
+
+// DeckOperationResponse is returned by peek, cut and insert. Position is where the deck was cut or the card was
+// inserted, Cards are the peeked or inserted cards.
+type DeckOperationResponse struct {
 	DeckId    string `json:"deck_id"`
-	Shuffled  bool   `json:"shuffled"`
 	Remaining int    `json:"remaining"`
+	Position  int    `json:"position"`
 	Cards     []Card `json:"cards"`
 }
 
+// ReturnCardsRequest puts drawn or discarded cards back into the deck. No cards means the whole discard pile.
+type ReturnCardsRequest struct {
+	Cards    []string `json:"cards"`
+	Position Position `json:"position"`
+	Shuffle  bool     `json:"shuffle"`
+}
+
+// Card is a playing card. Rank orders the values from 1 for the ace to 13 for the king, or to 14 when aces rank high,
+// jokers rank 0. Color is RED or BLACK, Symbol is the card's character in Unicode.
 type Card struct {
-	Value string `json:"value"`
-	Suit  string `json:"suit"`
-	Code  string `json:"code"`
+	Value  string `json:"value"`
+	Suit   string `json:"suit"`
+	Code   string `json:"code"`
+	Rank   int    `json:"rank"`
+	Color  string `json:"color"`
+	Symbol string `json:"symbol"`
+}
+
+type SortOrder string
+
+const (
+	BySuit SortOrder = "suit"
+	ByRank SortOrder = "rank"
+)
+
+// SortRequest orders cards by suit then rank, or by rank then suit. Suits follow the order of a new deck, spades,
+// diamonds, clubs and hearts, and jokers come last. AceHigh ranks aces above kings.
+type SortRequest struct {
+	By      SortOrder `json:"by"`
+	AceHigh bool      `json:"ace_high"`
+}
+
+// DeckSeedResponse tells which seeds produced the order of a deck
+type DeckSeedResponse struct {
+	DeckId     string `json:"deck_id"`
+	Seed       string `json:"seed"`
+	ClientSeed string `json:"client_seed"`
+	Seeded     bool   `json:"seeded"`
+}
+
+// VerifyDeckResponse replays the shuffle of InitialCards with the revealed seeds, giving the order Cards were dealt in
+type VerifyDeckResponse struct {
+	DeckId         string `json:"deck_id"`
+	ServerSeed     string `json:"server_seed"`
+	ServerSeedHash string `json:"server_seed_hash"`
+	ClientSeed     string `json:"client_seed"`
+	InitialCards   []Card `json:"initial_cards"`
+	Cards          []Card `json:"cards"`
+}
+
+type PileResponse struct {
+	DeckId    string `json:"deck_id"`
+	Name      string `json:"name"`
+	Remaining int    `json:"remaining"`
+	Cards     []Card `json:"cards"`
+}
+
+// MoveCardsRequest moves either the given cards or count cards from the top of the deck, or of the From pile when set
+type MoveCardsRequest struct {
+	From  string   `json:"from"`
+	Count int      `json:"count"`
+	Cards []string `json:"cards"`
+}
+
+// HandResponse is the best 5 card poker hand of the evaluated cards. A higher score wins, equal scores split the pot.
+type HandResponse struct {
+	Category    string `json:"category"`
+	Description string `json:"description"`
+	Score       int    `json:"score"`
+	Cards       []Card `json:"cards"`
+}
+
+type PlayerCards struct {
+	Name  string   `json:"name"`
+	Cards []string `json:"cards"`
+}
+
+// ShowdownRequest holds every player's hole cards and the community cards they share
+type ShowdownRequest struct {
+	Players []PlayerCards `json:"players"`
+	Board   []string      `json:"board"`
+}
+
+type PlayerHandResponse struct {
+	Name      string       `json:"name"`
+	HoleCards []Card       `json:"hole_cards"`
+	Hand      HandResponse `json:"hand"`
+	Winner    bool         `json:"winner"`
+}
+
+// ShowdownResponse names the winners of the pot, Split is set when more than one player holds the best hand
+type ShowdownResponse struct {
+	DeckId  string               `json:"deck_id,omitempty"`
+	Board   []Card               `json:"board"`
+	Players []PlayerHandResponse `json:"players"`
+	Winners []string             `json:"winners"`
+	Split   bool                 `json:"split"`
+}
+
+// EquityPlayer holds either known hole cards or a Range like "QQ+,AKs" (or "random") they are dealt from
+type EquityPlayer struct {
+	Name  string   `json:"name"`
+	Cards []string `json:"cards"`
+	Range string   `json:"range"`
+}
+
+// EquityRequest asks the win and tie probabilities of Hold'em players given the board so far. Iterations is the number
+// of Monte Carlo samples when the runouts aren't enumerated exactly, a Seed makes the sampling reproducible.
+type EquityRequest struct {
+	Players    []EquityPlayer `json:"players"`
+	Board      []string       `json:"board"`
+	Iterations int            `json:"iterations"`
+	Seed       string         `json:"seed"`
+}
+
+type PlayerEquityResponse struct {
+	Name   string  `json:"name"`
+	Cards  []Card  `json:"cards,omitempty"`
+	Range  string  `json:"range,omitempty"`
+	Win    float64 `json:"win"`
+	Tie    float64 `json:"tie"`
+	Equity float64 `json:"equity"`
+}
+
+type EquityResponse struct {
+	Exact   bool                   `json:"exact"`
+	Runouts int                    `json:"runouts"`
+	Players []PlayerEquityResponse `json:"players"`
+}
+
+// CreateBlackjackRequest deals a new blackjack game. Without a DeckId a new shuffled shoe of DecksCount decks is
+// created, otherwise the game is dealt from the shoe of an earlier game, which is reshuffled once its cut card was
+// reached.
+type CreateBlackjackRequest struct {
+	Bet              int    `json:"bet"`
+	DeckId           string `json:"deck_id"`
+	DecksCount       int    `json:"decks_count"`
+	DealerHitsSoft17 bool   `json:"dealer_hits_soft17"`
+}
+
+type BlackjackHandResponse struct {
+	Cards   []Card `json:"cards"`
+	Value   int    `json:"value"`
+	Soft    bool   `json:"soft"`
+	Bet     int    `json:"bet"`
+	Doubled bool   `json:"doubled"`
+	Done    bool   `json:"done"`
+	Outcome string `json:"outcome,omitempty"`
+	Payout  int    `json:"payout"`
+}
+
+// BlackjackGameResponse shows the dealer's hole card only once the game is settled. Actions are the moves the player
+// can make next.
+type BlackjackGameResponse struct {
+	GameId           string                  `json:"game_id"`
+	DeckId           string                  `json:"deck_id"`
+	Status           string                  `json:"status"`
+	DealerHitsSoft17 bool                    `json:"dealer_hits_soft17"`
+	Hands            []BlackjackHandResponse `json:"hands"`
+	ActiveHand       int                     `json:"active_hand"`
+	Dealer           []Card                  `json:"dealer"`
+	DealerValue      int                     `json:"dealer_value"`
+	Insurance        int                     `json:"insurance"`
+	InsurancePayout  int                     `json:"insurance_payout"`
+	Net              int                     `json:"net"`
+	Actions          []string                `json:"actions"`
+}
+
+type HoldemPlayer struct {
+	Name  string `json:"name"`
+	Stack int    `json:"stack"`
+}
+
+// CreateHoldemRequest seats the players at a new Hold'em table in the given order, the first hand is dealt right away
+type CreateHoldemRequest struct {
+	SmallBlind int            `json:"small_blind"`
+	BigBlind   int            `json:"big_blind"`
+	Players    []HoldemPlayer `json:"players"`
+}
+
+// HoldemActionRequest is a player's move, Token proves the request comes from the player. Amount is only used by raises
+// and is the total bet of the player on the street.
+type HoldemActionRequest struct {
+	Player string `json:"player"`
+	Token  string `json:"token"`
+	Action string `json:"action"`
+	Amount int    `json:"amount"`
+}
+
+// HoldemSeatResponse is a player dealt into the hand. Cards are only shown to their owner, and to everybody once the
+// hand went to showdown.
+type HoldemSeatResponse struct {
+	Name      string `json:"name"`
+	Stack     int    `json:"stack"`
+	Cards     []Card `json:"cards,omitempty"`
+	Bet       int    `json:"bet"`
+	Committed int    `json:"committed"`
+	Folded    bool   `json:"folded"`
+	AllIn     bool   `json:"all_in"`
+}
+
+type HoldemPotResponse struct {
+	Amount   int      `json:"amount"`
+	Eligible []string `json:"eligible"`
+	Winners  []string `json:"winners"`
+	Hand     string   `json:"hand,omitempty"`
+}
+
+type HoldemEventResponse struct {
+	Hand   int    `json:"hand"`
+	Street string `json:"street"`
+	Player string `json:"player,omitempty"`
+	Action string `json:"action"`
+	Amount int    `json:"amount,omitempty"`
+	Cards  []Card `json:"cards,omitempty"`
+}
+
+// HoldemTableResponse is the table as seen by one player. ToAct is the player whose turn it is, Actions the moves they
+// can make and MinRaiseTo the smallest amount they can raise to. Pots are only known once the hand is finished. History holds every hand played at the table.
+// Tokens are only returned when the table is created, every player shows the table and acts with their own token.
+type HoldemTableResponse struct {
+	TableId    string                `json:"table_id"`
+	DeckId     string                `json:"deck_id"`
+	SmallBlind int                   `json:"small_blind"`
+	BigBlind   int                   `json:"big_blind"`
+	Players    []HoldemPlayer        `json:"players"`
+	Button     string                `json:"button"`
+	HandNumber int                   `json:"hand_number"`
+	Street     string                `json:"street"`
+	Board      []Card                `json:"board"`
+	Seats      []HoldemSeatResponse  `json:"seats"`
+	ToAct      string                `json:"to_act,omitempty"`
+	CurrentBet int                   `json:"current_bet"`
+	MinRaiseTo int                   `json:"min_raise_to"`
+	Pot        int                   `json:"pot"`
+	Pots       []HoldemPotResponse   `json:"pots"`
+	Finished   bool                  `json:"finished"`
+	Actions    []string              `json:"actions"`
+	History    []HoldemEventResponse `json:"history"`
+	Tokens     map[string]string     `json:"tokens,omitempty"`
+}
+
+// CreateWarRequest starts a game of war on a new shuffled deck, optionally from a Seed to replay it. The game ends
+// after MaxRounds rounds if no player ran out of cards before.
+type CreateWarRequest struct {
+	MaxRounds int    `json:"max_rounds"`
+	Seed      string `json:"seed"`
+}
+
+// WarRoundResponse is a round of war. Cards are the cards each player turned face up, Counts the cards they hold after
+// the round.
+type WarRoundResponse struct {
+	Number   int               `json:"number"`
+	Cards    map[string][]Card `json:"cards"`
+	FaceDown int               `json:"face_down"`
+	Wars     int               `json:"wars"`
+	Winner   string            `json:"winner,omitempty"`
+	Won      int               `json:"won"`
+	Counts   map[string]int    `json:"counts"`
+}
+
+// WarGameResponse is the state of a game of war after Round rounds. Winner is empty while the game goes on and after a
+// draw, Rounds are the rounds played by the request.
+type WarGameResponse struct {
+	GameId    string             `json:"game_id"`
+	DeckId    string             `json:"deck_id"`
+	MaxRounds int                `json:"max_rounds"`
+	Round     int                `json:"round"`
+	Counts    map[string]int     `json:"counts"`
+	Finished  bool               `json:"finished"`
+	Winner    string             `json:"winner,omitempty"`
+	Rounds    []WarRoundResponse `json:"rounds"`
+}
+
+// CreateKlondikeRequest deals Klondike from a new shuffled deck, optionally from a Seed to replay the same deal.
+// DrawCount is the number of cards turned from the stock at once, 1 or 3.
+type CreateKlondikeRequest struct {
+	DrawCount int    `json:"draw_count"`
+	Seed      string `json:"seed"`
+}
+
+// KlondikeMoveRequest moves Count cards between piles named waste, foundation1 - 4 and tableau1 - 7
+type KlondikeMoveRequest struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Count int    `json:"count"`
+}
+
+// KlondikeColumnResponse is a column of the tableau, Hidden is the number of face down cards below Cards
+type KlondikeColumnResponse struct {
+	Hidden int    `json:"hidden"`
+	Cards  []Card `json:"cards"`
+}
+
+// KlondikeGameResponse shows the face up cards of a Klondike game. Stock and WasteCount are the number of cards in
+// those piles, Waste the cards of the waste that can be seen, the last one is on top.
+type KlondikeGameResponse struct {
+	GameId      string                   `json:"game_id"`
+	DeckId      string                   `json:"deck_id"`
+	DrawCount   int                      `json:"draw_count"`
+	Stock       int                      `json:"stock"`
+	Waste       []Card                   `json:"waste"`
+	WasteCount  int                      `json:"waste_count"`
+	Foundations [][]Card                 `json:"foundations"`
+	Tableau     []KlondikeColumnResponse `json:"tableau"`
+	Moves       int                      `json:"moves"`
+	CanUndo     bool                     `json:"can_undo"`
+	Won         bool                     `json:"won"`
 }
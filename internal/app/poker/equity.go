@@ -0,0 +1,316 @@
+package poker
+
+import (
+	"fmt"
+	"github.com/deck/internal/app/repo"
+	"strings"
+)
+
+// MaxExactRunouts is the most board runouts enumerated exactly, beyond that the equity is sampled with Monte Carlo
+const MaxExactRunouts = 50000
+
+// maxDealAttempts bounds dealing hole cards from ranges that collide with each other
+const maxDealAttempts = 1000
+
+// Random picks the Monte Carlo samples, a seeded one makes the calculation reproducible
+type Random interface {
+	Intn(n int) int
+}
+
+// Seat is a Hold'em player in an equity calculation. Either the hole cards are known, or they are dealt from Range.
+type Seat struct {
+	Cards []Card
+	Range [][2]Card
+}
+
+// Equity tells how often a seat wins the pot alone and how often it splits it. Equity is the share of the pot won on
+// average, a split between n players counting 1/n.
+type Equity struct {
+	Win    float64
+	Tie    float64
+	Equity float64
+}
+
+type EquityResult struct {
+	Seats   []Equity
+	Runouts int
+	Exact   bool
+}
+
+// CalculateEquity deals the rest of the board from the unseen cards and counts the winners of every runout. When all
+// hole cards are known and there are at most MaxExactRunouts runouts they are all enumerated, otherwise iterations
+// random runouts are sampled.
+func CalculateEquity(seats []Seat, board []Card, unseen []Card, random Random, iterations int) (EquityResult, error) {
+	if len(seats) < 2 {
+		return EquityResult{}, fmt.Errorf("equity needs at least 2 players")
+	}
+	if len(board) != 0 && (len(board) < 3 || len(board) > 5) {
+		return EquityResult{}, fmt.Errorf("the board must have 0, 3, 4 or 5 cards, got %d", len(board))
+	}
+
+	index := make(map[Card]int, len(unseen))
+	for i, c := range unseen {
+		index[c] = i
+	}
+	// hole cards of every seat as indexes into unseen, or -1 for known cards
+	holes := make([][][2]int, len(seats))
+	known := true
+	for i, seat := range seats {
+		switch {
+		case len(seat.Cards) == 2:
+			holes[i] = [][2]int{{-1, -1}}
+		case len(seat.Cards) == 0:
+			known = false
+			for _, combo := range seat.Range {
+				first, found := index[combo[0]]
+				second, alsoFound := index[combo[1]]
+				if found && alsoFound {
+					holes[i] = append(holes[i], [2]int{first, second})
+				}
+			}
+			if len(holes[i]) == 0 {
+				return EquityResult{}, fmt.Errorf("no hand of player %d's range can be dealt", i+1)
+			}
+		default:
+			return EquityResult{}, fmt.Errorf("player %d needs 2 hole cards or a range", i+1)
+		}
+	}
+
+	calc := equityCalculation{
+		seats:  seats,
+		board:  board,
+		unseen: unseen,
+		holes:  holes,
+		scores: make([]int, len(seats)),
+		result: EquityResult{Seats: make([]Equity, len(seats))},
+	}
+	missing := 5 - len(board)
+	if known && combinations(len(unseen), missing) <= MaxExactRunouts {
+		calc.result.Exact = true
+		calc.enumerate(make([]int, 0, missing), 0, missing)
+	} else {
+		if iterations <= 0 {
+			return EquityResult{}, fmt.Errorf("iterations must be positive")
+		}
+		for i := 0; i < iterations; i++ {
+			if err := calc.sample(random, missing); err != nil {
+				return EquityResult{}, err
+			}
+		}
+	}
+
+	for i := range calc.result.Seats {
+		e := &calc.result.Seats[i]
+		e.Win /= float64(calc.result.Runouts)
+		e.Tie /= float64(calc.result.Runouts)
+		e.Equity /= float64(calc.result.Runouts)
+	}
+	return calc.result, nil
+}
+
+type equityCalculation struct {
+	seats  []Seat
+	board  []Card
+	unseen []Card
+	holes  [][][2]int
+	scores []int
+	result EquityResult
+}
+
+// enumerate goes through every runout of missing cards, all hole cards being known
+func (c *equityCalculation) enumerate(runout []int, start, missing int) {
+	if len(runout) == missing {
+		c.tally(c.holeCards(make([][2]int, len(c.seats))), runout)
+		return
+	}
+	for i := start; i <= len(c.unseen)-(missing-len(runout)); i++ {
+		c.enumerate(append(runout, i), i+1, missing)
+	}
+}
+
+// sample deals the hole cards of the ranges and a random runout from the cards left
+func (c *equityCalculation) sample(random Random, missing int) error {
+	used := make([]bool, len(c.unseen))
+	dealt := make([][2]int, len(c.seats))
+	for i, combos := range c.holes {
+		if len(c.seats[i].Cards) > 0 {
+			continue
+		}
+		attempts := 0
+		for {
+			combo := combos[random.Intn(len(combos))]
+			if !used[combo[0]] && !used[combo[1]] {
+				used[combo[0]], used[combo[1]] = true, true
+				dealt[i] = combo
+				break
+			}
+			if attempts++; attempts == maxDealAttempts {
+				return fmt.Errorf("the players' ranges can't be dealt together")
+			}
+		}
+	}
+
+	left := make([]int, 0, len(c.unseen))
+	for i := range c.unseen {
+		if !used[i] {
+			left = append(left, i)
+		}
+	}
+	if len(left) < missing {
+		return fmt.Errorf("not enough cards left to deal the board")
+	}
+	// a partial Fisher-Yates shuffle, only the missing cards are needed
+	for i := 0; i < missing; i++ {
+		j := i + random.Intn(len(left)-i)
+		left[i], left[j] = left[j], left[i]
+	}
+	c.tally(c.holeCards(dealt), left[:missing])
+	return nil
+}
+
+func (c *equityCalculation) holeCards(dealt [][2]int) [][]Card {
+	cards := make([][]Card, len(c.seats))
+	for i, seat := range c.seats {
+		if len(seat.Cards) > 0 {
+			cards[i] = seat.Cards
+		} else {
+			cards[i] = []Card{c.unseen[dealt[i][0]], c.unseen[dealt[i][1]]}
+		}
+	}
+	return cards
+}
+
+// tally scores every seat for one runout and credits the winners
+func (c *equityCalculation) tally(holeCards [][]Card, runout []int) {
+	hand := make([]Card, 0, 7)
+	best, winners := -1, 0
+	for i, hole := range holeCards {
+		hand = append(append(hand[:0], hole...), c.board...)
+		for _, r := range runout {
+			hand = append(hand, c.unseen[r])
+		}
+		_, c.scores[i] = bestFive(hand)
+		switch {
+		case c.scores[i] > best:
+			best, winners = c.scores[i], 1
+		case c.scores[i] == best:
+			winners++
+		}
+	}
+	for i, score := range c.scores {
+		if score != best {
+			continue
+		}
+		e := &c.result.Seats[i]
+		if winners == 1 {
+			e.Win++
+		} else {
+			e.Tie++
+		}
+		e.Equity += 1 / float64(winners)
+	}
+	c.result.Runouts++
+}
+
+func combinations(n, k int) int {
+	res := 1
+	for i := 0; i < k; i++ {
+		res = res * (n - i) / (i + 1)
+		if res > MaxExactRunouts {
+			return res
+		}
+	}
+	return res
+}
+
+// ParseRange parses a comma separated range of starting hands like "QQ+,AKs,AQo,KJ". Pairs name the value twice,
+// suited (s) and offsuit (o) hands can be narrowed down and + adds every better kicker, or every higher pair. "random"
+// is every possible hand.
+func ParseRange(spec string) ([][2]Card, error) {
+	var combos [][2]Card
+	seen := make(map[[2]Card]bool)
+	add := func(first, second repo.CardCode, suited, offsuit bool) {
+		for i, s1 := range repo.SequentialSuits {
+			for j, s2 := range repo.SequentialSuits {
+				if (first == second && j <= i) || (suited && s1 != s2) || (offsuit && s1 == s2) {
+					continue
+				}
+				combo := [2]Card{{Value: first, Suit: s1}, {Value: second, Suit: s2}}
+				if !seen[combo] {
+					seen[combo] = true
+					combos = append(combos, combo)
+				}
+			}
+		}
+	}
+
+	for _, part := range strings.Split(strings.ToUpper(spec), ",") {
+		part = strings.TrimSpace(part)
+		if part == "RANDOM" {
+			byRank := valuesByRank()
+			for i, first := range byRank {
+				for _, second := range byRank[:i+1] {
+					add(first, second, false, false)
+				}
+			}
+			continue
+		}
+		plus := strings.HasSuffix(part, "+")
+		part = strings.TrimSuffix(part, "+")
+		suited := strings.HasSuffix(part, "S")
+		offsuit := strings.HasSuffix(part, "O")
+		if suited || offsuit {
+			part = part[:len(part)-1]
+		}
+		first, second, err := parseRangeValues(part)
+		if err != nil {
+			return nil, err
+		}
+		if first == second && (suited || offsuit) {
+			return nil, fmt.Errorf("invalid range %s, pairs can't be suited", part)
+		}
+		if ranks[first] < ranks[second] {
+			first, second = second, first
+		}
+
+		add(first, second, suited, offsuit)
+		if !plus {
+			continue
+		}
+		for _, v := range valuesByRank() {
+			switch {
+			case first == second && ranks[v] > ranks[first]:
+				add(v, v, false, false)
+			case first != second && ranks[v] > ranks[second] && ranks[v] < ranks[first]:
+				add(first, v, suited, offsuit)
+			}
+		}
+	}
+	return combos, nil
+}
+
+// parseRangeValues splits two card values like AK, 10J or TJ, T standing for 10 like in the usual range notation
+func parseRangeValues(part string) (repo.CardCode, repo.CardCode, error) {
+	part = strings.ReplaceAll(part, "T", string(repo.Ten))
+	for i := 1; i < len(part); i++ {
+		first, second := repo.CardCode(part[:i]), repo.CardCode(part[i:])
+		_, firstFound := ranks[first]
+		_, secondFound := ranks[second]
+		if firstFound && secondFound {
+			return first, second, nil
+		}
+	}
+	return "", "", fmt.Errorf("invalid range %s", part)
+}
+
+func valuesByRank() []repo.CardCode {
+	values := make([]repo.CardCode, 0, len(ranks))
+	for r := twoRank; r <= aceRank; r++ {
+		for v, rank := range ranks {
+			if rank == r {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
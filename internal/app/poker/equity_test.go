@@ -0,0 +1,132 @@
+package poker
+
+import (
+	"github.com/deck/internal/app/repo"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+// unseenCards is the 52 card deck without the known cards
+func unseenCards(known ...[]Card) []Card {
+	dealt := make(map[Card]bool)
+	for _, cards := range known {
+		for _, c := range cards {
+			dealt[c] = true
+		}
+	}
+	var unseen []Card
+	for _, s := range repo.SequentialSuits {
+		for _, v := range repo.SequentialValues {
+			if c := (Card{Value: v, Suit: s}); !dealt[c] {
+				unseen = append(unseen, c)
+			}
+		}
+	}
+	return unseen
+}
+
+func TestCalculateEquityExact(t *testing.T) {
+	aces, kings := parse(t, "AS,AD"), parse(t, "KS,KD")
+	board := parse(t, "2C,7H,9D,JC")
+
+	// Test case: on the turn kings only win with one of the 2 kings left in the 44 unseen cards
+	res, err := CalculateEquity([]Seat{{Cards: aces}, {Cards: kings}}, board, unseenCards(aces, kings, board), nil, 0)
+	assert.NoError(t, err)
+	assert.True(t, res.Exact)
+	assert.Equal(t, 44, res.Runouts)
+	assert.InDelta(t, 42.0/44, res.Seats[0].Equity, 1e-9)
+	assert.InDelta(t, 2.0/44, res.Seats[1].Win, 1e-9)
+	assert.Equal(t, 0.0, res.Seats[1].Tie)
+
+	// Test case: both players play the straight on the board and split every runout
+	first, second := parse(t, "2S,3S"), parse(t, "2D,3D")
+	board = parse(t, "10C,JH,QD,KC,AH")
+	res, err = CalculateEquity([]Seat{{Cards: first}, {Cards: second}}, board, unseenCards(first, second, board), nil, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.Runouts)
+	assert.Equal(t, Equity{Win: 0, Tie: 1, Equity: 0.5}, res.Seats[0])
+	assert.Equal(t, res.Seats[0], res.Seats[1])
+}
+
+func TestCalculateEquityMonteCarlo(t *testing.T) {
+	aces, kings := parse(t, "AS,AD"), parse(t, "KS,KD")
+	unseen := unseenCards(aces, kings)
+	seats := []Seat{{Cards: aces}, {Cards: kings}}
+
+	// Test case: preflop there are too many runouts, so they are sampled. Aces win about 82% against kings.
+	res, err := CalculateEquity(seats, nil, unseen, rand.New(rand.NewSource(1)), 5000)
+	assert.NoError(t, err)
+	assert.False(t, res.Exact)
+	assert.Equal(t, 5000, res.Runouts)
+	assert.InDelta(t, 0.82, res.Seats[0].Equity, 0.03)
+	assert.InDelta(t, 1, res.Seats[0].Equity+res.Seats[1].Equity, 1e-9)
+
+	// Test case: the same seed gives the same result
+	again, err := CalculateEquity(seats, nil, unseen, rand.New(rand.NewSource(1)), 5000)
+	assert.NoError(t, err)
+	assert.Equal(t, res, again)
+
+	// Test case: aces against a random hand win about 85%
+	random, err := ParseRange("random")
+	assert.NoError(t, err)
+	res, err = CalculateEquity([]Seat{{Cards: aces}, {Range: random}}, nil, unseenCards(aces), rand.New(rand.NewSource(2)), 5000)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.85, res.Seats[0].Equity, 0.03)
+
+	// Test case: a range with every hand blocked by the known cards
+	onlyAces, err := ParseRange("AA")
+	assert.NoError(t, err)
+	_, err = CalculateEquity([]Seat{{Cards: parse(t, "AS,AD")}, {Cards: parse(t, "AC,KD")}, {Range: onlyAces}}, nil,
+		unseenCards(parse(t, "AS,AD,AC,KD")), rand.New(rand.NewSource(1)), 100)
+	assert.EqualError(t, err, "no hand of player 3's range can be dealt")
+}
+
+func TestCalculateEquityInvalid(t *testing.T) {
+	aces, kings := parse(t, "AS,AD"), parse(t, "KS,KD")
+	unseen := unseenCards(aces, kings)
+
+	// Test case: a single player
+	_, err := CalculateEquity([]Seat{{Cards: aces}}, nil, unseen, nil, 100)
+	assert.EqualError(t, err, "equity needs at least 2 players")
+
+	// Test case: a board with 2 cards
+	_, err = CalculateEquity([]Seat{{Cards: aces}, {Cards: kings}}, parse(t, "2C,3C"), unseen, nil, 100)
+	assert.EqualError(t, err, "the board must have 0, 3, 4 or 5 cards, got 2")
+
+	// Test case: a player without cards or range
+	_, err = CalculateEquity([]Seat{{Cards: aces}, {Cards: parse(t, "KS")}}, nil, unseen, nil, 100)
+	assert.EqualError(t, err, "player 2 needs 2 hole cards or a range")
+
+	// Test case: sampling without iterations
+	_, err = CalculateEquity([]Seat{{Cards: aces}, {Cards: kings}}, nil, unseen, rand.New(rand.NewSource(1)), 0)
+	assert.EqualError(t, err, "iterations must be positive")
+}
+
+func TestParseRange(t *testing.T) {
+	tests := map[string]int{
+		"AA":         6,
+		"QQ+":        18,
+		"AKs":        4,
+		"AKo":        12,
+		"AK":         16,
+		"KA":         16,
+		"ATs+":       16,
+		"10Js":       4,
+		"22+":        78,
+		"AKs, AK":    16,
+		"random":     1326,
+		"QQ+,AKs,AQ": 18 + 4 + 16,
+	}
+	for spec, count := range tests {
+		combos, err := ParseRange(spec)
+		assert.NoError(t, err, spec)
+		assert.Len(t, combos, count, spec)
+	}
+
+	// Test case: invalid ranges
+	for _, spec := range []string{"AX", "QQs", "A", ""} {
+		_, err := ParseRange(spec)
+		assert.NotNil(t, err, spec)
+	}
+}
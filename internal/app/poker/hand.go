@@ -0,0 +1,251 @@
+package poker
+
+import (
+	"fmt"
+	"github.com/deck/internal/app/repo"
+	"sort"
+	"strings"
+)
+
+type Category int
+
+const (
+	HighCard Category = iota
+	OnePair
+	TwoPair
+	ThreeOfAKind
+	Straight
+	Flush
+	FullHouse
+	FourOfAKind
+	StraightFlush
+	RoyalFlush
+)
+
+var categoryNames = map[Category]string{
+	HighCard:      "HIGH_CARD",
+	OnePair:       "ONE_PAIR",
+	TwoPair:       "TWO_PAIR",
+	ThreeOfAKind:  "THREE_OF_A_KIND",
+	Straight:      "STRAIGHT",
+	Flush:         "FLUSH",
+	FullHouse:     "FULL_HOUSE",
+	FourOfAKind:   "FOUR_OF_A_KIND",
+	StraightFlush: "STRAIGHT_FLUSH",
+	RoyalFlush:    "ROYAL_FLUSH",
+}
+
+func (c Category) String() string {
+	return categoryNames[c]
+}
+
+// ranks orders the card values for poker, aces are high except in the wheel (A-2-3-4-5)
+var ranks = map[repo.CardCode]int{
+	repo.Two:   2,
+	repo.Three: 3,
+	repo.Four:  4,
+	repo.Five:  5,
+	repo.Six:   6,
+	repo.Seven: 7,
+	repo.Eight: 8,
+	repo.Nine:  9,
+	repo.Ten:   10,
+	repo.Jack:  11,
+	repo.Queen: 12,
+	repo.King:  13,
+	repo.Ace:   14,
+}
+
+var (
+	aceRank  = ranks[repo.Ace]
+	fiveRank = ranks[repo.Five]
+	twoRank  = ranks[repo.Two]
+)
+
+type Card struct {
+	Value repo.CardCode
+	Suit  repo.SuitCode
+}
+
+func (c Card) Code() string {
+	return fmt.Sprintf("%s%s", c.Value, c.Suit)
+}
+
+func (c Card) rank() int {
+	return ranks[c.Value]
+}
+
+// Hand is the best 5 card hand of a player. Score orders hands: a higher score wins, equal scores split.
+type Hand struct {
+	Category Category
+	Score    int
+	Cards    []Card
+}
+
+// ParseCard parses a card code like 10H, jokers can't be part of a poker hand
+func ParseCard(code string) (Card, error) {
+	code = strings.ToUpper(code)
+	if len(code) < 2 {
+		return Card{}, fmt.Errorf("invalid card code %s", code)
+	}
+	value := repo.CardCode(code[:len(code)-1])
+	suit := repo.SuitCode(code[len(code)-1:])
+	if _, found := ranks[value]; !found {
+		return Card{}, fmt.Errorf("invalid card code %s", code)
+	}
+	if _, found := repo.Suites[suit]; !found {
+		return Card{}, fmt.Errorf("invalid card code %s", code)
+	}
+	return Card{Value: value, Suit: suit}, nil
+}
+
+// ParseCards parses card codes and rejects duplicates, since a standard deck has every card once
+func ParseCards(codes []string) ([]Card, error) {
+	cards := make([]Card, len(codes))
+	seen := make(map[Card]bool, len(codes))
+	for i, code := range codes {
+		card, err := ParseCard(code)
+		if err != nil {
+			return nil, err
+		}
+		if seen[card] {
+			return nil, fmt.Errorf("card %s is given more than once", card.Code())
+		}
+		seen[card] = true
+		cards[i] = card
+	}
+	return cards, nil
+}
+
+// Evaluate ranks 5 to 7 cards by their best 5 card hand
+func Evaluate(cards []Card) (Hand, error) {
+	if len(cards) < 5 || len(cards) > 7 {
+		return Hand{}, fmt.Errorf("a hand needs 5 - 7 cards, got %d", len(cards))
+	}
+	best, _ := bestFive(cards)
+	var five [5]Card
+	for i, p := range best {
+		five[i] = cards[p]
+	}
+	return evaluateFive(five), nil
+}
+
+// bestFive returns the indexes of the best 5 of the cards and their score
+func bestFive(cards []Card) ([5]int, int) {
+	cardRanks := make([]int, len(cards))
+	for i, c := range cards {
+		cardRanks[i] = c.rank()
+	}
+	var best, picks [5]int
+	bestScore := -1
+	var choose func(start, picked int)
+	choose = func(start, picked int) {
+		if picked == 5 {
+			var fiveRanks [5]int
+			flush := true
+			for i, p := range picks {
+				fiveRanks[i] = cardRanks[p]
+				flush = flush && cards[p].Suit == cards[picks[0]].Suit
+			}
+			if _, score := scoreFive(fiveRanks, flush); score > bestScore {
+				best, bestScore = picks, score
+			}
+			return
+		}
+		for i := start; i <= len(cards)-(5-picked); i++ {
+			picks[picked] = i
+			choose(i+1, picked+1)
+		}
+	}
+	choose(0, 0)
+	return best, bestScore
+}
+
+// evaluateFive ranks exactly 5 cards and orders them the way they decide ties, e.g. the pair before the kickers
+func evaluateFive(five [5]Card) Hand {
+	var fiveRanks [5]int
+	var counts [15]int
+	flush := true
+	for i, c := range five {
+		fiveRanks[i] = c.rank()
+		counts[fiveRanks[i]]++
+		flush = flush && c.Suit == five[0].Suit
+	}
+	category, score := scoreFive(fiveRanks, flush)
+	cards := append([]Card{}, five[:]...)
+	sort.SliceStable(cards, func(i, j int) bool {
+		ri, rj := cards[i].rank(), cards[j].rank()
+		if counts[ri] != counts[rj] {
+			return counts[ri] > counts[rj]
+		}
+		return ri > rj
+	})
+	if (category == Straight || category == StraightFlush) && cards[0].Value == repo.Ace && cards[1].Value == repo.Five {
+		// the wheel, the ace plays low and goes to the end
+		cards = append(cards[1:], cards[0])
+	}
+	return Hand{Category: category, Score: score, Cards: cards}
+}
+
+// scoreFive ranks exactly 5 cards by their ranks without allocating, since it runs for every combination of every hand. The score is
+// the category followed by the ranks deciding ties, most important first, 4 bits each.
+func scoreFive(fiveRanks [5]int, flush bool) (Category, int) {
+	var counts [15]int
+	for _, r := range fiveRanks {
+		counts[r]++
+	}
+
+	// ranks grouped by how often they appear, then by rank, give the tie breaking order of every category
+	var grouped [5]int
+	groups := 0
+	for n := 4; n > 0; n-- {
+		for r := aceRank; r >= twoRank; r-- {
+			if counts[r] == n {
+				grouped[groups] = r
+				groups++
+			}
+		}
+	}
+
+	straightHigh := 0
+	if groups == 5 {
+		if grouped[0]-grouped[4] == 4 {
+			straightHigh = grouped[0]
+		} else if grouped[0] == aceRank && grouped[1] == fiveRank {
+			straightHigh = fiveRank
+		}
+	}
+
+	var category Category
+	switch {
+	case straightHigh > 0 && flush && straightHigh == aceRank:
+		category = RoyalFlush
+	case straightHigh > 0 && flush:
+		category = StraightFlush
+	case counts[grouped[0]] == 4:
+		category = FourOfAKind
+	case counts[grouped[0]] == 3 && counts[grouped[1]] == 2:
+		category = FullHouse
+	case flush:
+		category = Flush
+	case straightHigh > 0:
+		category = Straight
+	case counts[grouped[0]] == 3:
+		category = ThreeOfAKind
+	case counts[grouped[0]] == 2 && counts[grouped[1]] == 2:
+		category = TwoPair
+	case counts[grouped[0]] == 2:
+		category = OnePair
+	default:
+		category = HighCard
+	}
+	if straightHigh > 0 {
+		grouped = [5]int{straightHigh}
+	}
+
+	score := int(category)
+	for _, r := range grouped {
+		score = score<<4 + r
+	}
+	return category, score
+}
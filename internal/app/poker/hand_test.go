@@ -0,0 +1,127 @@
+package poker
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func evaluate(t *testing.T, codes string) Hand {
+	cards, err := ParseCards(strings.Split(codes, ","))
+	assert.NoError(t, err)
+	hand, err := Evaluate(cards)
+	assert.NoError(t, err)
+	return hand
+}
+
+func codes(cards []Card) []string {
+	res := make([]string, len(cards))
+	for i, c := range cards {
+		res[i] = c.Code()
+	}
+	return res
+}
+
+func TestEvaluateCategories(t *testing.T) {
+	tests := []struct {
+		cards    string
+		category Category
+	}{
+		{"AS,KD,9C,7H,3S", HighCard},
+		{"AS,AD,9C,7H,3S", OnePair},
+		{"AS,AD,9C,9H,3S", TwoPair},
+		{"AS,AD,AC,9H,3S", ThreeOfAKind},
+		{"AS,2D,3C,4H,5S", Straight},
+		{"9S,8D,7C,6H,5S", Straight},
+		{"AS,KS,9S,7S,3S", Flush},
+		{"AS,AD,AC,9H,9S", FullHouse},
+		{"AS,AD,AC,AH,3S", FourOfAKind},
+		{"AH,2H,3H,4H,5H", StraightFlush},
+		{"9H,8H,7H,6H,5H", StraightFlush},
+		{"AH,KH,QH,JH,10H", RoyalFlush},
+	}
+	previous := -1
+	for _, test := range tests {
+		hand := evaluate(t, test.cards)
+		assert.Equal(t, test.category, hand.Category, test.cards)
+		// Test case: the hands above are ordered from weakest to strongest
+		assert.GreaterOrEqual(t, hand.Score, previous, test.cards)
+		previous = hand.Score
+	}
+}
+
+func TestEvaluateTies(t *testing.T) {
+	tests := []struct {
+		name   string
+		weaker string
+		better string
+	}{
+		{"high card kicker", "AS,KD,9C,7H,3S", "AS,KD,9C,7H,4D"},
+		{"pair rank", "KS,KD,AC,7H,3S", "AS,AD,9C,7H,3S"},
+		{"pair kicker", "AS,AD,9C,7H,3S", "AC,AH,9D,7S,4S"},
+		{"two pair high pair", "QS,QD,JC,JH,AS", "KS,KD,2C,2H,3S"},
+		{"two pair low pair", "KS,KD,2C,2H,AS", "KC,KH,3C,3H,4S"},
+		{"two pair kicker", "KS,KD,3C,3H,4S", "KC,KH,3D,3S,5S"},
+		{"trips kicker", "7S,7D,7C,KH,2S", "7S,7D,7C,KH,3S"},
+		{"straight high card", "AS,2D,3C,4H,5S", "2S,3D,4C,5H,6S"},
+		{"flush kicker", "AS,KS,9S,7S,3S", "AH,KH,9H,7H,4H"},
+		{"full house trips", "KS,KD,KC,AH,AS", "AS,AD,AC,2H,2S"},
+		{"full house pair", "KS,KD,KC,2H,2S", "KH,KD,KC,3H,3S"},
+		{"quads kicker", "9S,9D,9C,9H,2S", "9S,9D,9C,9H,3S"},
+		{"straight flush high card", "AH,2H,3H,4H,5H", "2C,3C,4C,5C,6C"},
+	}
+	for _, test := range tests {
+		weaker := evaluate(t, test.weaker)
+		better := evaluate(t, test.better)
+		assert.Equal(t, weaker.Category, better.Category, test.name)
+		assert.Less(t, weaker.Score, better.Score, test.name)
+	}
+
+	// Test case: the same ranks in other suits split
+	assert.Equal(t, evaluate(t, "AS,KD,9C,7H,3S").Score, evaluate(t, "AD,KC,9H,7S,3D").Score)
+	assert.Equal(t, evaluate(t, "AH,KH,QH,JH,10H").Score, evaluate(t, "AS,KS,QS,JS,10S").Score)
+}
+
+func TestEvaluateBestOfSeven(t *testing.T) {
+	// Test case: a flush beats the straight and the pair in the same 7 cards
+	hand := evaluate(t, "2H,3H,4S,5D,6H,9H,KH")
+	assert.Equal(t, Flush, hand.Category)
+	assert.Equal(t, []string{"KH", "9H", "6H", "3H", "2H"}, codes(hand.Cards))
+
+	// Test case: the best two pairs are taken with the best kicker
+	hand = evaluate(t, "AS,AD,KC,KH,QS,QD,JC")
+	assert.Equal(t, TwoPair, hand.Category)
+	assert.Equal(t, []string{"AS", "AD", "KC", "KH", "QS"}, codes(hand.Cards))
+
+	// Test case: the board plays for both players
+	board := "AH,KH,QH,JH,10H"
+	assert.Equal(t, evaluate(t, board+",2C,3D").Score, evaluate(t, board+",9C,9D").Score)
+
+	// Test case: the wheel shows the ace last
+	hand = evaluate(t, "AS,2D,3C,4H,5S,9D")
+	assert.Equal(t, Straight, hand.Category)
+	assert.Equal(t, []string{"5S", "4H", "3C", "2D", "AS"}, codes(hand.Cards))
+}
+
+func TestEvaluateInvalid(t *testing.T) {
+	// Test case: too few and too many cards
+	cards, _ := ParseCards([]string{"AS", "KS", "QS", "JS"})
+	_, err := Evaluate(cards)
+	assert.NotNil(t, err)
+	cards, _ = ParseCards([]string{"AS", "KS", "QS", "JS", "10S", "9S", "8S", "7S"})
+	_, err = Evaluate(cards)
+	assert.NotNil(t, err)
+
+	// Test case: duplicates, jokers and unknown codes
+	_, err = ParseCards([]string{"AS", "AS"})
+	assert.NotNil(t, err)
+	_, err = ParseCards([]string{"X1"})
+	assert.NotNil(t, err)
+	_, err = ParseCards([]string{"1S"})
+	assert.NotNil(t, err)
+
+	// Test case: codes are parsed case insensitive
+	card, err := ParseCard("10h")
+	assert.NoError(t, err)
+	assert.Equal(t, "10H", card.Code())
+}
@@ -0,0 +1,93 @@
+package poker
+
+import (
+	"fmt"
+	"github.com/deck/internal/app/repo"
+	"strings"
+)
+
+// Winners returns the indexes of the hands with the best score, more than one means a split pot
+func Winners(hands []Hand) []int {
+	var winners []int
+	best := -1
+	for i, h := range hands {
+		switch {
+		case h.Score > best:
+			best = h.Score
+			winners = []int{i}
+		case h.Score == best:
+			winners = append(winners, i)
+		}
+	}
+	return winners
+}
+
+// Showdown evaluates every player's hole cards together with the shared board cards. A card can only be dealt once, so
+// the same card in two hands, or in a hand and on the board, is an error.
+func Showdown(holeCards [][]Card, board []Card) ([]Hand, error) {
+	seen := make(map[Card]bool)
+	for _, c := range board {
+		seen[c] = true
+	}
+	hands := make([]Hand, len(holeCards))
+	for i, hole := range holeCards {
+		for _, c := range hole {
+			if seen[c] {
+				return nil, fmt.Errorf("card %s is dealt more than once", c.Code())
+			}
+			seen[c] = true
+		}
+		hand, err := Evaluate(append(append([]Card{}, hole...), board...))
+		if err != nil {
+			return nil, err
+		}
+		hands[i] = hand
+	}
+	return hands, nil
+}
+
+// Description explains the hand with the ranks deciding it, e.g. "two pair, aces and kings, queen kicker"
+func (h Hand) Description() string {
+	c := h.Cards
+	switch h.Category {
+	case RoyalFlush:
+		return "royal flush"
+	case StraightFlush:
+		return fmt.Sprintf("straight flush, %s high", name(c[0]))
+	case FourOfAKind:
+		return fmt.Sprintf("four of a kind, %s%s", plural(c[0]), kickers(c[4:]))
+	case FullHouse:
+		return fmt.Sprintf("full house, %s full of %s", plural(c[0]), plural(c[3]))
+	case Flush:
+		return fmt.Sprintf("flush, %s high%s", name(c[0]), kickers(c[1:]))
+	case Straight:
+		return fmt.Sprintf("straight, %s high", name(c[0]))
+	case ThreeOfAKind:
+		return fmt.Sprintf("three of a kind, %s%s", plural(c[0]), kickers(c[3:]))
+	case TwoPair:
+		return fmt.Sprintf("two pair, %s and %s%s", plural(c[0]), plural(c[2]), kickers(c[4:]))
+	case OnePair:
+		return fmt.Sprintf("pair of %s%s", plural(c[0]), kickers(c[2:]))
+	default:
+		return fmt.Sprintf("%s high%s", name(c[0]), kickers(c[1:]))
+	}
+}
+
+func name(c Card) string {
+	return strings.ToLower(repo.Values[c.Value])
+}
+
+func plural(c Card) string {
+	return name(c) + "s"
+}
+
+func kickers(cards []Card) string {
+	names := make([]string, len(cards))
+	for i, c := range cards {
+		names[i] = name(c)
+	}
+	if len(names) == 1 {
+		return fmt.Sprintf(", %s kicker", names[0])
+	}
+	return fmt.Sprintf(", %s kickers", strings.Join(names, " "))
+}
@@ -0,0 +1,63 @@
+package poker
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, codes string) []Card {
+	cards, err := ParseCards(strings.Split(codes, ","))
+	assert.NoError(t, err)
+	return cards
+}
+
+func TestShowdown(t *testing.T) {
+	board := parse(t, "AH,KD,7C,7S,2H")
+
+	// Test case: the full house beats two pair
+	hands, err := Showdown([][]Card{parse(t, "AS,QC"), parse(t, "KS,KC")}, board)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, Winners(hands))
+	assert.Equal(t, "full house, kings full of 7s", hands[1].Description())
+	assert.Equal(t, "two pair, aces and 7s, king kicker", hands[0].Description())
+
+	// Test case: both players play two pair with the board's king kicker, so the pot is split
+	hands, err = Showdown([][]Card{parse(t, "AS,QC"), parse(t, "AD,JC")}, board)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1}, Winners(hands))
+
+	// Test case: the better kicker wins
+	hands, err = Showdown([][]Card{parse(t, "AS,QC"), parse(t, "AD,JC")}, parse(t, "AH,9D,7C,5S,2H"))
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0}, Winners(hands))
+	assert.Equal(t, "pair of aces, queen 9 7 kickers", hands[0].Description())
+
+	// Test case: a card dealt twice
+	_, err = Showdown([][]Card{parse(t, "AS,QC"), parse(t, "AS,JC")}, board)
+	assert.EqualError(t, err, "card AS is dealt more than once")
+	_, err = Showdown([][]Card{parse(t, "AH,QC")}, board)
+	assert.EqualError(t, err, "card AH is dealt more than once")
+
+	// Test case: not enough cards to make a hand
+	_, err = Showdown([][]Card{parse(t, "AS,QC")}, parse(t, "2C,3C"))
+	assert.NotNil(t, err)
+}
+
+func TestDescription(t *testing.T) {
+	tests := map[string]string{
+		"AS,KD,9C,7H,3S":  "ace high, king 9 7 3 kickers",
+		"QS,QD,9C,7H,3S":  "pair of queens, 9 7 3 kickers",
+		"6S,6D,6C,AH,3S":  "three of a kind, 6s, ace 3 kickers",
+		"AS,2D,3C,4H,5S":  "straight, 5 high",
+		"AS,KS,9S,7S,3S":  "flush, ace high, king 9 7 3 kickers",
+		"JS,JD,JC,JH,3S":  "four of a kind, jacks, 3 kicker",
+		"9H,8H,7H,6H,5H":  "straight flush, 9 high",
+		"AH,KH,QH,JH,10H": "royal flush",
+	}
+	for codes, description := range tests {
+		hand, err := Evaluate(parse(t, codes))
+		assert.NoError(t, err)
+		assert.Equal(t, description, hand.Description(), codes)
+	}
+}
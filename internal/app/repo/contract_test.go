@@ -0,0 +1,35 @@
+package repo_test
+
+import (
+	"github.com/deck/internal/app/repo"
+	"github.com/deck/internal/app/repo/repotest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPostgresRepoContract(t *testing.T) {
+	db, _, cleanup := repo.SetupTestContainer(t)
+	defer cleanup()
+
+	// every subtest starts from empty tables, truncating the decks empties the tables referencing them too
+	newRepos := func(t *testing.T) (repo.DeckRepo, repo.GameRepo) {
+		_, err := db.Exec("truncate decks cascade")
+		assert.NoError(t, err)
+		return repo.NewDeckRepo(db), repo.NewGameRepo(db)
+	}
+	repotest.DeckRepoContract(t, func(t *testing.T) repo.DeckRepo {
+		deckRepo, _ := newRepos(t)
+		return deckRepo
+	})
+	repotest.GameRepoContract(t, newRepos)
+}
+
+func TestMemoryRepoContract(t *testing.T) {
+	repotest.DeckRepoContract(t, func(t *testing.T) repo.DeckRepo {
+		deckRepo, _ := repo.NewMemoryRepos()
+		return deckRepo
+	})
+	repotest.GameRepoContract(t, func(t *testing.T) (repo.DeckRepo, repo.GameRepo) {
+		return repo.NewMemoryRepos()
+	})
+}
@@ -0,0 +1,174 @@
+package repo
+
+import (
+	"sort"
+	"time"
+)
+
+type EventType string
+
+const (
+	CreateEvent      EventType = "create"
+	DrawEvent        EventType = "draw"
+	DiscardEvent     EventType = "discard"
+	ReturnEvent      EventType = "return"
+	ShuffleEvent     EventType = "shuffle"
+	CutEvent         EventType = "cut"
+	InsertEvent      EventType = "insert"
+	CreatePileEvent  EventType = "create_pile"
+	MoveCardsEvent   EventType = "move_cards"
+	ShufflePileEvent EventType = "shuffle_pile"
+	DrawPileEvent    EventType = "draw_pile"
+	SortPileEvent    EventType = "sort_pile"
+	// UndoEvent restores the deck as it was before an earlier event
+	UndoEvent EventType = "undo"
+	// ImportEvent holds the whole state of a deck created before events were recorded
+	ImportEvent EventType = "import"
+	// CloneEvent creates a deck with the state of another one
+	CloneEvent EventType = "clone"
+	// RestoreEvent restores the deck as it was when a snapshot of it was saved
+	RestoreEvent EventType = "restore"
+)
+
+// StartsHistory tells if the event creates the deck, its changes hold the whole state the deck started with
+func (t EventType) StartsHistory() bool {
+	return t == CreateEvent || t == ImportEvent || t == CloneEvent
+}
+
+// DeckEvent is one operation on a deck. Number counts the events of the deck from 1, the deck's version after the
+// event is Number - 1. Payload describes the operation for people, Changes holds the new value of every field of the
+// deck it changed, so replaying the changes of the events in order rebuilds the deck.
+type DeckEvent struct {
+	DeckId    string    `db:"deck_id"`
+	Number    int       `db:"number"`
+	Type      EventType `db:"event_type"`
+	Actor     string    `db:"actor"`
+	Payload   string    `db:"payload"`
+	Changes   string    `db:"changes"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// DeckChanges are the fields of a deck changed by an event, unchanged fields are left nil. Piles holds the whole
+// content of every changed pile. ClearShuffledAt tells ShuffledAt went back to nil, which a nil ShuffledAt can't.
+type DeckChanges struct {
+	Cards      *[]string           `json:"cards,omitempty"`
+	Drawn      *[]string           `json:"drawn,omitempty"`
+	Discarded  *[]string           `json:"discarded,omitempty"`
+	Remaining  *int                `json:"remaining,omitempty"`
+	Size       *int                `json:"size,omitempty"`
+	Shuffled   *bool               `json:"shuffled,omitempty"`
+	ShuffledAt *time.Time          `json:"shuffled_at,omitempty"`
+	Piles      map[string][]string `json:"piles,omitempty"`
+
+	ClearShuffledAt bool `json:"clear_shuffled_at,omitempty"`
+}
+
+// DiffDeck returns the fields of after which differ from before
+func DiffDeck(before, after Deck) DeckChanges {
+	var changes DeckChanges
+	if !sameCards(before.Cards, after.Cards) {
+		changes.Cards = copyCards(after.Cards)
+	}
+	if !sameCards(before.Drawn, after.Drawn) {
+		changes.Drawn = copyCards(after.Drawn)
+	}
+	if !sameCards(before.Discarded, after.Discarded) {
+		changes.Discarded = copyCards(after.Discarded)
+	}
+	if before.Remaining != after.Remaining {
+		changes.Remaining = &after.Remaining
+	}
+	if before.Size != after.Size {
+		changes.Size = &after.Size
+	}
+	if before.Shuffled != after.Shuffled {
+		changes.Shuffled = &after.Shuffled
+	}
+	if after.ShuffledAt != nil && (before.ShuffledAt == nil || !before.ShuffledAt.Equal(*after.ShuffledAt)) {
+		changes.ShuffledAt = after.ShuffledAt
+	}
+	if after.ShuffledAt == nil && before.ShuffledAt != nil {
+		changes.ClearShuffledAt = true
+	}
+	piles := make(map[string][]string, len(before.Piles))
+	for _, p := range before.Piles {
+		piles[p.Name] = p.Cards
+	}
+	for _, p := range after.Piles {
+		if cards, found := piles[p.Name]; found && sameCards(cards, p.Cards) {
+			continue
+		}
+		if changes.Piles == nil {
+			changes.Piles = make(map[string][]string)
+		}
+		changes.Piles[p.Name] = *copyCards(p.Cards)
+	}
+	return changes
+}
+
+// Apply writes the changes onto the deck, piles it doesn't have yet are added in the order of their names
+func (c DeckChanges) Apply(deck *Deck, at time.Time) {
+	if c.Cards != nil {
+		deck.Cards = *copyCards(*c.Cards)
+	}
+	if c.Drawn != nil {
+		deck.Drawn = *copyCards(*c.Drawn)
+	}
+	if c.Discarded != nil {
+		deck.Discarded = *copyCards(*c.Discarded)
+	}
+	if c.Remaining != nil {
+		deck.Remaining = *c.Remaining
+	}
+	if c.Size != nil {
+		deck.Size = *c.Size
+	}
+	if c.Shuffled != nil {
+		deck.Shuffled = *c.Shuffled
+	}
+	if c.ShuffledAt != nil {
+		shuffledAt := *c.ShuffledAt
+		deck.ShuffledAt = &shuffledAt
+	}
+	if c.ClearShuffledAt {
+		deck.ShuffledAt = nil
+	}
+	names := make([]string, 0, len(c.Piles))
+	for name := range c.Piles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cards := *copyCards(c.Piles[name])
+		found := false
+		for i := range deck.Piles {
+			if deck.Piles[i].Name == name {
+				deck.Piles[i].Cards = cards
+				deck.Piles[i].UpdatedAt = at
+				found = true
+			}
+		}
+		if !found {
+			deck.Piles = append(deck.Piles, Pile{DeckId: deck.Id, Name: name, Cards: cards, CreatedAt: at, UpdatedAt: at})
+		}
+	}
+	deck.UpdatedAt = at
+}
+
+// sameCards compares two lists of cards, nil being the same as empty
+func sameCards(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func copyCards(cards []string) *[]string {
+	copied := append([]string{}, cards...)
+	return &copied
+}
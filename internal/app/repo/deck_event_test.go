@@ -0,0 +1,48 @@
+package repo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDiffDeck(t *testing.T) {
+	now := time.Now().UTC()
+	before := Deck{Id: "deck", Cards: []string{"AS", "KD", "QH"}, Remaining: 3, Size: 3,
+		Piles: []Pile{{Name: "hand", Cards: []string{}}}}
+	after := Deck{Id: "deck", Cards: []string{"QH"}, Drawn: []string{"AS"}, Remaining: 1, Size: 3, Shuffled: true,
+		ShuffledAt: &now, Piles: []Pile{{Name: "hand", Cards: []string{"KD"}}, {Name: "board"}}}
+
+	// Test case: only the changed fields are kept
+	changes := DiffDeck(before, after)
+
+	assert.Equal(t, []string{"QH"}, *changes.Cards)
+	assert.Equal(t, []string{"AS"}, *changes.Drawn)
+	assert.Nil(t, changes.Discarded)
+	assert.Equal(t, 1, *changes.Remaining)
+	assert.Nil(t, changes.Size)
+	assert.True(t, *changes.Shuffled)
+	assert.Equal(t, map[string][]string{"hand": {"KD"}, "board": {}}, changes.Piles)
+
+	// Test case: applying the changes turns before into after
+	changes.Apply(&before, now)
+
+	assert.Equal(t, after.Cards, before.Cards)
+	assert.Equal(t, after.Drawn, before.Drawn)
+	assert.Equal(t, after.Remaining, before.Remaining)
+	assert.True(t, before.Shuffled)
+	assert.Equal(t, []string{"hand", "board"}, []string{before.Piles[0].Name, before.Piles[1].Name})
+	assert.Equal(t, []string{"KD"}, []string(before.Piles[0].Cards))
+
+	// Test case: the shuffle time went back to nil, e.g. the shuffle was undone
+	changes = DiffDeck(after, Deck{Id: "deck", Cards: after.Cards, Drawn: after.Drawn, Remaining: 1, Size: 3,
+		Shuffled: true, Piles: after.Piles})
+
+	assert.True(t, changes.ClearShuffledAt)
+	assert.Nil(t, changes.ShuffledAt)
+	changes.Apply(&before, now)
+	assert.Nil(t, before.ShuffledAt)
+
+	// Test case: nothing changed
+	assert.Equal(t, DeckChanges{}, DiffDeck(after, after))
+}
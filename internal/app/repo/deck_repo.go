@@ -1,16 +1,57 @@
 package repo
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/golang/glog"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"strings"
 	"time"
 )
 
+// ErrVersionConflict is returned when a deck or a game was modified by someone else since it was read
+var ErrVersionConflict = errors.New("modified concurrently")
+
 type DeckRepo interface {
-	CreateDeck(deck Deck) error
+	CreateDeck(deck Deck, event DeckEvent) error
 	GetDeckById(id string) (*Deck, error)
-	UpdateDeck(deck Deck) error
+	UpdateDeck(deck Deck, event DeckEvent) error
+	GetDeckEvents(id string, after int, limit int) ([]DeckEvent, error)
+	ListDecks(filter DeckFilter) ([]Deck, error)
+	DeleteDeck(id string) error
+	DeleteExpiredDecks(now time.Time, idleSince *time.Time, limit int) (int, error)
+	SaveSnapshot(snapshot DeckSnapshot, limit int) error
+	GetSnapshot(deckId, name string) (*DeckSnapshot, error)
+	ListSnapshots(deckId string) ([]DeckSnapshot, error)
+	DeleteSnapshot(deckId, name string) error
+}
+
+// DeckFilter selects the decks ListDecks returns. Unset fields don't filter, the time ranges include their start and
+// exclude their end. ParentId lists the clones of a deck, ActiveAt leaves out the decks expired by then, After
+// continues a listing behind the deck it points to.
+type DeckFilter struct {
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+	Shuffled    *bool
+	Empty       *bool
+	Owner       string
+	ParentId    string
+	ActiveAt    *time.Time
+	After       *DeckCursor
+	Limit       int
 }
+
+// DeckCursor is the position of a deck in a listing, ordered by creation time then id
+type DeckCursor struct {
+	CreatedAt time.Time
+	Id        string
+}
+
 type deckRepo struct {
 	db *sqlx.DB
 }
@@ -19,15 +60,44 @@ func NewDeckRepo(db *sqlx.DB) DeckRepo {
 	return &deckRepo{db: db}
 }
 
-func (r *deckRepo) CreateDeck(deck Deck) error {
-	_, err := r.db.NamedExec(`insert into decks (id, shuffled, remaining, cards, created_at, updated_at) 
-                          values (:id, :shuffled, :remaining, :cards, :created_at, :updated_at)`, deck)
+// CreateDeck saves a new deck and its piles together with the event creating it
+func (r *deckRepo) CreateDeck(deck Deck, event DeckEvent) error {
+	deck.Drawn = emptyIfNil(deck.Drawn)
+	deck.Discarded = emptyIfNil(deck.Discarded)
+	deck.InitialCards = emptyIfNil(deck.InitialCards)
+	if len(deck.DeckType) == 0 {
+		deck.DeckType = Standard
+	}
+	tx, err := r.db.Beginx()
 	if err != nil {
 		return err
 	}
-	return nil
+	defer tx.Rollback()
+
+	_, err = tx.NamedExec(`insert into decks (id, shuffled, shuffled_at, remaining, cards, drawn, discarded, deck_type, jokers, 
+                          server_seed, seeded, client_seed, initial_cards, decks_count, cut_card, size, owner, parent_id, 
+                          expires_at, version, created_at, updated_at) 
+                          values (:id, :shuffled, :shuffled_at, :remaining, :cards, :drawn, :discarded, :deck_type, :jokers, 
+                          :server_seed, :seeded, :client_seed, :initial_cards, :decks_count, :cut_card, :size, :owner, 
+                          :parent_id, :expires_at, :version, :created_at, :updated_at)`, deck)
+	if err != nil {
+		return err
+	}
+	for _, p := range deck.Piles {
+		_, err = tx.Exec(`insert into piles (deck_id, name, cards, created_at, updated_at) values ($1, $2, $3, $4, $5)`,
+			deck.Id, p.Name, emptyIfNil(p.Cards), p.CreatedAt, p.UpdatedAt)
+		if err != nil {
+			glog.Errorf("error while saving pile %s of deck with id %s", p.Name, deck.Id, err)
+			return err
+		}
+	}
+	if err = insertEvent(tx, Deck{}, deck, event, deck.CreatedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
+// GetDeckById returns the deck together with its piles
 func (r *deckRepo) GetDeckById(id string) (*Deck, error) {
 	var deck Deck
 	err := r.db.Get(&deck, "select * from decks where id=$1", id)
@@ -35,17 +105,204 @@ func (r *deckRepo) GetDeckById(id string) (*Deck, error) {
 		glog.Errorf("error while getting deck with id %s", id, err)
 		return nil, err
 	}
+	err = r.db.Select(&deck.Piles, "select * from piles where deck_id=$1 order by created_at, name", id)
+	if err != nil {
+		glog.Errorf("error while getting piles of deck with id %s", id, err)
+		return nil, err
+	}
 	return &deck, nil
 }
 
-func (r *deckRepo) UpdateDeck(deck Deck) error {
-	res, err := r.db.Exec(`update decks set shuffled=$1, remaining=$2, cards=$3, updated_at=$4 where id=$5`,
-		deck.Shuffled, deck.Remaining, deck.Cards, time.Now().UTC(), deck.Id)
+// UpdateDeck saves the deck and its piles in one transaction, so a card moved between them is never lost or doubled,
+// and appends the event with the fields it changed to the deck's history. It only succeeds if nobody else updated the
+// deck since it was read (optimistic locking on version) and returns ErrVersionConflict otherwise.
+func (r *deckRepo) UpdateDeck(deck Deck, event DeckEvent) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var before Deck
+	err = tx.Get(&before, "select * from decks where id=$1 and version=$2 for update", deck.Id, deck.Version)
+	if err == sql.ErrNoRows {
+		glog.Warningf("version conflict while updating deck with id %s", deck.Id)
+		return ErrVersionConflict
+	}
+	if err != nil {
+		return err
+	}
+	if err = tx.Select(&before.Piles, "select * from piles where deck_id=$1 order by created_at, name", deck.Id); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	res, err := tx.Exec(`update decks set shuffled=$1, shuffled_at=$2, remaining=$3, cards=$4, drawn=$5, discarded=$6, 
+                          size=$7, updated_at=$8, version=version+1 where id=$9 and version=$10`,
+		deck.Shuffled, deck.ShuffledAt, deck.Remaining, emptyIfNil(deck.Cards), emptyIfNil(deck.Drawn),
+		emptyIfNil(deck.Discarded), deck.Size, now, deck.Id, deck.Version)
 	if err != nil {
 		glog.Errorf("error while updating deck with id %s", deck.Id, err)
 		return err
 	}
 	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		glog.Warningf("version conflict while updating deck with id %s", deck.Id)
+		return ErrVersionConflict
+	}
 	glog.Infof("%d rows updated", rows)
+
+	for _, p := range deck.Piles {
+		_, err = tx.Exec(`insert into piles (deck_id, name, cards, created_at, updated_at) values ($1, $2, $3, $4, $4)
+                          on conflict (deck_id, name) do update set cards=excluded.cards, updated_at=excluded.updated_at`,
+			deck.Id, p.Name, emptyIfNil(p.Cards), now)
+		if err != nil {
+			glog.Errorf("error while saving pile %s of deck with id %s", p.Name, deck.Id, err)
+			return err
+		}
+	}
+	deck.Version++
+	if err = insertEvent(tx, before, deck, event, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetDeckEvents returns up to limit events of the deck numbered after the given number, in order
+func (r *deckRepo) GetDeckEvents(id string, after int, limit int) ([]DeckEvent, error) {
+	events := []DeckEvent{}
+	err := r.db.Select(&events, `select * from deck_events where deck_id=$1 and number>$2 order by number limit $3`,
+		id, after, limit)
+	if err != nil {
+		glog.Errorf("error while getting events of deck with id %s", id, err)
+		return nil, err
+	}
+	return events, nil
+}
+
+// insertEvent records the event turning the deck from before into after, numbered after the version of after
+func insertEvent(tx *sqlx.Tx, before, after Deck, event DeckEvent, at time.Time) error {
+	changes, err := json.Marshal(DiffDeck(before, after))
+	if err != nil {
+		return err
+	}
+	if len(event.Payload) == 0 {
+		event.Payload = "{}"
+	}
+	_, err = tx.Exec(`insert into deck_events (deck_id, number, event_type, actor, payload, changes, created_at) 
+                          values ($1, $2, $3, $4, $5, $6, $7)`,
+		after.Id, after.Version+1, event.Type, event.Actor, event.Payload, string(changes), at)
+	if err != nil {
+		glog.Errorf("error while recording event of deck with id %s", after.Id, err)
+	}
+	return err
+}
+
+// ListDecks returns up to filter.Limit decks ordered by creation time, without their cards and piles
+func (r *deckRepo) ListDecks(filter DeckFilter) ([]Deck, error) {
+	var conditions []string
+	var args []interface{}
+	// where adds a condition, numbering its ? placeholders after the arguments before it
+	where := func(condition string, values ...interface{}) {
+		for _, v := range values {
+			args = append(args, v)
+			condition = strings.Replace(condition, "?", fmt.Sprintf("$%d", len(args)), 1)
+		}
+		conditions = append(conditions, condition)
+	}
+	if filter.CreatedFrom != nil {
+		where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		where("created_at < ?", *filter.CreatedTo)
+	}
+	if filter.UpdatedFrom != nil {
+		where("updated_at >= ?", *filter.UpdatedFrom)
+	}
+	if filter.UpdatedTo != nil {
+		where("updated_at < ?", *filter.UpdatedTo)
+	}
+	if filter.Shuffled != nil {
+		where("shuffled = ?", *filter.Shuffled)
+	}
+	if filter.Empty != nil && *filter.Empty {
+		where("remaining = 0")
+	} else if filter.Empty != nil {
+		where("remaining > 0")
+	}
+	if len(filter.Owner) > 0 {
+		where("owner = ?", filter.Owner)
+	}
+	if len(filter.ParentId) > 0 {
+		where("parent_id = ?", filter.ParentId)
+	}
+	if filter.ActiveAt != nil {
+		where("(expires_at is null or expires_at > ?)", *filter.ActiveAt)
+	}
+	if filter.After != nil {
+		where("(created_at, id) > (?, ?)", filter.After.CreatedAt, filter.After.Id)
+	}
+
+	query := `select id, shuffled, shuffled_at, remaining, deck_type, jokers, decks_count, size, owner, parent_id, 
+                          expires_at, version, created_at, updated_at from decks`
+	if len(conditions) > 0 {
+		query += " where " + strings.Join(conditions, " and ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" order by created_at, id limit $%d", len(args))
+
+	decks := []Deck{}
+	if err := r.db.Select(&decks, query, args...); err != nil {
+		glog.Errorf("error while listing decks", err)
+		return nil, err
+	}
+	return decks, nil
+}
+
+// DeleteDeck deletes the deck together with its piles and games, sql.ErrNoRows tells there was no such deck
+func (r *deckRepo) DeleteDeck(id string) error {
+	res, err := r.db.Exec("delete from decks where id=$1", id)
+	if err != nil {
+		glog.Errorf("error while deleting deck with id %s", id, err)
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
 	return nil
 }
+
+// DeleteExpiredDecks deletes up to limit decks which expired by now or, when idleSince is given, weren't updated since
+// then. A deck whose game was played since then isn't idle, even if the game didn't touch the deck. It returns the
+// number of decks deleted.
+func (r *deckRepo) DeleteExpiredDecks(now time.Time, idleSince *time.Time, limit int) (int, error) {
+	res, err := r.db.Exec(`delete from decks where id in (
+                          select d.id from decks d where d.expires_at <= $1
+                          or $2::timestamp is not null and d.updated_at < $2 and not exists (
+                              select 1 from games g where g.deck_id = d.id and g.updated_at >= $2)
+                          limit $3)`, now, idleSince, limit)
+	if err != nil {
+		glog.Errorf("error while deleting expired decks", err)
+		return 0, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(rows), nil
+}
+
+// emptyIfNil keeps nil slices from being written as null into not null array columns
+func emptyIfNil(cards pq.StringArray) pq.StringArray {
+	if cards == nil {
+		return pq.StringArray{}
+	}
+	return cards
+}
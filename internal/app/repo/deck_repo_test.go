@@ -2,8 +2,11 @@ package repo
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4"
+	migratePostgres "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
@@ -11,10 +14,13 @@ import (
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"github.com/testcontainers/testcontainers-go/wait"
 	"log"
+	"sync"
 	"testing"
 	"time"
 )
 
+const migrationPath = "file://../../../db/migrations"
+
 // TestContainer represents a test container for the PostgreSQL database.
 type TestContainer struct {
 	container testcontainers.Container
@@ -68,17 +74,11 @@ func setupTestContainer(t *testing.T) (*sqlx.DB, *TestContainer, func()) {
 	db, err := sqlx.Open("postgres", dsn)
 	assert.NoError(t, err)
 
-	// should be created from an init sql script or migrate script
-	db.Exec(`
-		create table if not exists decks (
-			id varchar(50) primary key,
-			shuffled bool default false not null,
-			remaining int not null,
-			cards text[] not null,
-			created_at timestamp without time zone default current_timestamp not null,
-			updated_at timestamp without time zone default current_timestamp not null
-		)
-	`)
+	driver, err := migratePostgres.WithInstance(db.DB, &migratePostgres.Config{})
+	assert.NoError(t, err)
+	m, err := migrate.NewWithDatabaseInstance(migrationPath, "postgres", driver)
+	assert.NoError(t, err)
+	assert.NoError(t, m.Up())
 
 	return db, container, func() {
 		assert.NoError(t, db.Close())
@@ -94,15 +94,24 @@ func TestDeckRepo(t *testing.T) {
 
 	// Test case: CreateDeck
 	deck := Deck{
-		Id:        "test-deck-id",
-		Shuffled:  true,
-		Remaining: 52,
-		Cards:     []string{"AH", "2C", "3D", "4S", "5H"},
-		CreatedAt: time.Now().UTC(),
-		UpdatedAt: time.Now().UTC(),
+		Id:           "test-deck-id",
+		Shuffled:     true,
+		Remaining:    52,
+		Cards:        []string{"AH", "2C", "3D", "4S", "5H"},
+		DeckType:     Standard,
+		Jokers:       true,
+		ServerSeed:   "server-seed",
+		Seeded:       true,
+		ClientSeed:   "client-seed",
+		InitialCards: []string{"2C", "3D", "4S", "5H", "AH"},
+		DecksCount:   1,
+		CutCard:      4,
+		Size:         5,
+		CreatedAt:    time.Now().UTC(),
+		UpdatedAt:    time.Now().UTC(),
 	}
 
-	err := repo.CreateDeck(deck)
+	err := repo.CreateDeck(deck, DeckEvent{Type: CreateEvent})
 	assert.NoError(t, err)
 
 	// Test case: GetDeckById
@@ -110,10 +119,23 @@ func TestDeckRepo(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, fetchedDeck)
 	assert.Equal(t, deck.Id, fetchedDeck.Id)
+	assert.Equal(t, deck.DeckType, fetchedDeck.DeckType)
+	assert.Equal(t, deck.Jokers, fetchedDeck.Jokers)
+	assert.Equal(t, deck.ServerSeed, fetchedDeck.ServerSeed)
+	assert.Equal(t, deck.Seeded, fetchedDeck.Seeded)
+	assert.Equal(t, deck.ClientSeed, fetchedDeck.ClientSeed)
+	assert.Equal(t, deck.InitialCards, fetchedDeck.InitialCards)
+	assert.Equal(t, deck.DecksCount, fetchedDeck.DecksCount)
+	assert.Equal(t, deck.CutCard, fetchedDeck.CutCard)
+	assert.Equal(t, deck.Size, fetchedDeck.Size)
 
 	// Test case: UpdateDeck
 	deck.Remaining = 50
-	err = repo.UpdateDeck(deck)
+	deck.Drawn = []string{"AH"}
+	deck.Discarded = []string{"2C"}
+	shuffledAt := time.Now().UTC().Truncate(time.Millisecond)
+	deck.ShuffledAt = &shuffledAt
+	err = repo.UpdateDeck(deck, DeckEvent{Type: DrawEvent})
 	assert.NoError(t, err)
 
 	// Verify the updated deck
@@ -121,4 +143,251 @@ func TestDeckRepo(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, updatedDeck)
 	assert.Equal(t, deck.Remaining, updatedDeck.Remaining)
+	assert.Equal(t, deck.Drawn, updatedDeck.Drawn)
+	assert.Equal(t, deck.Discarded, updatedDeck.Discarded)
+	assert.True(t, shuffledAt.Equal(*updatedDeck.ShuffledAt))
+
+	// Test case: UpdateDeck moves cards between the deck and its piles
+	updatedDeck.Cards = updatedDeck.Cards[2:]
+	updatedDeck.Piles = []Pile{{Name: "hand", Cards: []string{"AH", "2C"}}, {Name: "board", Cards: []string{}}}
+	err = repo.UpdateDeck(*updatedDeck, DeckEvent{Type: DrawEvent})
+	assert.NoError(t, err)
+
+	withPiles, err := repo.GetDeckById("test-deck-id")
+	assert.NoError(t, err)
+	assert.Equal(t, updatedDeck.Cards, withPiles.Cards)
+	assert.Len(t, withPiles.Piles, 2)
+	assert.ElementsMatch(t, []string{"hand", "board"}, []string{withPiles.Piles[0].Name, withPiles.Piles[1].Name})
+
+	// Test case: a stale update changes neither the deck nor its piles
+	updatedDeck.Piles[0].Cards = nil
+	assert.Equal(t, ErrVersionConflict, repo.UpdateDeck(*updatedDeck, DeckEvent{Type: DrawEvent}))
+	unchanged, err := repo.GetDeckById("test-deck-id")
+	assert.NoError(t, err)
+	assert.Equal(t, withPiles.Piles, unchanged.Piles)
+
+	// Test case: every saved change was recorded with the fields it changed, the stale one wasn't
+	events, err := repo.GetDeckEvents("test-deck-id", 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, events, 3)
+	assert.Equal(t, []int{1, 2, 3}, []int{events[0].Number, events[1].Number, events[2].Number})
+	assert.Equal(t, CreateEvent, events[0].Type)
+	var changes DeckChanges
+	assert.NoError(t, json.Unmarshal([]byte(events[2].Changes), &changes))
+	assert.Equal(t, []string(withPiles.Cards), *changes.Cards)
+	assert.Equal(t, []string{"AH", "2C"}, changes.Piles["hand"])
+	assert.Nil(t, changes.Drawn)
+
+	events, err = repo.GetDeckEvents("test-deck-id", 2, 10)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+}
+
+func TestDeckRepoConcurrentDraws(t *testing.T) {
+	db, _, cleanup := setupTestContainer(t)
+	defer cleanup()
+	// keep below Postgres' max_connections while hundreds of goroutines draw
+	db.SetMaxOpenConns(20)
+
+	repo := NewDeckRepo(db)
+	var cards []string
+	for _, s := range SequentialSuits {
+		for _, v := range SequentialValues {
+			cards = append(cards, fmt.Sprintf("%s%s", v, s))
+		}
+	}
+	now := time.Now().UTC()
+	err := repo.CreateDeck(Deck{Id: "concurrent-deck-id", Remaining: len(cards), Cards: cards, CreatedAt: now, UpdatedAt: now},
+		DeckEvent{Type: CreateEvent})
+	assert.NoError(t, err)
+
+	// Test case: hundreds of parallel draws never hand out the same card twice
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	dealt := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				deck, err := repo.GetDeckById("concurrent-deck-id")
+				if !assert.NoError(t, err) || deck.Remaining == 0 {
+					return
+				}
+				card := deck.Cards[0]
+				deck.Cards = deck.Cards[1:]
+				deck.Remaining--
+				err = repo.UpdateDeck(*deck, DeckEvent{Type: DrawEvent})
+				if err == ErrVersionConflict {
+					continue
+				}
+				assert.NoError(t, err)
+				mu.Lock()
+				dealt[card]++
+				mu.Unlock()
+				return
+			}
+		}()
+	}
+	wg.Wait()
+
+	for card, count := range dealt {
+		assert.Equal(t, 1, count, "card %s was dealt more than once", card)
+	}
+	assert.Len(t, dealt, len(cards))
+
+	deck, err := repo.GetDeckById("concurrent-deck-id")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, deck.Remaining)
+	assert.Empty(t, deck.Cards)
+
+	// Test case: saving a stale deck fails with a version conflict
+	deck.Version--
+	assert.Equal(t, ErrVersionConflict, repo.UpdateDeck(*deck, DeckEvent{Type: DrawEvent}))
+}
+
+func TestListDecks(t *testing.T) {
+	db, _, cleanup := setupTestContainer(t)
+	defer cleanup()
+
+	repo := NewDeckRepo(db)
+	start := time.Date(2023, 12, 31, 10, 0, 0, 0, time.UTC)
+	for i, d := range []Deck{
+		{Id: "deck-a", Owner: "alice", Shuffled: true, Remaining: 1, Cards: []string{"AS"}},
+		{Id: "deck-b", Owner: "bob"},
+		{Id: "deck-c", Owner: "alice", Remaining: 1, Cards: []string{"KH"}},
+	} {
+		d.CreatedAt = start.Add(time.Duration(i) * time.Hour)
+		d.UpdatedAt = d.CreatedAt
+		assert.NoError(t, repo.CreateDeck(d, DeckEvent{Type: CreateEvent}))
+	}
+
+	// Test case: every deck oldest first, without its cards
+	decks, err := repo.ListDecks(DeckFilter{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, decks, 3)
+	assert.Equal(t, "deck-a", decks[0].Id)
+	assert.Equal(t, "alice", decks[0].Owner)
+	assert.Empty(t, decks[0].Cards)
+
+	// Test case: filters and the cursor combine
+	empty := false
+	from := start.Add(time.Hour)
+	decks, err = repo.ListDecks(DeckFilter{Owner: "alice", Empty: &empty, CreatedFrom: &from, Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, decks, 1)
+	assert.Equal(t, "deck-c", decks[0].Id)
+
+	decks, err = repo.ListDecks(DeckFilter{After: &DeckCursor{CreatedAt: start, Id: "deck-a"}, Limit: 1})
+	assert.NoError(t, err)
+	assert.Len(t, decks, 1)
+	assert.Equal(t, "deck-b", decks[0].Id)
+}
+
+func TestCloneAndSnapshots(t *testing.T) {
+	db, _, cleanup := setupTestContainer(t)
+	defer cleanup()
+
+	repo := NewDeckRepo(db)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	deck := Deck{Id: "parent", Remaining: 2, Cards: []string{"AS", "KH"}, Size: 3, CreatedAt: now, UpdatedAt: now,
+		Piles: []Pile{{Name: "hand", Cards: []string{"QD"}, CreatedAt: now, UpdatedAt: now}}}
+	assert.NoError(t, repo.CreateDeck(deck, DeckEvent{Type: CreateEvent}))
+
+	// Test case: a clone is created with its piles and its parent
+	parentId := deck.Id
+	clone := deck
+	clone.Id = "clone"
+	clone.ParentId = &parentId
+	assert.NoError(t, repo.CreateDeck(clone, DeckEvent{Type: CloneEvent}))
+	fetched, err := repo.GetDeckById("clone")
+	assert.NoError(t, err)
+	assert.Equal(t, "parent", *fetched.ParentId)
+	assert.Equal(t, deck.Cards, fetched.Cards)
+	assert.Len(t, fetched.Piles, 1)
+	assert.Equal(t, []string{"QD"}, []string(fetched.Piles[0].Cards))
+	decks, err := repo.ListDecks(DeckFilter{ParentId: "parent", Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, decks, 1)
+	assert.Equal(t, "clone", decks[0].Id)
+	events, err := repo.GetDeckEvents("clone", 0, 10)
+	assert.NoError(t, err)
+	var changes DeckChanges
+	assert.NoError(t, json.Unmarshal([]byte(events[0].Changes), &changes))
+	assert.Equal(t, []string{"QD"}, changes.Piles["hand"])
+
+	// Test case: snapshots are saved, replaced, listed and deleted
+	state, err := json.Marshal(SnapshotState(deck))
+	assert.NoError(t, err)
+	snapshot := DeckSnapshot{DeckId: "parent", Name: "start", State: string(state), CreatedAt: now}
+	assert.NoError(t, repo.SaveSnapshot(snapshot, 20))
+	snapshot.Version = 3
+	assert.NoError(t, repo.SaveSnapshot(snapshot, 20))
+	later := DeckSnapshot{DeckId: "parent", Name: "later", State: "{}", CreatedAt: now.Add(time.Second)}
+	assert.NoError(t, repo.SaveSnapshot(later, 20))
+
+	fetchedSnapshot, err := repo.GetSnapshot("parent", "start")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, fetchedSnapshot.Version)
+	assert.JSONEq(t, string(state), fetchedSnapshot.State)
+	snapshots, err := repo.ListSnapshots("parent")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"start", "later"}, []string{snapshots[0].Name, snapshots[1].Name})
+
+	assert.NoError(t, repo.DeleteSnapshot("parent", "later"))
+	assert.Equal(t, sql.ErrNoRows, repo.DeleteSnapshot("parent", "later"))
+	_, err = repo.GetSnapshot("parent", "later")
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	// Test case: deleting the parent keeps the lineage of its clone and deletes its snapshots
+	assert.NoError(t, repo.DeleteDeck("parent"))
+	fetched, err = repo.GetDeckById("clone")
+	assert.NoError(t, err)
+	assert.Equal(t, "parent", *fetched.ParentId)
+	snapshots, err = repo.ListSnapshots("parent")
+	assert.NoError(t, err)
+	assert.Empty(t, snapshots)
+}
+
+func TestDeleteDecks(t *testing.T) {
+	db, _, cleanup := setupTestContainer(t)
+	defer cleanup()
+
+	repo := NewDeckRepo(db)
+	gameRepo := NewGameRepo(db)
+	now := time.Now().UTC()
+	expired, later, idle := now.Add(-time.Minute), now.Add(time.Hour), now.Add(-48*time.Hour)
+	for _, d := range []Deck{
+		{Id: "expired-1", ExpiresAt: &expired, CreatedAt: now, UpdatedAt: now},
+		{Id: "expired-2", ExpiresAt: &expired, CreatedAt: now, UpdatedAt: now},
+		{Id: "live", ExpiresAt: &later, CreatedAt: now, UpdatedAt: now},
+		{Id: "idle", CreatedAt: idle, UpdatedAt: idle},
+		{Id: "idle-with-game", CreatedAt: idle, UpdatedAt: idle},
+	} {
+		assert.NoError(t, repo.CreateDeck(d, DeckEvent{Type: CreateEvent}))
+	}
+	assert.NoError(t, gameRepo.CreateGame(Game{Id: "game", GameType: KlondikeGame, DeckId: "idle-with-game", State: "{}",
+		CreatedAt: now, UpdatedAt: now}))
+
+	// Test case: DeleteDeck
+	assert.NoError(t, repo.DeleteDeck("live"))
+	_, err := repo.GetDeckById("live")
+	assert.Equal(t, sql.ErrNoRows, err)
+	assert.Equal(t, sql.ErrNoRows, repo.DeleteDeck("live"))
+
+	// Test case: expired decks are deleted in batches
+	deleted, err := repo.DeleteExpiredDecks(now, nil, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	deleted, err = repo.DeleteExpiredDecks(now, nil, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	// Test case: idle decks are deleted unless their game was played meanwhile
+	idleSince := now.Add(-24 * time.Hour)
+	deleted, err = repo.DeleteExpiredDecks(now, &idleSince, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	_, err = repo.GetDeckById("idle-with-game")
+	assert.NoError(t, err)
 }
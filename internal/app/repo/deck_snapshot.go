@@ -0,0 +1,122 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"time"
+)
+
+// ErrTooManySnapshots is returned when a new snapshot is saved for a deck which has as many as it can have already
+var ErrTooManySnapshots = errors.New("too many snapshots")
+
+// DeckSnapshot is the state of a deck saved under a name to be restored later. Version is the version of the deck it
+// was taken at, State the JSON encoded DeckChanges of SnapshotState.
+type DeckSnapshot struct {
+	DeckId    string    `db:"deck_id"`
+	Name      string    `db:"name"`
+	Version   int       `db:"version"`
+	Actor     string    `db:"actor"`
+	State     string    `db:"state"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// SnapshotState holds every field of the deck an operation can change, including the empty ones, so applying it onto
+// a cleared deck restores the deck exactly
+func SnapshotState(deck Deck) DeckChanges {
+	remaining, size, shuffled := deck.Remaining, deck.Size, deck.Shuffled
+	state := DeckChanges{
+		Cards:      copyCards(deck.Cards),
+		Drawn:      copyCards(deck.Drawn),
+		Discarded:  copyCards(deck.Discarded),
+		Remaining:  &remaining,
+		Size:       &size,
+		Shuffled:   &shuffled,
+		ShuffledAt: deck.ShuffledAt,
+		Piles:      make(map[string][]string, len(deck.Piles)),
+	}
+	for _, p := range deck.Piles {
+		state.Piles[p.Name] = *copyCards(p.Cards)
+	}
+	return state
+}
+
+// SaveSnapshot saves the snapshot, replacing the deck's snapshot of the same name. A deck has at most limit snapshots,
+// ErrTooManySnapshots tells it has that many already. The deck is locked while its snapshots are counted, so
+// concurrent saves can't go over the limit.
+func (r *deckRepo) SaveSnapshot(snapshot DeckSnapshot, limit int) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var deckId string
+	err = tx.Get(&deckId, "select id from decks where id=$1 for update", snapshot.DeckId)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("deck with id %s doesn't exist", snapshot.DeckId)
+	}
+	if err != nil {
+		return err
+	}
+	var others int
+	err = tx.Get(&others, "select count(*) from deck_snapshots where deck_id=$1 and name<>$2", snapshot.DeckId,
+		snapshot.Name)
+	if err != nil {
+		return err
+	}
+	if others >= limit {
+		return ErrTooManySnapshots
+	}
+	_, err = tx.NamedExec(`insert into deck_snapshots (deck_id, name, version, actor, state, created_at)
+                          values (:deck_id, :name, :version, :actor, :state, :created_at)
+                          on conflict (deck_id, name) do update set version=excluded.version, actor=excluded.actor,
+                          state=excluded.state, created_at=excluded.created_at`, snapshot)
+	if err != nil {
+		glog.Errorf("error while saving snapshot %s of deck with id %s", snapshot.Name, snapshot.DeckId, err)
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetSnapshot returns the deck's snapshot of the given name, sql.ErrNoRows tells there is none
+func (r *deckRepo) GetSnapshot(deckId, name string) (*DeckSnapshot, error) {
+	var snapshot DeckSnapshot
+	err := r.db.Get(&snapshot, "select * from deck_snapshots where deck_id=$1 and name=$2", deckId, name)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			glog.Errorf("error while getting snapshot %s of deck with id %s", name, deckId, err)
+		}
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// ListSnapshots returns the snapshots of the deck, oldest first
+func (r *deckRepo) ListSnapshots(deckId string) ([]DeckSnapshot, error) {
+	snapshots := []DeckSnapshot{}
+	err := r.db.Select(&snapshots, "select * from deck_snapshots where deck_id=$1 order by created_at, name", deckId)
+	if err != nil {
+		glog.Errorf("error while listing snapshots of deck with id %s", deckId, err)
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// DeleteSnapshot deletes the deck's snapshot of the given name, sql.ErrNoRows tells there was none
+func (r *deckRepo) DeleteSnapshot(deckId, name string) error {
+	res, err := r.db.Exec("delete from deck_snapshots where deck_id=$1 and name=$2", deckId, name)
+	if err != nil {
+		glog.Errorf("error while deleting snapshot %s of deck with id %s", name, deckId, err)
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,4 @@
+package repo
+
+// SetupTestContainer lets the contract tests of the repo_test package run against Postgres
+var SetupTestContainer = setupTestContainer
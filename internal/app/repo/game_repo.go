@@ -0,0 +1,74 @@
+package repo
+
+import (
+	"github.com/golang/glog"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type GameRepo interface {
+	CreateGame(game Game) error
+	GetGameById(id string) (*Game, error)
+	GetGamesByDeckId(deckId string) ([]Game, error)
+	UpdateGame(game Game) error
+}
+
+type gameRepo struct {
+	db *sqlx.DB
+}
+
+func NewGameRepo(db *sqlx.DB) GameRepo {
+	return &gameRepo{db: db}
+}
+
+func (r *gameRepo) CreateGame(game Game) error {
+	_, err := r.db.NamedExec(`insert into games (id, game_type, deck_id, state, version, created_at, updated_at)
+                          values (:id, :game_type, :deck_id, :state, :version, :created_at, :updated_at)`, game)
+	if err != nil {
+		glog.Errorf("error while creating game with id %s", game.Id, err)
+		return err
+	}
+	return nil
+}
+
+func (r *gameRepo) GetGameById(id string) (*Game, error) {
+	var game Game
+	err := r.db.Get(&game, "select * from games where id=$1", id)
+	if err != nil {
+		glog.Errorf("error while getting game with id %s", id, err)
+		return nil, err
+	}
+	return &game, nil
+}
+
+// GetGamesByDeckId returns the games dealing from the deck, oldest first
+func (r *gameRepo) GetGamesByDeckId(deckId string) ([]Game, error) {
+	games := []Game{}
+	err := r.db.Select(&games, "select * from games where deck_id=$1 order by created_at, id", deckId)
+	if err != nil {
+		glog.Errorf("error while getting games of deck with id %s", deckId, err)
+		return nil, err
+	}
+	return games, nil
+}
+
+// UpdateGame saves the game's state and deck, as a game may move on to a new deck, if nobody else updated it since it
+// was read, and returns ErrVersionConflict otherwise
+func (r *gameRepo) UpdateGame(game Game) error {
+	res, err := r.db.Exec(`update games set state=$1, deck_id=$2, updated_at=$3, version=version+1
+                          where id=$4 and version=$5`,
+		game.State, game.DeckId, time.Now().UTC(), game.Id, game.Version)
+	if err != nil {
+		glog.Errorf("error while updating game with id %s", game.Id, err)
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		glog.Warningf("version conflict while updating game with id %s", game.Id)
+		return ErrVersionConflict
+	}
+	return nil
+}
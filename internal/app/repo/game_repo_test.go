@@ -0,0 +1,57 @@
+package repo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestGameRepo(t *testing.T) {
+	db, _, cleanup := setupTestContainer(t)
+	defer cleanup()
+
+	deckRepo := NewDeckRepo(db)
+	gameRepo := NewGameRepo(db)
+	now := time.Now().UTC()
+	assert.NoError(t, deckRepo.CreateDeck(Deck{Id: "shoe-id", Remaining: 2, Cards: []string{"AS", "KD"}, CreatedAt: now,
+		UpdatedAt: now}, DeckEvent{Type: CreateEvent}))
+	assert.NoError(t, deckRepo.CreateDeck(Deck{Id: "next-shoe-id", Remaining: 2, Cards: []string{"AS", "KD"}, CreatedAt: now,
+		UpdatedAt: now}, DeckEvent{Type: CreateEvent}))
+
+	// Test case: CreateGame
+	game := Game{
+		Id:        "game-id",
+		GameType:  BlackjackGame,
+		DeckId:    "shoe-id",
+		State:     `{"status": "player_turn"}`,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	assert.NoError(t, gameRepo.CreateGame(game))
+
+	// Test case: GetGameById
+	fetched, err := gameRepo.GetGameById("game-id")
+	assert.NoError(t, err)
+	assert.Equal(t, BlackjackGame, fetched.GameType)
+	assert.Equal(t, "shoe-id", fetched.DeckId)
+	assert.JSONEq(t, game.State, fetched.State)
+	assert.Equal(t, 0, fetched.Version)
+
+	// Test case: UpdateGame
+	fetched.State = `{"status": "settled"}`
+	fetched.DeckId = "next-shoe-id"
+	assert.NoError(t, gameRepo.UpdateGame(*fetched))
+	updated, err := gameRepo.GetGameById("game-id")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"status": "settled"}`, updated.State)
+	assert.Equal(t, "next-shoe-id", updated.DeckId)
+	assert.Equal(t, 1, updated.Version)
+
+	// Test case: a stale update is rejected
+	assert.Equal(t, ErrVersionConflict, gameRepo.UpdateGame(*fetched))
+
+	// Test case: a game without a deck can't be created
+	game.Id = "other-game-id"
+	game.DeckId = "unknown-deck"
+	assert.Error(t, gameRepo.CreateGame(game))
+}
@@ -0,0 +1,411 @@
+package repo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryRepo keeps decks, their history and snapshots, and games in memory, for local development, demos and tests
+// without Postgres. It behaves like the Postgres repositories, one lock guards all of it so a deck and its piles
+// always change together. Everything is lost when the process ends.
+type memoryRepo struct {
+	mu        sync.RWMutex
+	decks     map[string]Deck
+	events    map[string][]DeckEvent
+	snapshots map[string]map[string]DeckSnapshot
+	games     map[string]Game
+}
+
+// NewMemoryRepos returns a deck and a game repository sharing one in-memory store, so deleting a deck deletes its
+// games like the database does
+func NewMemoryRepos() (DeckRepo, GameRepo) {
+	r := &memoryRepo{
+		decks:     make(map[string]Deck),
+		events:    make(map[string][]DeckEvent),
+		snapshots: make(map[string]map[string]DeckSnapshot),
+		games:     make(map[string]Game),
+	}
+	return r, r
+}
+
+func (r *memoryRepo) CreateDeck(deck Deck, event DeckEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, found := r.decks[deck.Id]; found {
+		return fmt.Errorf("deck with id %s already exists", deck.Id)
+	}
+	deck = copyDeck(deck)
+	deck.Drawn = emptyIfNil(deck.Drawn)
+	deck.Discarded = emptyIfNil(deck.Discarded)
+	deck.InitialCards = emptyIfNil(deck.InitialCards)
+	if len(deck.DeckType) == 0 {
+		deck.DeckType = Standard
+	}
+	for i := range deck.Piles {
+		deck.Piles[i].DeckId = deck.Id
+		deck.Piles[i].Cards = emptyIfNil(deck.Piles[i].Cards)
+	}
+	if err := r.recordEvent(Deck{}, deck, event, deck.CreatedAt); err != nil {
+		return err
+	}
+	r.decks[deck.Id] = deck
+	return nil
+}
+
+func (r *memoryRepo) GetDeckById(id string) (*Deck, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deck, found := r.decks[id]
+	if !found {
+		return nil, sql.ErrNoRows
+	}
+	deck = copyDeck(deck)
+	sortPiles(deck.Piles)
+	return &deck, nil
+}
+
+// UpdateDeck saves the cards of the deck and its piles, piles left out are kept. It returns ErrVersionConflict if the
+// deck was updated since it was read.
+func (r *memoryRepo) UpdateDeck(deck Deck, event DeckEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	before, found := r.decks[deck.Id]
+	if !found || before.Version != deck.Version {
+		return ErrVersionConflict
+	}
+	now := time.Now().UTC()
+	updated := copyDeck(before)
+	updated.Shuffled, updated.ShuffledAt = deck.Shuffled, copyTime(deck.ShuffledAt)
+	updated.Remaining, updated.Size = deck.Remaining, deck.Size
+	updated.Cards = emptyIfNil(*copyCards(deck.Cards))
+	updated.Drawn = emptyIfNil(*copyCards(deck.Drawn))
+	updated.Discarded = emptyIfNil(*copyCards(deck.Discarded))
+	updated.UpdatedAt = now
+	updated.Version++
+	for _, p := range deck.Piles {
+		cards := *copyCards(p.Cards)
+		found = false
+		for i := range updated.Piles {
+			if updated.Piles[i].Name == p.Name {
+				updated.Piles[i].Cards, updated.Piles[i].UpdatedAt = cards, now
+				found = true
+			}
+		}
+		if !found {
+			updated.Piles = append(updated.Piles,
+				Pile{DeckId: deck.Id, Name: p.Name, Cards: cards, CreatedAt: now, UpdatedAt: now})
+		}
+	}
+	deck.Version++
+	if err := r.recordEvent(before, deck, event, now); err != nil {
+		return err
+	}
+	r.decks[deck.Id] = updated
+	return nil
+}
+
+func (r *memoryRepo) GetDeckEvents(id string, after int, limit int) ([]DeckEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	events := []DeckEvent{}
+	for _, e := range r.events[id] {
+		if e.Number > after && len(events) < limit {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// recordEvent appends the event turning the deck from before into after to its history, like insertEvent does
+func (r *memoryRepo) recordEvent(before, after Deck, event DeckEvent, at time.Time) error {
+	changes, err := json.Marshal(DiffDeck(before, after))
+	if err != nil {
+		return err
+	}
+	if len(event.Payload) == 0 {
+		event.Payload = "{}"
+	}
+	event.DeckId = after.Id
+	event.Number = after.Version + 1
+	event.Changes = string(changes)
+	event.CreatedAt = at
+	r.events[after.Id] = append(r.events[after.Id], event)
+	return nil
+}
+
+// ListDecks filters and orders the decks like the database query does, leaving out their cards and piles
+func (r *memoryRepo) ListDecks(filter DeckFilter) ([]Deck, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	decks := []Deck{}
+	for _, d := range r.decks {
+		switch {
+		case filter.CreatedFrom != nil && d.CreatedAt.Before(*filter.CreatedFrom),
+			filter.CreatedTo != nil && !d.CreatedAt.Before(*filter.CreatedTo),
+			filter.UpdatedFrom != nil && d.UpdatedAt.Before(*filter.UpdatedFrom),
+			filter.UpdatedTo != nil && !d.UpdatedAt.Before(*filter.UpdatedTo),
+			filter.Shuffled != nil && d.Shuffled != *filter.Shuffled,
+			filter.Empty != nil && (d.Remaining == 0) != *filter.Empty,
+			len(filter.Owner) > 0 && d.Owner != filter.Owner,
+			len(filter.ParentId) > 0 && (d.ParentId == nil || *d.ParentId != filter.ParentId),
+			filter.ActiveAt != nil && d.ExpiresAt != nil && !d.ExpiresAt.After(*filter.ActiveAt),
+			filter.After != nil && (d.CreatedAt.Before(filter.After.CreatedAt) ||
+				d.CreatedAt.Equal(filter.After.CreatedAt) && d.Id <= filter.After.Id):
+			continue
+		}
+		decks = append(decks, Deck{
+			Id:         d.Id,
+			Shuffled:   d.Shuffled,
+			ShuffledAt: copyTime(d.ShuffledAt),
+			Remaining:  d.Remaining,
+			DeckType:   d.DeckType,
+			Jokers:     d.Jokers,
+			DecksCount: d.DecksCount,
+			Size:       d.Size,
+			Owner:      d.Owner,
+			ParentId:   copyString(d.ParentId),
+			ExpiresAt:  copyTime(d.ExpiresAt),
+			Version:    d.Version,
+			CreatedAt:  d.CreatedAt,
+			UpdatedAt:  d.UpdatedAt,
+		})
+	}
+	sort.Slice(decks, func(i, j int) bool {
+		if !decks[i].CreatedAt.Equal(decks[j].CreatedAt) {
+			return decks[i].CreatedAt.Before(decks[j].CreatedAt)
+		}
+		return decks[i].Id < decks[j].Id
+	})
+	if len(decks) > filter.Limit {
+		decks = decks[:filter.Limit]
+	}
+	return decks, nil
+}
+
+func (r *memoryRepo) DeleteDeck(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, found := r.decks[id]; !found {
+		return sql.ErrNoRows
+	}
+	r.deleteDeck(id)
+	return nil
+}
+
+// DeleteExpiredDecks deletes decks like the database query does, a deck whose game was played since idleSince isn't idle
+func (r *memoryRepo) DeleteExpiredDecks(now time.Time, idleSince *time.Time, limit int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	played := make(map[string]bool)
+	for _, g := range r.games {
+		if idleSince != nil && !g.UpdatedAt.Before(*idleSince) {
+			played[g.DeckId] = true
+		}
+	}
+	deleted := 0
+	for id, d := range r.decks {
+		if deleted == limit {
+			break
+		}
+		expired := d.ExpiresAt != nil && !d.ExpiresAt.After(now)
+		idle := idleSince != nil && d.UpdatedAt.Before(*idleSince) && !played[id]
+		if expired || idle {
+			r.deleteDeck(id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// deleteDeck deletes the deck with everything stored with it, the caller holds the lock
+func (r *memoryRepo) deleteDeck(id string) {
+	delete(r.decks, id)
+	delete(r.events, id)
+	delete(r.snapshots, id)
+	for gameId, g := range r.games {
+		if g.DeckId == id {
+			delete(r.games, gameId)
+		}
+	}
+}
+
+func (r *memoryRepo) SaveSnapshot(snapshot DeckSnapshot, limit int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, found := r.decks[snapshot.DeckId]; !found {
+		return fmt.Errorf("deck with id %s doesn't exist", snapshot.DeckId)
+	}
+	if _, found := r.snapshots[snapshot.DeckId][snapshot.Name]; !found && len(r.snapshots[snapshot.DeckId]) >= limit {
+		return ErrTooManySnapshots
+	}
+	if r.snapshots[snapshot.DeckId] == nil {
+		r.snapshots[snapshot.DeckId] = make(map[string]DeckSnapshot)
+	}
+	r.snapshots[snapshot.DeckId][snapshot.Name] = snapshot
+	return nil
+}
+
+func (r *memoryRepo) GetSnapshot(deckId, name string) (*DeckSnapshot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	snapshot, found := r.snapshots[deckId][name]
+	if !found {
+		return nil, sql.ErrNoRows
+	}
+	return &snapshot, nil
+}
+
+func (r *memoryRepo) ListSnapshots(deckId string) ([]DeckSnapshot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	snapshots := []DeckSnapshot{}
+	for _, s := range r.snapshots[deckId] {
+		snapshots = append(snapshots, s)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		if !snapshots[i].CreatedAt.Equal(snapshots[j].CreatedAt) {
+			return snapshots[i].CreatedAt.Before(snapshots[j].CreatedAt)
+		}
+		return snapshots[i].Name < snapshots[j].Name
+	})
+	return snapshots, nil
+}
+
+func (r *memoryRepo) DeleteSnapshot(deckId, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, found := r.snapshots[deckId][name]; !found {
+		return sql.ErrNoRows
+	}
+	delete(r.snapshots[deckId], name)
+	return nil
+}
+
+func (r *memoryRepo) CreateGame(game Game) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, found := r.games[game.Id]; found {
+		return fmt.Errorf("game with id %s already exists", game.Id)
+	}
+	if _, found := r.decks[game.DeckId]; !found {
+		return fmt.Errorf("deck with id %s doesn't exist", game.DeckId)
+	}
+	r.games[game.Id] = game
+	return nil
+}
+
+func (r *memoryRepo) GetGameById(id string) (*Game, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	game, found := r.games[id]
+	if !found {
+		return nil, sql.ErrNoRows
+	}
+	return &game, nil
+}
+
+func (r *memoryRepo) GetGamesByDeckId(deckId string) ([]Game, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	games := []Game{}
+	for _, game := range r.games {
+		if game.DeckId == deckId {
+			games = append(games, game)
+		}
+	}
+	sort.Slice(games, func(i, j int) bool {
+		if !games[i].CreatedAt.Equal(games[j].CreatedAt) {
+			return games[i].CreatedAt.Before(games[j].CreatedAt)
+		}
+		return games[i].Id < games[j].Id
+	})
+	return games, nil
+}
+
+func (r *memoryRepo) UpdateGame(game Game) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, found := r.games[game.Id]
+	if !found || stored.Version != game.Version {
+		return ErrVersionConflict
+	}
+	if _, found = r.decks[game.DeckId]; !found {
+		return fmt.Errorf("deck with id %s doesn't exist", game.DeckId)
+	}
+	stored.State, stored.DeckId = game.State, game.DeckId
+	stored.UpdatedAt = time.Now().UTC()
+	stored.Version++
+	r.games[game.Id] = stored
+	return nil
+}
+
+// copyDeck copies the cards, piles and pointers of the deck, so the stored deck never shares them with a caller
+func copyDeck(deck Deck) Deck {
+	if deck.Cards != nil {
+		deck.Cards = *copyCards(deck.Cards)
+	}
+	if deck.Drawn != nil {
+		deck.Drawn = *copyCards(deck.Drawn)
+	}
+	if deck.Discarded != nil {
+		deck.Discarded = *copyCards(deck.Discarded)
+	}
+	if deck.InitialCards != nil {
+		deck.InitialCards = *copyCards(deck.InitialCards)
+	}
+	deck.ShuffledAt = copyTime(deck.ShuffledAt)
+	deck.ExpiresAt = copyTime(deck.ExpiresAt)
+	deck.ParentId = copyString(deck.ParentId)
+	if deck.Piles != nil {
+		deck.Piles = append([]Pile{}, deck.Piles...)
+		for i := range deck.Piles {
+			deck.Piles[i].Cards = *copyCards(deck.Piles[i].Cards)
+		}
+	}
+	return deck
+}
+
+// sortPiles orders piles like the database returns them, by creation time then name
+func sortPiles(piles []Pile) {
+	sort.SliceStable(piles, func(i, j int) bool {
+		if !piles[i].CreatedAt.Equal(piles[j].CreatedAt) {
+			return piles[i].CreatedAt.Before(piles[j].CreatedAt)
+		}
+		return piles[i].Name < piles[j].Name
+	})
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	copied := *t
+	return &copied
+}
+
+func copyString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	copied := *s
+	return &copied
+}
@@ -0,0 +1,66 @@
+package repo
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryRepoConcurrentDraws(t *testing.T) {
+	repo, _ := NewMemoryRepos()
+	var cards []string
+	for _, s := range SequentialSuits {
+		for _, v := range SequentialValues {
+			cards = append(cards, fmt.Sprintf("%s%s", v, s))
+		}
+	}
+	now := time.Now().UTC()
+	err := repo.CreateDeck(Deck{Id: "concurrent-deck-id", Remaining: len(cards), Cards: cards, CreatedAt: now, UpdatedAt: now},
+		DeckEvent{Type: CreateEvent})
+	assert.NoError(t, err)
+
+	// Test case: hundreds of parallel draws never hand out the same card twice
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	dealt := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				deck, err := repo.GetDeckById("concurrent-deck-id")
+				if !assert.NoError(t, err) || deck.Remaining == 0 {
+					return
+				}
+				card := deck.Cards[0]
+				deck.Cards = deck.Cards[1:]
+				deck.Remaining--
+				err = repo.UpdateDeck(*deck, DeckEvent{Type: DrawEvent})
+				if err == ErrVersionConflict {
+					continue
+				}
+				assert.NoError(t, err)
+				mu.Lock()
+				dealt[card]++
+				mu.Unlock()
+				return
+			}
+		}()
+	}
+	wg.Wait()
+
+	for card, count := range dealt {
+		assert.Equal(t, 1, count, "card %s was dealt more than once", card)
+	}
+	assert.Len(t, dealt, len(cards))
+
+	deck, err := repo.GetDeckById("concurrent-deck-id")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, deck.Remaining)
+	assert.Empty(t, deck.Cards)
+	events, err := repo.GetDeckEvents("concurrent-deck-id", 0, 100)
+	assert.NoError(t, err)
+	assert.Len(t, events, len(cards)+1)
+}
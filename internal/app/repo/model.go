@@ -7,6 +7,7 @@ import (
 
 type CardCode string
 type SuitCode string
+type DeckType string
 
 const (
 	Ace   CardCode = "A"
@@ -24,6 +25,12 @@ const (
 	King  CardCode = "K"
 )
 
+// Jokers don't have a suit, their code is the whole card code
+const (
+	RedJoker   CardCode = "X1"
+	BlackJoker CardCode = "X2"
+)
+
 const (
 	Spades   SuitCode = "S"
 	Diamonds SuitCode = "D"
@@ -31,8 +38,31 @@ const (
 	Hearts   SuitCode = "H"
 )
 
+const (
+	Standard DeckType = "standard"
+	Piquet   DeckType = "piquet"
+	Euchre   DeckType = "euchre"
+	Pinochle DeckType = "pinochle"
+	Stripped DeckType = "stripped"
+)
+
 var SequentialValues = []CardCode{Ace, Two, Three, Four, Five, Six, Seven, Eight, Nine, Ten, Jack, Queen, King}
 var SequentialSuits = []SuitCode{Spades, Diamonds, Clubs, Hearts}
+var SequentialJokers = []CardCode{RedJoker, BlackJoker}
+
+// DeckComposition describes one deck of a type: every suit has the Values, and the deck contains Copies of each card
+type DeckComposition struct {
+	Values []CardCode
+	Copies int
+}
+
+var Compositions = map[DeckType]DeckComposition{
+	Standard: {Values: SequentialValues, Copies: 1},
+	Piquet:   {Values: []CardCode{Ace, Seven, Eight, Nine, Ten, Jack, Queen, King}, Copies: 1},
+	Euchre:   {Values: []CardCode{Ace, Nine, Ten, Jack, Queen, King}, Copies: 1},
+	Pinochle: {Values: []CardCode{Ace, Nine, Ten, Jack, Queen, King}, Copies: 2},
+	Stripped: {Values: []CardCode{Ace, Six, Seven, Eight, Nine, Ten, Jack, Queen, King}, Copies: 1},
+}
 
 var Values = map[CardCode]string{
 	Ace:   "ACE",
@@ -57,11 +87,126 @@ var Suites = map[SuitCode]string{
 	Hearts:   "HEARTS",
 }
 
+type Color string
+
+const (
+	Red   Color = "RED"
+	Black Color = "BLACK"
+)
+
+var SuitColors = map[SuitCode]Color{
+	Spades:   Black,
+	Diamonds: Red,
+	Clubs:    Black,
+	Hearts:   Red,
+}
+
+// Rank is the position of the value in SequentialValues, from 1 for the ace to 13 for the king, and 0 for codes that
+// aren't values
+func Rank(value CardCode) int {
+	for i, v := range SequentialValues {
+		if v == value {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// AceHighRank ranks the ace above the king as 14, the other values like Rank
+func AceHighRank(value CardCode) int {
+	if value == Ace {
+		return len(SequentialValues) + 1
+	}
+	return Rank(value)
+}
+
+// symbolBases are the code points before the ace of each suit in the Unicode Playing Cards block
+var symbolBases = map[SuitCode]rune{
+	Spades:   0x1F0A0,
+	Hearts:   0x1F0B0,
+	Diamonds: 0x1F0C0,
+	Clubs:    0x1F0D0,
+}
+
+var JokerSymbols = map[CardCode]string{
+	RedJoker:   "\U0001F0BF",
+	BlackJoker: "\U0001F0CF",
+}
+
+// Symbol is the character of the card in the Unicode Playing Cards block
+func Symbol(value CardCode, suit SuitCode) string {
+	point := Rank(value)
+	// the block has a knight between the jack and the queen
+	if point > Rank(Jack) {
+		point++
+	}
+	return string(symbolBases[suit] + rune(point))
+}
+
+// Jokers maps the joker codes to their colour, which is returned as their suit
+var Jokers = map[CardCode]string{
+	RedJoker:   string(Red),
+	BlackJoker: string(Black),
+}
+
 type Deck struct {
-	Id        string         `db:"id"`
-	Shuffled  bool           `db:"shuffled"`
-	Remaining int            `db:"remaining"`
+	Id       string `db:"id"`
+	Shuffled bool   `db:"shuffled"`
+	// ShuffledAt is when the deck was last shuffled, nil if it never was
+	ShuffledAt *time.Time     `db:"shuffled_at"`
+	Remaining  int            `db:"remaining"`
+	Cards      pq.StringArray `db:"cards"`
+	Drawn      pq.StringArray `db:"drawn"`
+	Discarded  pq.StringArray `db:"discarded"`
+	DeckType   DeckType       `db:"deck_type"`
+	Jokers     bool           `db:"jokers"`
+	// ServerSeed and ClientSeed produced the shuffle of InitialCards, the deck in its order before shuffling.
+	// Seeded decks got their server seed from the client to be reproducible.
+	ServerSeed   string         `db:"server_seed"`
+	Seeded       bool           `db:"seeded"`
+	ClientSeed   string         `db:"client_seed"`
+	InitialCards pq.StringArray `db:"initial_cards"`
+	// DecksCount is the number of decks in the shoe and Size the number of cards it was created with.
+	// Dealing CutCard cards reaches the cut card, 0 means there is none.
+	DecksCount int    `db:"decks_count"`
+	CutCard    int    `db:"cut_card"`
+	Size       int    `db:"size"`
+	Owner      string `db:"owner"`
+	// ParentId is the deck a clone was made from
+	ParentId  *string    `db:"parent_id"`
+	ExpiresAt *time.Time `db:"expires_at"`
+	Version   int        `db:"version"`
+	CreatedAt time.Time  `db:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at"`
+	Piles     []Pile     `db:"-"`
+}
+
+// Pile is a named stack of cards belonging to a deck, like a player's hand or the board
+type Pile struct {
+	DeckId    string         `db:"deck_id"`
+	Name      string         `db:"name"`
 	Cards     pq.StringArray `db:"cards"`
 	CreatedAt time.Time      `db:"created_at"`
 	UpdatedAt time.Time      `db:"updated_at"`
 }
+
+type GameType string
+
+const (
+	BlackjackGame GameType = "blackjack"
+	HoldemGame    GameType = "holdem"
+	WarGame       GameType = "war"
+	KlondikeGame  GameType = "klondike"
+)
+
+// Game is a game dealt from a deck. State is the JSON encoded state of the game's engine, Version guards it against
+// concurrent moves like it does for decks.
+type Game struct {
+	Id        string    `db:"id"`
+	GameType  GameType  `db:"game_type"`
+	DeckId    string    `db:"deck_id"`
+	State     string    `db:"state"`
+	Version   int       `db:"version"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
@@ -0,0 +1,457 @@
+// Package repotest holds the behaviour every backend of the repositories must have. The Postgres and in-memory
+// backends and the mocks of the service tests all run it, so they can't drift apart.
+package repotest
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/deck/internal/app/repo"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// DeckRepoContract runs the behaviour of repo.DeckRepo against the repositories of newRepo, which returns an empty one
+// for every subtest
+func DeckRepoContract(t *testing.T, newRepo func(t *testing.T) repo.DeckRepo) {
+	t.Run("CreateDeck", func(t *testing.T) { testCreateDeck(t, newRepo(t)) })
+	t.Run("UpdateDeck", func(t *testing.T) { testUpdateDeck(t, newRepo(t)) })
+	t.Run("ListDecks", func(t *testing.T) { testListDecks(t, newRepo(t)) })
+	t.Run("DeleteDeck", func(t *testing.T) { testDeleteDeck(t, newRepo(t)) })
+	t.Run("DeleteExpiredDecks", func(t *testing.T) { testDeleteExpiredDecks(t, newRepo(t)) })
+	t.Run("Snapshots", func(t *testing.T) { testSnapshots(t, newRepo(t)) })
+}
+
+// GameRepoContract runs the behaviour of repo.GameRepo against the repositories of newRepos, which returns empty deck
+// and game repositories sharing their storage for every subtest
+func GameRepoContract(t *testing.T, newRepos func(t *testing.T) (repo.DeckRepo, repo.GameRepo)) {
+	t.Run("Games", func(t *testing.T) {
+		deckRepo, gameRepo := newRepos(t)
+		testGames(t, deckRepo, gameRepo)
+	})
+}
+
+// now is truncated to the precision of Postgres timestamps
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func createDeck(t *testing.T, r repo.DeckRepo, deck repo.Deck) {
+	if deck.CreatedAt.IsZero() {
+		deck.CreatedAt = now()
+	}
+	if deck.UpdatedAt.IsZero() {
+		deck.UpdatedAt = deck.CreatedAt
+	}
+	assert.NoError(t, r.CreateDeck(deck, repo.DeckEvent{Type: repo.CreateEvent}))
+}
+
+func deckIds(decks []repo.Deck) []string {
+	ids := make([]string, len(decks))
+	for i, d := range decks {
+		ids[i] = d.Id
+	}
+	return ids
+}
+
+func pileNames(piles []repo.Pile) []string {
+	names := make([]string, len(piles))
+	for i, p := range piles {
+		names[i] = p.Name
+	}
+	return names
+}
+
+func testCreateDeck(t *testing.T, r repo.DeckRepo) {
+	createdAt := now()
+	shuffledAt, expiresAt := createdAt.Add(-time.Minute), createdAt.Add(time.Hour)
+	parentId := "parent-id"
+	deck := repo.Deck{
+		Id:           "deck-id",
+		Shuffled:     true,
+		ShuffledAt:   &shuffledAt,
+		Remaining:    3,
+		Cards:        []string{"AS", "KH", "QD"},
+		DeckType:     repo.Piquet,
+		Jokers:       true,
+		ServerSeed:   "server-seed",
+		Seeded:       true,
+		ClientSeed:   "client-seed",
+		InitialCards: []string{"KH", "QD", "AS", "JC"},
+		DecksCount:   1,
+		CutCard:      2,
+		Size:         4,
+		Owner:        "alice",
+		ParentId:     &parentId,
+		ExpiresAt:    &expiresAt,
+		CreatedAt:    createdAt,
+		UpdatedAt:    createdAt,
+		Piles:        []repo.Pile{{Name: "hand", Cards: []string{"JC"}, CreatedAt: createdAt, UpdatedAt: createdAt}},
+	}
+	event := repo.DeckEvent{Type: repo.CreateEvent, Actor: "alice", Payload: `{"size": 4}`}
+
+	// Test case: the deck is saved with its piles and settings, apart from the caller's cards
+	assert.NoError(t, r.CreateDeck(deck, event))
+	deck.Cards[0] = "2S"
+	fetched, err := r.GetDeckById("deck-id")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"AS", "KH", "QD"}, []string(fetched.Cards))
+	assert.Empty(t, fetched.Drawn)
+	assert.Empty(t, fetched.Discarded)
+	assert.Equal(t, 3, fetched.Remaining)
+	assert.True(t, fetched.Shuffled)
+	assert.True(t, shuffledAt.Equal(*fetched.ShuffledAt))
+	assert.Equal(t, repo.Piquet, fetched.DeckType)
+	assert.True(t, fetched.Jokers)
+	assert.Equal(t, "server-seed", fetched.ServerSeed)
+	assert.True(t, fetched.Seeded)
+	assert.Equal(t, "client-seed", fetched.ClientSeed)
+	assert.Equal(t, []string{"KH", "QD", "AS", "JC"}, []string(fetched.InitialCards))
+	assert.Equal(t, 1, fetched.DecksCount)
+	assert.Equal(t, 2, fetched.CutCard)
+	assert.Equal(t, 4, fetched.Size)
+	assert.Equal(t, "alice", fetched.Owner)
+	assert.Equal(t, "parent-id", *fetched.ParentId)
+	assert.True(t, expiresAt.Equal(*fetched.ExpiresAt))
+	assert.Equal(t, 0, fetched.Version)
+	assert.True(t, createdAt.Equal(fetched.CreatedAt))
+	assert.Len(t, fetched.Piles, 1)
+	assert.Equal(t, "deck-id", fetched.Piles[0].DeckId)
+	assert.Equal(t, []string{"JC"}, []string(fetched.Piles[0].Cards))
+
+	// Test case: changing a fetched deck doesn't change the saved one
+	fetched.Cards[0] = "2S"
+	fetched.Piles[0].Cards[0] = "2S"
+	again, err := r.GetDeckById("deck-id")
+	assert.NoError(t, err)
+	assert.Equal(t, "AS", again.Cards[0])
+	assert.Equal(t, "JC", again.Piles[0].Cards[0])
+
+	// Test case: the event creating the deck holds its whole state
+	events, err := r.GetDeckEvents("deck-id", 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "deck-id", events[0].DeckId)
+	assert.Equal(t, 1, events[0].Number)
+	assert.Equal(t, repo.CreateEvent, events[0].Type)
+	assert.Equal(t, "alice", events[0].Actor)
+	assert.JSONEq(t, `{"size": 4}`, events[0].Payload)
+	assert.True(t, createdAt.Equal(events[0].CreatedAt))
+	var changes repo.DeckChanges
+	assert.NoError(t, json.Unmarshal([]byte(events[0].Changes), &changes))
+	assert.Equal(t, []string{"AS", "KH", "QD"}, *changes.Cards)
+	assert.Equal(t, []string{"JC"}, changes.Piles["hand"])
+
+	// Test case: a deck without type is a standard one, an event without payload has an empty one
+	assert.NoError(t, r.CreateDeck(repo.Deck{Id: "other-id", CreatedAt: createdAt, UpdatedAt: createdAt},
+		repo.DeckEvent{Type: repo.CreateEvent}))
+	other, err := r.GetDeckById("other-id")
+	assert.NoError(t, err)
+	assert.Equal(t, repo.Standard, other.DeckType)
+	assert.Nil(t, other.ParentId)
+	assert.Nil(t, other.ExpiresAt)
+	assert.Empty(t, other.Piles)
+	events, err = r.GetDeckEvents("other-id", 0, 10)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{}`, events[0].Payload)
+
+	// Test case: ids are unique
+	assert.Error(t, r.CreateDeck(deck, event))
+
+	// Test case: unknown deck
+	_, err = r.GetDeckById("unknown")
+	assert.Equal(t, sql.ErrNoRows, err)
+	events, err = r.GetDeckEvents("unknown", 0, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func testUpdateDeck(t *testing.T, r repo.DeckRepo) {
+	start := now()
+	createDeck(t, r, repo.Deck{Id: "deck-id", Remaining: 3, Cards: []string{"AS", "KH", "QD"}, Size: 3, Owner: "alice",
+		CreatedAt: start})
+
+	// Test case: the cards of the deck and new piles are saved, its settings aren't
+	deck, err := r.GetDeckById("deck-id")
+	assert.NoError(t, err)
+	deck.Cards = []string{"QD"}
+	deck.Remaining = 1
+	deck.Drawn = []string{"AS"}
+	deck.Owner = "bob"
+	deck.Piles = []repo.Pile{{Name: "hand", Cards: []string{"KH"}}, {Name: "board"}}
+	err = r.UpdateDeck(*deck, repo.DeckEvent{Type: repo.DrawEvent, Actor: "bob", Payload: `{"count": 2}`})
+	assert.NoError(t, err)
+
+	updated, err := r.GetDeckById("deck-id")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"QD"}, []string(updated.Cards))
+	assert.Equal(t, []string{"AS"}, []string(updated.Drawn))
+	assert.Equal(t, 1, updated.Remaining)
+	assert.Equal(t, "alice", updated.Owner)
+	assert.Equal(t, 1, updated.Version)
+	assert.False(t, updated.UpdatedAt.Before(start))
+	// piles created together are ordered by name
+	assert.Equal(t, []string{"board", "hand"}, pileNames(updated.Piles))
+	assert.Empty(t, updated.Piles[0].Cards)
+	assert.Equal(t, []string{"KH"}, []string(updated.Piles[1].Cards))
+
+	// Test case: piles left out of an update are kept, older piles come first
+	updated.Piles = []repo.Pile{{Name: "hand", Cards: []string{"KH", "QD"}}, {Name: "discard", Cards: []string{}}}
+	updated.Cards = []string{}
+	updated.Remaining = 0
+	assert.NoError(t, r.UpdateDeck(*updated, repo.DeckEvent{Type: repo.MoveCardsEvent}))
+	moved, err := r.GetDeckById("deck-id")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"board", "hand", "discard"}, pileNames(moved.Piles))
+	assert.Equal(t, []string{"KH", "QD"}, []string(moved.Piles[1].Cards))
+	assert.Equal(t, 2, moved.Version)
+
+	// Test case: a stale update changes nothing
+	updated.Piles[0].Cards = nil
+	assert.Equal(t, repo.ErrVersionConflict, r.UpdateDeck(*updated, repo.DeckEvent{Type: repo.DrawEvent}))
+	unchanged, err := r.GetDeckById("deck-id")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, unchanged.Version)
+	assert.Equal(t, []string{"KH", "QD"}, []string(unchanged.Piles[1].Cards))
+	assert.Equal(t, repo.ErrVersionConflict, r.UpdateDeck(repo.Deck{Id: "unknown"}, repo.DeckEvent{Type: repo.DrawEvent}))
+
+	// Test case: every update is recorded with the fields it changed
+	events, err := r.GetDeckEvents("deck-id", 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, events, 3)
+	assert.Equal(t, []int{1, 2, 3}, []int{events[0].Number, events[1].Number, events[2].Number})
+	assert.Equal(t, repo.DrawEvent, events[1].Type)
+	assert.Equal(t, "bob", events[1].Actor)
+	assert.JSONEq(t, `{"count": 2}`, events[1].Payload)
+	assert.False(t, events[1].CreatedAt.Before(start))
+	var changes repo.DeckChanges
+	assert.NoError(t, json.Unmarshal([]byte(events[1].Changes), &changes))
+	assert.Equal(t, []string{"QD"}, *changes.Cards)
+	assert.Equal(t, []string{"AS"}, *changes.Drawn)
+	assert.Equal(t, 1, *changes.Remaining)
+	assert.Nil(t, changes.Discarded)
+	assert.Equal(t, map[string][]string{"hand": {"KH"}, "board": {}}, changes.Piles)
+	var moveChanges repo.DeckChanges
+	assert.NoError(t, json.Unmarshal([]byte(events[2].Changes), &moveChanges))
+	assert.Equal(t, map[string][]string{"hand": {"KH", "QD"}, "discard": {}}, moveChanges.Piles)
+
+	events, err = r.GetDeckEvents("deck-id", 1, 1)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, 2, events[0].Number)
+}
+
+func testListDecks(t *testing.T, r repo.DeckRepo) {
+	start := now()
+	parentId, expiresAt := "deck-a", start.Add(90*time.Minute)
+	createDeck(t, r, repo.Deck{Id: "deck-a", Owner: "alice", Shuffled: true, Remaining: 1, Cards: []string{"AS"},
+		CreatedAt: start, Piles: []repo.Pile{{Name: "hand", CreatedAt: start, UpdatedAt: start}}})
+	createDeck(t, r, repo.Deck{Id: "deck-b", Owner: "bob", ParentId: &parentId, ExpiresAt: &expiresAt,
+		CreatedAt: start.Add(time.Hour)})
+	createDeck(t, r, repo.Deck{Id: "deck-c", Owner: "alice", Remaining: 1, Cards: []string{"KH"},
+		CreatedAt: start.Add(2 * time.Hour), UpdatedAt: start.Add(3 * time.Hour)})
+
+	// Test case: every deck oldest first, without its cards and piles
+	decks, err := r.ListDecks(repo.DeckFilter{Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"deck-a", "deck-b", "deck-c"}, deckIds(decks))
+	assert.Equal(t, "alice", decks[0].Owner)
+	assert.True(t, decks[0].Shuffled)
+	assert.Equal(t, 1, decks[0].Remaining)
+	assert.Empty(t, decks[0].Cards)
+	assert.Empty(t, decks[0].Piles)
+	assert.Equal(t, "deck-a", *decks[1].ParentId)
+
+	// Test case: every filter
+	empty, notEmpty, shuffled := true, false, true
+	from, to := start.Add(time.Hour), start.Add(3*time.Hour)
+	activeAt := start.Add(2 * time.Hour)
+	for _, test := range []struct {
+		filter repo.DeckFilter
+		ids    []string
+	}{
+		{repo.DeckFilter{CreatedFrom: &from}, []string{"deck-b", "deck-c"}},
+		{repo.DeckFilter{CreatedTo: &from}, []string{"deck-a"}},
+		{repo.DeckFilter{UpdatedFrom: &to}, []string{"deck-c"}},
+		{repo.DeckFilter{UpdatedTo: &from}, []string{"deck-a"}},
+		{repo.DeckFilter{Shuffled: &shuffled}, []string{"deck-a"}},
+		{repo.DeckFilter{Empty: &empty}, []string{"deck-b"}},
+		{repo.DeckFilter{Empty: &notEmpty, Owner: "alice", CreatedFrom: &from}, []string{"deck-c"}},
+		{repo.DeckFilter{ParentId: "deck-a"}, []string{"deck-b"}},
+		{repo.DeckFilter{ActiveAt: &activeAt}, []string{"deck-a", "deck-c"}},
+	} {
+		test.filter.Limit = 10
+		decks, err = r.ListDecks(test.filter)
+		assert.NoError(t, err)
+		assert.Equal(t, test.ids, deckIds(decks), "filter %+v", test.filter)
+	}
+
+	// Test case: a page continues behind its cursor
+	decks, err = r.ListDecks(repo.DeckFilter{After: &repo.DeckCursor{CreatedAt: start, Id: "deck-a"}, Limit: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"deck-b"}, deckIds(decks))
+}
+
+func testDeleteDeck(t *testing.T, r repo.DeckRepo) {
+	createDeck(t, r, repo.Deck{Id: "deck-id", Remaining: 1, Cards: []string{"AS"}})
+	assert.NoError(t, r.SaveSnapshot(repo.DeckSnapshot{DeckId: "deck-id", Name: "start", State: "{}", CreatedAt: now()}, 1))
+
+	// Test case: the deck is deleted with its history and snapshots
+	assert.NoError(t, r.DeleteDeck("deck-id"))
+	_, err := r.GetDeckById("deck-id")
+	assert.Equal(t, sql.ErrNoRows, err)
+	events, err := r.GetDeckEvents("deck-id", 0, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+	snapshots, err := r.ListSnapshots("deck-id")
+	assert.NoError(t, err)
+	assert.Empty(t, snapshots)
+
+	// Test case: a deck of the same id starts over
+	createDeck(t, r, repo.Deck{Id: "deck-id"})
+	events, err = r.GetDeckEvents("deck-id", 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	// Test case: unknown deck
+	assert.Equal(t, sql.ErrNoRows, r.DeleteDeck("unknown"))
+}
+
+func testDeleteExpiredDecks(t *testing.T, r repo.DeckRepo) {
+	current := now()
+	expired, later, idle := current.Add(-time.Minute), current.Add(time.Hour), current.Add(-48*time.Hour)
+	createDeck(t, r, repo.Deck{Id: "expired-1", ExpiresAt: &expired})
+	createDeck(t, r, repo.Deck{Id: "expired-2", ExpiresAt: &expired})
+	createDeck(t, r, repo.Deck{Id: "live", ExpiresAt: &later})
+	createDeck(t, r, repo.Deck{Id: "idle", CreatedAt: idle})
+	createDeck(t, r, repo.Deck{Id: "fresh"})
+
+	// Test case: expired decks are deleted in batches
+	deleted, err := r.DeleteExpiredDecks(current, nil, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	deleted, err = r.DeleteExpiredDecks(current, nil, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	// Test case: idle decks are deleted once they weren't updated for long
+	idleSince := current.Add(-24 * time.Hour)
+	deleted, err = r.DeleteExpiredDecks(current, &idleSince, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	decks, err := r.ListDecks(repo.DeckFilter{Limit: 10})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"live", "fresh"}, deckIds(decks))
+}
+
+func testSnapshots(t *testing.T, r repo.DeckRepo) {
+	createDeck(t, r, repo.Deck{Id: "deck-id"})
+	current := now()
+
+	// Test case: saving again replaces the snapshot of the same name
+	snapshot := repo.DeckSnapshot{DeckId: "deck-id", Name: "start", Actor: "alice", State: `{"cards": ["AS"]}`,
+		CreatedAt: current}
+	assert.NoError(t, r.SaveSnapshot(snapshot, 2))
+	snapshot.Version = 2
+	assert.NoError(t, r.SaveSnapshot(snapshot, 2))
+	later := repo.DeckSnapshot{DeckId: "deck-id", Name: "later", State: "{}", CreatedAt: current.Add(time.Second)}
+	assert.NoError(t, r.SaveSnapshot(later, 2))
+
+	fetched, err := r.GetSnapshot("deck-id", "start")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fetched.Version)
+	assert.Equal(t, "alice", fetched.Actor)
+	assert.JSONEq(t, `{"cards": ["AS"]}`, fetched.State)
+	assert.True(t, current.Equal(fetched.CreatedAt))
+
+	// Test case: a deck can't have more snapshots than the limit, replacing one still works
+	extra := repo.DeckSnapshot{DeckId: "deck-id", Name: "extra", State: "{}", CreatedAt: current}
+	assert.Equal(t, repo.ErrTooManySnapshots, r.SaveSnapshot(extra, 2))
+	assert.NoError(t, r.SaveSnapshot(later, 2))
+
+	// Test case: snapshots are listed oldest first
+	snapshots, err := r.ListSnapshots("deck-id")
+	assert.NoError(t, err)
+	assert.Len(t, snapshots, 2)
+	assert.Equal(t, []string{"start", "later"}, []string{snapshots[0].Name, snapshots[1].Name})
+
+	// Test case: DeleteSnapshot
+	assert.NoError(t, r.DeleteSnapshot("deck-id", "later"))
+	assert.Equal(t, sql.ErrNoRows, r.DeleteSnapshot("deck-id", "later"))
+	_, err = r.GetSnapshot("deck-id", "later")
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	// Test case: an unknown deck has no snapshots
+	assert.Error(t, r.SaveSnapshot(repo.DeckSnapshot{DeckId: "unknown", Name: "start", State: "{}", CreatedAt: current},
+		2))
+	snapshots, err = r.ListSnapshots("unknown")
+	assert.NoError(t, err)
+	assert.Empty(t, snapshots)
+}
+
+func testGames(t *testing.T, deckRepo repo.DeckRepo, gameRepo repo.GameRepo) {
+	current := now()
+	idle := current.Add(-48 * time.Hour)
+	createDeck(t, deckRepo, repo.Deck{Id: "shoe-id"})
+	createDeck(t, deckRepo, repo.Deck{Id: "next-shoe-id"})
+	createDeck(t, deckRepo, repo.Deck{Id: "idle-id", CreatedAt: idle})
+
+	// Test case: CreateGame and GetGameById
+	game := repo.Game{Id: "game-id", GameType: repo.BlackjackGame, DeckId: "shoe-id", State: `{"status": "player_turn"}`,
+		CreatedAt: current, UpdatedAt: current}
+	assert.NoError(t, gameRepo.CreateGame(game))
+	fetched, err := gameRepo.GetGameById("game-id")
+	assert.NoError(t, err)
+	assert.Equal(t, repo.BlackjackGame, fetched.GameType)
+	assert.Equal(t, "shoe-id", fetched.DeckId)
+	assert.JSONEq(t, game.State, fetched.State)
+	assert.Equal(t, 0, fetched.Version)
+	assert.True(t, current.Equal(fetched.CreatedAt))
+
+	// Test case: UpdateGame saves the state and the deck, a stale update is rejected
+	fetched.State = `{"status": "settled"}`
+	fetched.DeckId = "next-shoe-id"
+	assert.NoError(t, gameRepo.UpdateGame(*fetched))
+	updated, err := gameRepo.GetGameById("game-id")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"status": "settled"}`, updated.State)
+	assert.Equal(t, "next-shoe-id", updated.DeckId)
+	assert.Equal(t, 1, updated.Version)
+	assert.Equal(t, repo.ErrVersionConflict, gameRepo.UpdateGame(*fetched))
+	assert.Equal(t, repo.ErrVersionConflict, gameRepo.UpdateGame(repo.Game{Id: "unknown", DeckId: "shoe-id"}))
+
+	// Test case: GetGamesByDeckId finds the games dealing from the deck, oldest first
+	assert.NoError(t, gameRepo.CreateGame(repo.Game{Id: "next-game-id", GameType: repo.BlackjackGame,
+		DeckId: "next-shoe-id", State: "{}", CreatedAt: current.Add(time.Minute), UpdatedAt: current}))
+	games, err := gameRepo.GetGamesByDeckId("next-shoe-id")
+	assert.NoError(t, err)
+	if assert.Len(t, games, 2) {
+		assert.Equal(t, []string{"game-id", "next-game-id"}, []string{games[0].Id, games[1].Id})
+	}
+	games, err = gameRepo.GetGamesByDeckId("shoe-id")
+	assert.NoError(t, err)
+	assert.Empty(t, games)
+
+	// Test case: a game needs a deck and an id of its own
+	assert.Error(t, gameRepo.CreateGame(repo.Game{Id: "other-id", GameType: repo.WarGame, DeckId: "unknown",
+		State: "{}", CreatedAt: current, UpdatedAt: current}))
+	assert.Error(t, gameRepo.CreateGame(game))
+	_, err = gameRepo.GetGameById("unknown")
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	// Test case: a deck whose game was played since isn't idle
+	assert.NoError(t, gameRepo.CreateGame(repo.Game{Id: "idle-game-id", GameType: repo.KlondikeGame, DeckId: "idle-id",
+		State: "{}", CreatedAt: current, UpdatedAt: current}))
+	idleSince := current.Add(-24 * time.Hour)
+	deleted, err := deckRepo.DeleteExpiredDecks(current, &idleSince, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, deleted)
+
+	// Test case: deleting a deck deletes its games
+	assert.NoError(t, deckRepo.DeleteDeck("next-shoe-id"))
+	_, err = gameRepo.GetGameById("game-id")
+	assert.Equal(t, sql.ErrNoRows, err)
+	games, err = gameRepo.GetGamesByDeckId("next-shoe-id")
+	assert.NoError(t, err)
+	assert.Empty(t, games)
+}
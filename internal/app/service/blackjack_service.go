@@ -0,0 +1,256 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/deck/internal/app/blackjack"
+	customErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/repo"
+	"github.com/golang/glog"
+	"github.com/google/uuid"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultShoeDecks = 6
+	// shoePenetration is the share of the shoe dealt before the cut card comes out
+	shoePenetration = 0.75
+)
+
+type BlackjackService interface {
+	CreateGame(req model.CreateBlackjackRequest) (*model.BlackjackGameResponse, error)
+	GetGame(id string) (*model.BlackjackGameResponse, error)
+	PlayAction(id string, action string) (*model.BlackjackGameResponse, error)
+}
+
+type blackjackService struct {
+	repo  repo.GameRepo
+	decks DeckService
+}
+
+func NewBlackjackService(repo repo.GameRepo, decks DeckService) BlackjackService {
+	return &blackjackService{repo: repo, decks: decks}
+}
+
+func (s *blackjackService) CreateGame(req model.CreateBlackjackRequest) (*model.BlackjackGameResponse, error) {
+	if req.Bet <= 0 {
+		return nil, customErr.New(http.StatusBadRequest, "bet must be positive")
+	}
+	// the game is the actor of the operations on its shoe
+	id := uuid.New().String()
+	decks := s.decks.WithActor(id)
+	deckId, created, err := s.prepareShoe(decks, req)
+	if err != nil {
+		return nil, err
+	}
+
+	deal := newDealer(decks, deckId)
+	// a new shoe no game was saved for is deleted, the cards dealt from a shoe of earlier games go back to it
+	fail := func(err error) error {
+		if created {
+			s.deleteShoe(decks, deckId)
+			return err
+		}
+		return deal.giveBack(err)
+	}
+	state, err := blackjack.Deal(blackjack.Rules{DealerHitsSoft17: req.DealerHitsSoft17}, req.Bet, deal.draw)
+	if err != nil {
+		return nil, fail(toGameError(err, blackjack.ErrGameOver))
+	}
+	stateJson, err := json.Marshal(state)
+	if err != nil {
+		return nil, fail(customErr.Wrap(http.StatusInternalServerError, "couldn't encode game", err))
+	}
+	now := time.Now().UTC()
+	game := repo.Game{
+		Id:        id,
+		GameType:  repo.BlackjackGame,
+		DeckId:    deckId,
+		State:     string(stateJson),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err = s.repo.CreateGame(game); err != nil {
+		return nil, fail(customErr.Wrap(http.StatusInternalServerError, "couldn't save game", err))
+	}
+	return toBlackjackResponse(game, state)
+}
+
+func (s *blackjackService) GetGame(id string) (*model.BlackjackGameResponse, error) {
+	game, state, err := s.getGame(id)
+	if err != nil {
+		return nil, err
+	}
+	return toBlackjackResponse(*game, state)
+}
+
+// PlayAction makes the player's move, the dealer plays by itself once the player is done. The cards dealt go back to
+// the shoe when the game can't be saved, e.g. because another request changed it meanwhile.
+func (s *blackjackService) PlayAction(id string, action string) (*model.BlackjackGameResponse, error) {
+	game, state, err := s.getGame(id)
+	if err != nil {
+		return nil, err
+	}
+	deal := newDealer(s.decks.WithActor(game.Id), game.DeckId)
+	if err = state.Play(blackjack.Action(action), deal.draw); err != nil {
+		return nil, deal.giveBack(toGameError(err, blackjack.ErrGameOver))
+	}
+	stateJson, err := json.Marshal(state)
+	if err != nil {
+		return nil, deal.giveBack(customErr.Wrap(http.StatusInternalServerError, "couldn't encode game", err))
+	}
+	game.State = string(stateJson)
+	err = s.repo.UpdateGame(*game)
+	if err == repo.ErrVersionConflict {
+		return nil, deal.giveBack(
+			customErr.Wrap(http.StatusConflict, "game was modified by another request, please retry", err))
+	}
+	if err != nil {
+		return nil, deal.giveBack(customErr.Wrap(http.StatusInternalServerError, "couldn't update game", err))
+	}
+	game.Version++
+	return toBlackjackResponse(*game, state)
+}
+
+// prepareShoe returns the shoe to deal from and whether it was created for the game: a new one, or the shoe of an
+// earlier game reshuffled when its cut card came out. New shoes aren't seeded, a player knowing the seed would know
+// every card to come, the dealer's hole card too.
+func (s *blackjackService) prepareShoe(decks DeckService, req model.CreateBlackjackRequest) (string, bool, error) {
+	if len(req.DeckId) == 0 {
+		decksCount := req.DecksCount
+		if decksCount == 0 {
+			decksCount = defaultShoeDecks
+		}
+		if decksCount < 1 || decksCount > maxDecksCount {
+			return "", false, customErr.New(http.StatusBadRequest,
+				fmt.Sprintf("decks count must be between 1 - %d", maxDecksCount))
+		}
+		size := len(GenerateDefaultDeck()) * decksCount
+		shoe, err := decks.CreateDeck(model.CreateDeckRequest{
+			Shuffled:   true,
+			DecksCount: decksCount,
+			CutCard:    int(float64(size) * shoePenetration),
+		})
+		if err != nil {
+			return "", false, err
+		}
+		return shoe.DeckId, true, nil
+	}
+
+	// only the shoe of an earlier game is dealt from again, nobody saw the order of its cards
+	games, err := s.repo.GetGamesByDeckId(req.DeckId)
+	if err != nil {
+		return "", false, customErr.Wrap(http.StatusInternalServerError,
+			"couldn't get the shoe's games from the database", err)
+	}
+	if len(games) == 0 || games[0].GameType != repo.BlackjackGame {
+		return "", false, customErr.New(http.StatusBadRequest,
+			fmt.Sprintf("deck with id %s isn't the shoe of a blackjack game", req.DeckId))
+	}
+	shoe, err := decks.GetDeckById(req.DeckId)
+	if err != nil {
+		return "", false, err
+	}
+	if shoe.CutCardReached {
+		// the drawn cards go back into the shoe, none of them may still be in a game
+		if err = checkSettled(games); err != nil {
+			return "", false, err
+		}
+		if _, err = decks.ShuffleDeck(shoe.DeckId, true); err != nil {
+			return "", false, err
+		}
+	}
+	return shoe.DeckId, false, nil
+}
+
+// checkSettled fails with a conflict unless every game dealt from the shoe is settled
+func checkSettled(games []repo.Game) error {
+	for _, game := range games {
+		var state blackjack.Game
+		if err := json.Unmarshal([]byte(game.State), &state); err != nil {
+			return customErr.Wrap(http.StatusInternalServerError, "couldn't decode game", err)
+		}
+		if state.Status != blackjack.Settled {
+			return customErr.New(http.StatusConflict,
+				fmt.Sprintf("game %s isn't settled yet, the shoe can't be reshuffled before", game.Id))
+		}
+	}
+	return nil
+}
+
+// deleteShoe deletes a new shoe no game was saved for, it would be left behind otherwise
+func (s *blackjackService) deleteShoe(decks DeckService, deckId string) {
+	if err := decks.DeleteDeck(deckId); err != nil {
+		glog.Errorf("couldn't delete shoe with id %s: %s", deckId, err)
+	}
+}
+
+func (s *blackjackService) getGame(id string) (*repo.Game, *blackjack.Game, error) {
+	game, err := s.repo.GetGameById(id)
+	if err == sql.ErrNoRows {
+		return nil, nil, customErr.New(http.StatusNotFound, fmt.Sprintf("game with id %s wasn't found", id))
+	}
+	if err != nil {
+		return nil, nil, customErr.Wrap(http.StatusInternalServerError, "couldn't get game from the database", err)
+	}
+	if game.GameType != repo.BlackjackGame {
+		return nil, nil, customErr.New(http.StatusNotFound, fmt.Sprintf("blackjack game with id %s wasn't found", id))
+	}
+	var state blackjack.Game
+	if err = json.Unmarshal([]byte(game.State), &state); err != nil {
+		return nil, nil, customErr.Wrap(http.StatusInternalServerError, "couldn't decode game", err)
+	}
+	return game, &state, nil
+}
+
+func toBlackjackResponse(game repo.Game, state *blackjack.Game) (*model.BlackjackGameResponse, error) {
+	hands := make([]model.BlackjackHandResponse, len(state.Hands))
+	for i, h := range state.Hands {
+		cards, err := toCards(h.Cards)
+		if err != nil {
+			return nil, err
+		}
+		value, soft := blackjack.Value(h.Cards)
+		hands[i] = model.BlackjackHandResponse{
+			Cards:   cards,
+			Value:   value,
+			Soft:    soft,
+			Bet:     h.Bet,
+			Doubled: h.Doubled,
+			Done:    h.Done,
+			Outcome: string(h.Outcome),
+			Payout:  h.Payout,
+		}
+	}
+	// the hole card stays hidden while the player decides
+	dealerCards := state.Dealer
+	if state.Status != blackjack.Settled {
+		dealerCards = dealerCards[:1]
+	}
+	dealer, err := toCards(dealerCards)
+	if err != nil {
+		return nil, err
+	}
+	dealerValue, _ := blackjack.Value(dealerCards)
+	actions := make([]string, 0)
+	for _, a := range state.Actions() {
+		actions = append(actions, string(a))
+	}
+	return &model.BlackjackGameResponse{
+		GameId:           game.Id,
+		DeckId:           game.DeckId,
+		Status:           string(state.Status),
+		DealerHitsSoft17: state.Rules.DealerHitsSoft17,
+		Hands:            hands,
+		ActiveHand:       state.ActiveHand,
+		Dealer:           dealer,
+		DealerValue:      dealerValue,
+		Insurance:        state.Insurance,
+		InsurancePayout:  state.InsurancePayout,
+		Net:              state.Net,
+		Actions:          actions,
+	}, nil
+}
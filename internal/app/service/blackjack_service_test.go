@@ -0,0 +1,204 @@
+package service
+
+import (
+	"errors"
+	customErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/repo"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+// shoe is a deck of the given cards to deal blackjack from
+func shoe(cards ...string) repo.Deck {
+	return repo.Deck{Id: "shoe", Remaining: len(cards), Cards: cards, Size: len(cards)}
+}
+
+// putShoe stores the shoe as the one an earlier blackjack game, settled by now, dealt from
+func putShoe(mockRepo *MockRepo, gameRepo *MockGameRepo, shoe repo.Deck) {
+	mockRepo.putDeck(shoe)
+	_ = gameRepo.CreateGame(repo.Game{Id: "earlier", GameType: repo.BlackjackGame, DeckId: shoe.Id,
+		State: `{"status": "settled"}`})
+}
+
+func TestCreateBlackjackGame(t *testing.T) {
+	decks, mockRepo, gameRepo := newGameDecks()
+	putShoe(mockRepo, gameRepo, shoe("10S", "9D", "7C", "8H", "5D"))
+	blackjackService := NewBlackjackService(gameRepo, decks)
+
+	// Test case: deal from the shoe of an earlier game, the hole card is hidden
+	game, err := blackjackService.CreateGame(model.CreateBlackjackRequest{Bet: 10, DeckId: "shoe"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "player_turn", game.Status)
+	assert.Equal(t, []string{"10S", "7C"}, cardCodes(game.Hands[0].Cards))
+	assert.Equal(t, 17, game.Hands[0].Value)
+	assert.Equal(t, []string{"9D"}, cardCodes(game.Dealer))
+	assert.Equal(t, 9, game.DealerValue)
+	assert.Equal(t, []string{"hit", "stand", "double"}, game.Actions)
+	assert.Equal(t, []string{"5D"}, []string(mockRepo.deck("shoe").Cards))
+	assert.Equal(t, repo.BlackjackGame, gameRepo.game(game.GameId).GameType)
+
+	// Test case: a new shoe of 6 decks is created without a deck id
+	game, err = blackjackService.CreateGame(model.CreateBlackjackRequest{Bet: 10})
+
+	assert.NoError(t, err)
+	shoe := mockRepo.deck(game.DeckId)
+	assert.Equal(t, 6, shoe.DecksCount)
+	assert.Equal(t, 312-4, shoe.Remaining)
+	assert.Equal(t, 234, shoe.CutCard)
+	assert.False(t, shoe.Seeded)
+
+	// Test case: a new shoe is deleted when the game can't be saved
+	gameRepo.GameError = errors.New("database is down")
+	game, err = blackjackService.CreateGame(model.CreateBlackjackRequest{Bet: 10})
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, err.(*customErr.Error).Kind())
+	assert.Nil(t, game)
+	gameRepo.GameError = nil
+	decksLeft, err := mockRepo.ListDecks(repo.DeckFilter{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, decksLeft, 2)
+
+	// Test case: invalid bet
+	game, err = blackjackService.CreateGame(model.CreateBlackjackRequest{Bet: 0, DeckId: "shoe"})
+
+	assert.EqualError(t, err, "bet must be positive")
+	assert.Nil(t, game)
+
+	// Test case: the shoe runs out
+	game, err = blackjackService.CreateGame(model.CreateBlackjackRequest{Bet: 10, DeckId: "shoe"})
+
+	assert.Error(t, err)
+	assert.Nil(t, game)
+
+	// Test case: unknown shoe
+	game, err = blackjackService.CreateGame(model.CreateBlackjackRequest{Bet: 10, DeckId: "unknown"})
+
+	assert.EqualError(t, err, "deck with id unknown isn't the shoe of a blackjack game")
+	assert.Nil(t, game)
+
+	// Test case: a deck no blackjack game dealt from can't be a shoe, its cards may have been seen
+	mockRepo.putDeck(repo.Deck{Id: "seen", Remaining: 52, Cards: GenerateDefaultDeck(), Size: 52})
+	game, err = blackjackService.CreateGame(model.CreateBlackjackRequest{Bet: 10, DeckId: "seen"})
+
+	assert.EqualError(t, err, "deck with id seen isn't the shoe of a blackjack game")
+	assert.Nil(t, game)
+}
+
+func TestCreateBlackjackGameReshufflesShoe(t *testing.T) {
+	decks, mockRepo, gameRepo := newGameDecks()
+	putShoe(mockRepo, gameRepo, shoe("10S", "9D", "7C", "8H"))
+	blackjackService := NewBlackjackService(gameRepo, decks)
+	shoe := mockRepo.deck("shoe")
+	shoe.Cards = []string{"10S", "9D", "7C", "8H"}
+	shoe.Drawn = []string{"2S", "3S", "4S", "5S"}
+	shoe.Remaining = 4
+	shoe.Size = 8
+	shoe.CutCard = 4
+	putShoe(mockRepo, gameRepo, shoe)
+
+	// Test case: the cut card came out, so the drawn cards are shuffled back into the shoe before dealing
+	game, err := blackjackService.CreateGame(model.CreateBlackjackRequest{Bet: 10, DeckId: "shoe"})
+
+	assert.NoError(t, err)
+	assert.NotNil(t, game)
+	assert.Equal(t, 4, mockRepo.deck("shoe").Remaining)
+	assert.Len(t, mockRepo.deck("shoe").Drawn, 4)
+	assert.True(t, mockRepo.deck("shoe").Shuffled)
+
+	// Test case: the shoe isn't reshuffled while a game dealt from it isn't settled, its cards would be dealt twice
+	shoe = mockRepo.deck("shoe")
+	shoe.Cards, shoe.Drawn, shoe.Remaining = shoe.Cards[:2], append(shoe.Drawn, shoe.Cards[2:]...), 2
+	assert.NoError(t, mockRepo.DeckRepo.UpdateDeck(shoe, repo.DeckEvent{Type: repo.DrawEvent}))
+	other, err := blackjackService.CreateGame(model.CreateBlackjackRequest{Bet: 10, DeckId: "shoe"})
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, err.(*customErr.Error).Kind())
+	assert.Nil(t, other)
+	assert.Equal(t, 2, mockRepo.deck("shoe").Remaining)
+}
+
+func TestPlayBlackjackAction(t *testing.T) {
+	decks, mockRepo, gameRepo := newGameDecks()
+	putShoe(mockRepo, gameRepo, shoe("10S", "9D", "7C", "8H", "4D"))
+	blackjackService := NewBlackjackService(gameRepo, decks)
+	game, err := blackjackService.CreateGame(model.CreateBlackjackRequest{Bet: 10, DeckId: "shoe"})
+	assert.NoError(t, err)
+
+	// Test case: hit, the card comes from the shoe
+	game, err = blackjackService.PlayAction(game.GameId, "hit")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 21, game.Hands[0].Value)
+	// 21 stands by itself, the dealer stands on 17 and shows the hole card
+	assert.Equal(t, "settled", game.Status)
+	assert.Equal(t, []string{"9D", "8H"}, cardCodes(game.Dealer))
+	assert.Equal(t, "win", game.Hands[0].Outcome)
+	assert.Equal(t, 10, game.Net)
+	assert.Empty(t, game.Actions)
+	assert.Equal(t, 1, gameRepo.game(game.GameId).Version)
+
+	// Test case: the game is the actor of its draws from the shoe
+	history, err := decks.GetDeckHistory("shoe", 0, 0)
+	assert.NoError(t, err)
+	draws := 0
+	for _, e := range history.Events {
+		if e.Type == "draw" {
+			draws++
+			assert.Equal(t, game.GameId, e.Actor)
+		}
+	}
+	assert.Equal(t, 2, draws)
+
+	// Test case: the state survives in the repository
+	fetched, err := blackjackService.GetGame(game.GameId)
+
+	assert.NoError(t, err)
+	assert.Equal(t, game, fetched)
+
+	// Test case: the game is over
+	game, err = blackjackService.PlayAction(game.GameId, "stand")
+
+	assert.EqualError(t, err, "the game is already settled")
+	assert.Nil(t, game)
+
+	// Test case: unknown game
+	game, err = blackjackService.GetGame("unknown")
+
+	assert.EqualError(t, err, "game with id unknown wasn't found")
+	assert.Nil(t, game)
+}
+
+func TestPlayBlackjackActionInvalid(t *testing.T) {
+	decks, mockRepo, gameRepo := newGameDecks()
+	putShoe(mockRepo, gameRepo, shoe("10S", "9D", "7C", "8H", "4D"))
+	blackjackService := NewBlackjackService(gameRepo, decks)
+	game, err := blackjackService.CreateGame(model.CreateBlackjackRequest{Bet: 10, DeckId: "shoe"})
+	assert.NoError(t, err)
+
+	// Test case: an action that isn't allowed
+	res, err := blackjackService.PlayAction(game.GameId, "split")
+
+	assert.EqualError(t, err, "split isn't allowed now")
+	assert.Nil(t, res)
+
+	// Test case: another request changed the game meanwhile, the card dealt goes back to the shoe
+	conflictRepo := &conflictingGameRepo{MockGameRepo: gameRepo}
+	res, err = NewBlackjackService(conflictRepo, decks).PlayAction(game.GameId, "hit")
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, err.(*customErr.Error).Kind())
+	assert.Nil(t, res)
+	assert.Equal(t, 0, gameRepo.game(game.GameId).Version)
+	assert.Equal(t, []string{"4D"}, []string(mockRepo.deck("shoe").Cards))
+	assert.Len(t, mockRepo.deck("shoe").Drawn, 4)
+
+	// Test case: the same card is dealt once the game is saved
+	res, err = blackjackService.PlayAction(game.GameId, "hit")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10S", "7C", "4D"}, cardCodes(res.Hands[0].Cards))
+}
@@ -2,182 +2,900 @@ package service
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	customErr "github.com/deck/internal/app/error"
 	"github.com/deck/internal/app/model"
 	"github.com/deck/internal/app/repo"
 	"github.com/google/uuid"
-	"math/rand"
+	"github.com/lib/pq"
 	"net/http"
 	"regexp"
 	"strings"
 	"time"
 )
 
+// validCardPattern matches a value followed by a suit, or a joker
+var validCardPattern = regexp.MustCompile(fmt.Sprintf("^((%s)(%s)|%s)$",
+	joinCodes(repo.SequentialValues), joinCodes(repo.SequentialSuits), joinCodes(repo.SequentialJokers)))
+
+const (
+	// maxDecksCount is the largest shoe a deck can be created with
+	maxDecksCount = 8
+	maxSeedLength = 64
+	// maxOwnerLength fits the owner column
+	maxOwnerLength = 100
+	maxDeckTTL     = 30 * 24 * time.Hour
+)
+
 type DeckService interface {
 	CreateDeck(req model.CreateDeckRequest) (*model.CreateDeckResponse, error)
 	GetDeckById(id string) (*model.OpenDeckResponse, error)
+	DeleteDeck(id string) error
+	WithActor(actor string) DeckService
+	GetDeckHistory(id string, after, limit int) (*model.DeckHistoryResponse, error)
+	GetDeckAt(id string, number int) (*model.DeckStateResponse, error)
+	WithUndoDepth(depth int) DeckService
+	WithoutGameDecks(games repo.GameRepo) DeckService
+	WithAceHigh(aceHigh bool) DeckService
+	Undo(id string, req model.UndoRequest) (*model.DeckStateResponse, error)
+	CloneDeck(id string, req model.CloneDeckRequest) (*model.CreateDeckResponse, error)
+	SaveSnapshot(id, name string) (*model.SnapshotResponse, error)
+	ListSnapshots(id string) (*model.ListSnapshotsResponse, error)
+	RestoreSnapshot(id, name string) (*model.DeckStateResponse, error)
+	DeleteSnapshot(id, name string) error
 	DrawCards(id string, count int) ([]model.Card, error)
+	DrawCardsFrom(id string, count int, from model.Position) ([]model.Card, error)
+	DrawSpecificCards(id string, codes []string) ([]model.Card, error)
+	DiscardCards(id string, codes []string) (*model.OpenDeckResponse, error)
+	ReturnCards(id string, req model.ReturnCardsRequest) (*model.OpenDeckResponse, error)
+	ShuffleDeck(id string, returnCards bool) (*model.OpenDeckResponse, error)
+	PeekCards(id string, count int) (*model.DeckOperationResponse, error)
+	CutDeck(id string, position int) (*model.DeckOperationResponse, error)
+	InsertCard(id, code string, index int) (*model.DeckOperationResponse, error)
+	GetDeckSeed(id string) (*model.DeckSeedResponse, error)
+	VerifyDeck(id string) (*model.VerifyDeckResponse, error)
+	CreatePile(id, name string) (*model.PileResponse, error)
+	GetPile(id, name string) (*model.PileResponse, error)
+	MoveCards(id, name string, req model.MoveCardsRequest) (*model.PileResponse, error)
+	ShufflePile(id, name string) (*model.PileResponse, error)
+	DrawFromPile(id, name string, count int) ([]model.Card, error)
+	ListDecks(req model.ListDecksRequest) (*model.ListDecksResponse, error)
+	SortCards(codes []string, req model.SortRequest) ([]model.Card, error)
+	SortPile(id, name string, req model.SortRequest) (*model.PileResponse, error)
+	EvaluateHand(codes []string) (*model.HandResponse, error)
+	EvaluatePile(id, name string) (*model.HandResponse, error)
+	Showdown(req model.ShowdownRequest) (*model.ShowdownResponse, error)
+	DeckShowdown(id string, players []string, board string) (*model.ShowdownResponse, error)
+	CalculateEquity(req model.EquityRequest) (*model.EquityResponse, error)
 }
 
 type deckService struct {
-	repo repo.DeckRepo
+	repo      repo.DeckRepo
+	random    RandomSource
+	actor     string
+	undoDepth int
+	games     repo.GameRepo
+	aceHigh   bool
+}
+
+func NewDeckService(repo repo.DeckRepo, random RandomSource) DeckService {
+	return &deckService{repo: repo, random: random, undoDepth: DefaultUndoDepth}
 }
 
-func NewDeckService(repo repo.DeckRepo) DeckService {
-	return &deckService{repo: repo}
+// WithActor returns the service recording the given actor, e.g. a player or a game, on the events of the decks it
+// changes
+func (s *deckService) WithActor(actor string) DeckService {
+	withActor := *s
+	withActor.actor = actor
+	return &withActor
+}
+
+// WithUndoDepth returns the service undoing at most depth operations of a deck in a row
+func (s *deckService) WithUndoDepth(depth int) DeckService {
+	withDepth := *s
+	withDepth.undoDepth = depth
+	return &withDepth
+}
+
+// WithAceHigh returns the service ranking the aces of the cards it shows above the kings, or below the twos
+func (s *deckService) WithAceHigh(aceHigh bool) DeckService {
+	withAceHigh := *s
+	withAceHigh.aceHigh = aceHigh
+	return &withAceHigh
+}
+
+// WithoutGameDecks returns the service refusing the decks the games deal from, so nobody can read the cards a game
+// hides or change them behind the game's back
+func (s *deckService) WithoutGameDecks(games repo.GameRepo) DeckService {
+	withoutGames := *s
+	withoutGames.games = games
+	return &withoutGames
 }
 
 func (s *deckService) CreateDeck(req model.CreateDeckRequest) (*model.CreateDeckResponse, error) {
+	deckType := repo.DeckType(strings.ToLower(req.DeckType))
+	if len(deckType) == 0 {
+		deckType = repo.Standard
+	}
+	if _, found := repo.Compositions[deckType]; !found {
+		return nil, customErr.New(http.StatusBadRequest, fmt.Sprintf("unknown deck type %s", deckType))
+	}
 	var cards []string
 	if len(req.Cards) == 0 {
-		cards = GenerateDefaultDeck()
+		cards = GenerateDeck(deckType, req.Jokers)
 	} else {
-		cards = strings.Split(req.Cards, ",")
-		err := validateCards(cards)
+		cards = strings.Split(strings.ToUpper(req.Cards), ",")
+		err := validateCardsOf(cards, deckType, req.Jokers)
 		if err != nil {
 			return nil, err
 		}
 	}
+	decksCount := req.DecksCount
+	if decksCount == 0 {
+		decksCount = 1
+	}
+	if decksCount < 1 || decksCount > maxDecksCount {
+		return nil, customErr.New(http.StatusBadRequest, fmt.Sprintf("decks count must be between 1 - %d", maxDecksCount))
+	}
+	cards = buildShoe(cards, decksCount)
+	if req.CutCard < 0 || req.CutCard > len(cards) {
+		return nil, customErr.New(http.StatusBadRequest, "cut card must be within the shoe")
+	}
+	if len(req.ClientSeed) > maxSeedLength || len(req.Seed) > maxSeedLength {
+		return nil, customErr.New(http.StatusBadRequest, fmt.Sprintf("seeds must be at most %d characters", maxSeedLength))
+	}
+	if err := validateOwnerAndTTL(req.Owner, req.TTL); err != nil {
+		return nil, err
+	}
+	if len(req.Seed) > 0 && !req.Shuffled {
+		return nil, customErr.New(http.StatusBadRequest, "seed can only be given for shuffled decks")
+	}
+	initialCards := append([]string{}, cards...)
+	serverSeed := req.Seed
 	if req.Shuffled {
-		ShuffleCards(cards)
+		if len(serverSeed) == 0 {
+			var err error
+			serverSeed, err = newServerSeed(s.random)
+			if err != nil {
+				return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't generate server seed", err)
+			}
+		}
+		fairShuffle(cards, serverSeed, req.ClientSeed)
 	}
 	now := time.Now().UTC()
+	var shuffledAt *time.Time
+	if req.Shuffled {
+		shuffledAt = &now
+	}
 	deck := repo.Deck{
-		Id:        uuid.New().String(),
-		Shuffled:  req.Shuffled,
-		Remaining: len(cards),
-		Cards:     cards,
-		CreatedAt: now,
-		UpdatedAt: now,
+		Id:           uuid.New().String(),
+		Shuffled:     req.Shuffled,
+		ShuffledAt:   shuffledAt,
+		Remaining:    len(cards),
+		Cards:        cards,
+		DeckType:     deckType,
+		Jokers:       req.Jokers,
+		ServerSeed:   serverSeed,
+		Seeded:       len(req.Seed) > 0,
+		ClientSeed:   req.ClientSeed,
+		InitialCards: initialCards,
+		DecksCount:   decksCount,
+		CutCard:      req.CutCard,
+		Size:         len(cards),
+		Owner:        req.Owner,
+		ExpiresAt:    expiryOf(req.TTL, now),
+		CreatedAt:    now,
+		UpdatedAt:    now,
 	}
-	err := s.repo.CreateDeck(deck)
+	event, err := s.event(repo.CreateEvent, map[string]interface{}{
+		"shuffled": deck.Shuffled, "deck_type": deck.DeckType, "jokers": deck.Jokers, "decks_count": deck.DecksCount,
+		"size": deck.Size,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.repo.CreateDeck(deck, event)
 	if err != nil {
 		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't save deck", err)
 	}
+	return toCreateDeckResponse(deck), nil
+}
+
+func validateOwnerAndTTL(owner string, ttl time.Duration) error {
+	if len(owner) > maxOwnerLength {
+		return customErr.New(http.StatusBadRequest, fmt.Sprintf("owner must be at most %d characters", maxOwnerLength))
+	}
+	if ttl < 0 || ttl > maxDeckTTL {
+		return customErr.New(http.StatusBadRequest, fmt.Sprintf("ttl must be positive and at most %s", maxDeckTTL))
+	}
+	return nil
+}
+
+// expiryOf is when a deck created now with the ttl expires, nil if it never does
+func expiryOf(ttl time.Duration, now time.Time) *time.Time {
+	if ttl == 0 {
+		return nil
+	}
+	expiry := now.Add(ttl)
+	return &expiry
+}
+
+func toCreateDeckResponse(deck repo.Deck) *model.CreateDeckResponse {
 	return &model.CreateDeckResponse{
+		DeckId:         deck.Id,
+		Shuffled:       deck.Shuffled,
+		Remaining:      deck.Remaining,
+		DeckType:       string(deck.DeckType),
+		Jokers:         deck.Jokers,
+		DecksCount:     deck.DecksCount,
+		ServerSeedHash: hashSeed(deck.ServerSeed),
+		ClientSeed:     deck.ClientSeed,
+		Owner:          deck.Owner,
+		ParentId:       deck.ParentId,
+		ExpiresAt:      deck.ExpiresAt,
+	}
+}
+
+func (s *deckService) GetDeckById(id string) (*model.OpenDeckResponse, error) {
+	deck, err := s.getDeck(id)
+	if err != nil {
+		return nil, err
+	}
+	return toOpenDeckResponse(*deck, s.aceHigh)
+}
+
+// DeleteDeck deletes the deck with its piles and the games played with it
+func (s *deckService) DeleteDeck(id string) error {
+	if err := s.checkNotGameDeck(id); err != nil {
+		return err
+	}
+	err := s.repo.DeleteDeck(id)
+	if err == sql.ErrNoRows {
+		return customErr.New(http.StatusNotFound, fmt.Sprintf("deck with id %s wasn't found", id))
+	}
+	if err != nil {
+		return customErr.Wrap(http.StatusInternalServerError, "couldn't delete deck", err)
+	}
+	return nil
+}
+
+func (s *deckService) DrawCards(id string, count int) ([]model.Card, error) {
+	return s.DrawCardsFrom(id, count, model.Top)
+}
+
+// DrawCardsFrom draws count cards from the top, the bottom or random places of the deck, without reshuffling it
+func (s *deckService) DrawCardsFrom(id string, count int, from model.Position) ([]model.Card, error) {
+	if from != model.Top && from != model.Bottom && from != model.Random {
+		return nil, customErr.New(http.StatusBadRequest, "from must be top, bottom or random")
+	}
+	deck, err := s.getDeck(id)
+	if err != nil {
+		return nil, err
+	}
+	maxCount := 52 * decksCount(*deck)
+	if deck.Size > maxCount {
+		maxCount = deck.Size
+	}
+	if count <= 0 || count > maxCount {
+		return nil, customErr.New(http.StatusBadRequest, fmt.Sprintf("count must be between 1 - %d", maxCount))
+	}
+	if count > deck.Remaining {
+		return nil, customErr.New(http.StatusBadRequest, "count must be less or equal than deck's remaining")
+	}
+	drawn, rest := takeCards(s.sourceFor(*deck), deck.Cards, count, from)
+	cards, err := toRankedCards(drawn, s.aceHigh)
+	if err != nil {
+		return nil, err
+	}
+	updatedDeck := updateDeck(*deck, drawn, rest)
+	err = s.saveDeck(updatedDeck, repo.DrawEvent, map[string]interface{}{"count": count, "from": from, "cards": drawn})
+	if err != nil {
+		return nil, err
+	}
+	return cards, nil
+}
+
+// DrawSpecificCards pulls the given cards out of the deck, wherever they are
+func (s *deckService) DrawSpecificCards(id string, codes []string) ([]model.Card, error) {
+	if len(codes) == 0 {
+		return nil, customErr.New(http.StatusBadRequest, "cards to draw must be given")
+	}
+	if err := validateCodes(codes); err != nil {
+		return nil, err
+	}
+	deck, err := s.getDeck(id)
+	if err != nil {
+		return nil, err
+	}
+	rest := deck.Cards
+	for _, c := range codes {
+		if !removeCard(&rest, c) {
+			return nil, customErr.New(http.StatusNotFound, fmt.Sprintf("card %s isn't in the deck", c))
+		}
+	}
+	cards, err := toRankedCards(codes, s.aceHigh)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.saveDeck(updateDeck(*deck, codes, rest), repo.DrawEvent, map[string]interface{}{"cards": codes}); err != nil {
+		return nil, err
+	}
+	return cards, nil
+}
+
+func (s *deckService) DiscardCards(id string, codes []string) (*model.OpenDeckResponse, error) {
+	if len(codes) == 0 {
+		return nil, customErr.New(http.StatusBadRequest, "cards to discard must be given")
+	}
+	if err := validateCodes(codes); err != nil {
+		return nil, err
+	}
+	deck, err := s.getDeck(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range codes {
+		if !removeCard(&deck.Drawn, c) {
+			return nil, customErr.New(http.StatusNotFound, fmt.Sprintf("card %s wasn't drawn from the deck", c))
+		}
+		deck.Discarded = append(deck.Discarded, c)
+	}
+	if err = s.saveDeck(*deck, repo.DiscardEvent, map[string]interface{}{"cards": codes}); err != nil {
+		return nil, err
+	}
+	return toOpenDeckResponse(*deck, s.aceHigh)
+}
+
+func (s *deckService) ReturnCards(id string, req model.ReturnCardsRequest) (*model.OpenDeckResponse, error) {
+	if err := validateCodes(req.Cards); err != nil {
+		return nil, err
+	}
+	deck, err := s.getDeck(id)
+	if err != nil {
+		return nil, err
+	}
+	cards := req.Cards
+	if len(cards) == 0 {
+		cards = deck.Discarded
+		deck.Discarded = nil
+	} else {
+		for _, c := range cards {
+			if !removeCard(&deck.Discarded, c) && !removeCard(&deck.Drawn, c) {
+				return nil, customErr.New(http.StatusNotFound, fmt.Sprintf("card %s is neither drawn nor discarded", c))
+			}
+		}
+	}
+	if len(cards) == 0 {
+		return nil, customErr.New(http.StatusBadRequest, "there are no cards to return")
+	}
+	random := s.sourceFor(*deck)
+	deck.Cards, err = insertCards(random, deck.Cards, cards, req.Position)
+	if err != nil {
+		return nil, err
+	}
+	if req.Shuffle {
+		shuffleDeck(random, deck)
+	}
+	deck.Remaining = len(deck.Cards)
+	err = s.saveDeck(*deck, repo.ReturnEvent, map[string]interface{}{
+		"cards": cards, "position": req.Position, "shuffle": req.Shuffle,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toOpenDeckResponse(*deck, s.aceHigh)
+}
+
+// ShuffleDeck reshuffles the remaining cards of the deck. With returnCards the drawn and discarded cards are put back
+// first, cards in piles stay where they are.
+func (s *deckService) ShuffleDeck(id string, returnCards bool) (*model.OpenDeckResponse, error) {
+	deck, err := s.getDeck(id)
+	if err != nil {
+		return nil, err
+	}
+	if returnCards {
+		deck.Cards = append(append(append([]string{}, deck.Cards...), deck.Drawn...), deck.Discarded...)
+		deck.Drawn = nil
+		deck.Discarded = nil
+		deck.Remaining = len(deck.Cards)
+	}
+	shuffleDeck(s.sourceFor(*deck), deck)
+	if err = s.saveDeck(*deck, repo.ShuffleEvent, map[string]interface{}{"return_cards": returnCards}); err != nil {
+		return nil, err
+	}
+	return toOpenDeckResponse(*deck, s.aceHigh)
+}
+
+// PeekCards shows the top cards of the deck without drawing them
+func (s *deckService) PeekCards(id string, count int) (*model.DeckOperationResponse, error) {
+	if count <= 0 {
+		return nil, customErr.New(http.StatusBadRequest, "count must be positive")
+	}
+	deck, err := s.getDeck(id)
+	if err != nil {
+		return nil, err
+	}
+	if count > deck.Remaining {
+		return nil, customErr.New(http.StatusBadRequest, "count must be less or equal than deck's remaining")
+	}
+	cards, err := toRankedCards(deck.Cards[:count], s.aceHigh)
+	if err != nil {
+		return nil, err
+	}
+	return &model.DeckOperationResponse{
 		DeckId:    deck.Id,
-		Shuffled:  deck.Shuffled,
 		Remaining: deck.Remaining,
+		Cards:     cards,
 	}, nil
 }
 
-func (s *deckService) GetDeckById(id string) (*model.OpenDeckResponse, error) {
-	deck, err := s.repo.GetDeckById(id)
-	if err == sql.ErrNoRows {
-		return nil, customErr.New(http.StatusNotFound, fmt.Sprintf("deck with id %s wasn't found", id))
+// CutDeck moves the cards above position to the bottom of the deck. Position 0 cuts at a random place, always leaving
+// at least one card on both sides.
+func (s *deckService) CutDeck(id string, position int) (*model.DeckOperationResponse, error) {
+	if position < 0 {
+		return nil, customErr.New(http.StatusBadRequest, "position can't be negative")
 	}
+	deck, err := s.getDeck(id)
 	if err != nil {
-		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't get deck from the database", err)
+		return nil, err
 	}
-	cards := make([]model.Card, len(deck.Cards))
-	for i, c := range deck.Cards {
-		card, err := getValueAndSuit(c)
-		if err != nil {
-			return nil, err
+	if deck.Remaining < 2 {
+		return nil, customErr.New(http.StatusBadRequest, "deck needs at least 2 cards to be cut")
+	}
+	if position == 0 {
+		position = 1 + s.sourceFor(*deck).Intn(deck.Remaining-1)
+	}
+	if position >= deck.Remaining {
+		return nil, customErr.New(http.StatusBadRequest, fmt.Sprintf("position must be between 1 - %d", deck.Remaining-1))
+	}
+	deck.Cards = append(append([]string{}, deck.Cards[position:]...), deck.Cards[:position]...)
+	if err = s.saveDeck(*deck, repo.CutEvent, map[string]interface{}{"position": position}); err != nil {
+		return nil, err
+	}
+	return &model.DeckOperationResponse{
+		DeckId:    deck.Id,
+		Remaining: deck.Remaining,
+		Position:  position,
+		Cards:     []model.Card{},
+	}, nil
+}
+
+// InsertCard puts a card at index of the deck, 0 being the top. A drawn or discarded copy of the card is taken back,
+// otherwise the card is only added while the deck has fewer copies of it than its composition allows.
+func (s *deckService) InsertCard(id, code string, index int) (*model.DeckOperationResponse, error) {
+	code = strings.ToUpper(code)
+	if err := validateCodes([]string{code}); err != nil {
+		return nil, err
+	}
+	deck, err := s.getDeck(id)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index > deck.Remaining {
+		return nil, customErr.New(http.StatusBadRequest, fmt.Sprintf("index must be between 0 - %d", deck.Remaining))
+	}
+	if !removeCard(&deck.Discarded, code) && !removeCard(&deck.Drawn, code) {
+		allowed := countCards(GenerateDeck(repo.DeckType(deckType(*deck)), deck.Jokers))[code] * decksCount(*deck)
+		if allowed == 0 {
+			return nil, customErr.New(http.StatusBadRequest, fmt.Sprintf("card %s isn't part of a %s deck", code, deckType(*deck)))
 		}
-		cards[i] = *card
+		if countCards(allCards(*deck))[code] >= allowed {
+			return nil, customErr.New(http.StatusConflict, fmt.Sprintf("card %s is already in the deck", code))
+		}
+		deck.Size++
 	}
-	return &model.OpenDeckResponse{
+	deck.Cards = append(append(append([]string{}, deck.Cards[:index]...), code), deck.Cards[index:]...)
+	deck.Remaining = len(deck.Cards)
+	if err = s.saveDeck(*deck, repo.InsertEvent, map[string]interface{}{"card": code, "index": index}); err != nil {
+		return nil, err
+	}
+	cards, err := toRankedCards([]string{code}, s.aceHigh)
+	if err != nil {
+		return nil, err
+	}
+	return &model.DeckOperationResponse{
 		DeckId:    deck.Id,
-		Shuffled:  deck.Shuffled,
 		Remaining: deck.Remaining,
+		Position:  index,
 		Cards:     cards,
 	}, nil
 }
 
-func (s *deckService) DrawCards(id string, count int) ([]model.Card, error) {
-	if count <= 0 || count > 52 {
-		return nil, customErr.New(http.StatusBadRequest, "count must be between 1 - 52")
+// GetDeckSeed reports the seed of a deck created with one. Server generated seeds stay secret until the deck is finished.
+func (s *deckService) GetDeckSeed(id string) (*model.DeckSeedResponse, error) {
+	deck, err := s.getDeck(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(deck.ServerSeed) == 0 {
+		return nil, customErr.New(http.StatusNotFound, "deck wasn't shuffled with a seed")
+	}
+	if !deck.Seeded && deck.Remaining > 0 {
+		return nil, customErr.New(http.StatusConflict, "server seed is revealed once the deck is finished")
 	}
-	deck, err := s.GetDeckById(id)
+	return &model.DeckSeedResponse{
+		DeckId:     deck.Id,
+		Seed:       deck.ServerSeed,
+		ClientSeed: deck.ClientSeed,
+		Seeded:     deck.Seeded,
+	}, nil
+}
+
+// VerifyDeck reveals the seeds of a finished deck and replays its shuffle, so the dealt order can be checked
+func (s *deckService) VerifyDeck(id string) (*model.VerifyDeckResponse, error) {
+	deck, err := s.getDeck(id)
 	if err != nil {
 		return nil, err
 	}
-	if count > deck.Remaining {
-		return nil, customErr.New(http.StatusBadRequest, "count must be less or equal than deck's remaining")
+	if len(deck.ServerSeed) == 0 {
+		return nil, customErr.New(http.StatusBadRequest, "deck wasn't shuffled with a server seed")
 	}
-	cards := drawFirstCards(*deck, count)
-	updatedDeck := updateDeck(*deck, count)
-	err = s.repo.UpdateDeck(updatedDeck)
+	if deck.Remaining > 0 {
+		return nil, customErr.New(http.StatusConflict, "server seed is revealed once the deck is finished")
+	}
+	order := append([]string{}, deck.InitialCards...)
+	fairShuffle(order, deck.ServerSeed, deck.ClientSeed)
+	initialCards, err := toRankedCards(deck.InitialCards, s.aceHigh)
 	if err != nil {
-		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't update deck", err)
+		return nil, err
 	}
-	return cards, nil
+	cards, err := toRankedCards(order, s.aceHigh)
+	if err != nil {
+		return nil, err
+	}
+	return &model.VerifyDeckResponse{
+		DeckId:         deck.Id,
+		ServerSeed:     deck.ServerSeed,
+		ServerSeedHash: hashSeed(deck.ServerSeed),
+		ClientSeed:     deck.ClientSeed,
+		InitialCards:   initialCards,
+		Cards:          cards,
+	}, nil
+}
+
+// sourceFor returns the randomness for operations on the deck. Seeded decks get a source derived from their seed and
+// version, so replaying the same operations on a deck created with the same seed gives the same result.
+func (s *deckService) sourceFor(deck repo.Deck) RandomSource {
+	if deck.Seeded {
+		return newFairStream(deck.ServerSeed, fmt.Sprintf("%s:%d", deck.ClientSeed, deck.Version))
+	}
+	return s.random
+}
+
+func (s *deckService) getDeck(id string) (*repo.Deck, error) {
+	deck, err := s.repo.GetDeckById(id)
+	if err == sql.ErrNoRows {
+		return nil, customErr.New(http.StatusNotFound, fmt.Sprintf("deck with id %s wasn't found", id))
+	}
+	if err != nil {
+		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't get deck from the database", err)
+	}
+	// an expired deck is gone even if the reaper didn't delete it yet
+	if deck.ExpiresAt != nil && !deck.ExpiresAt.After(time.Now().UTC()) {
+		return nil, customErr.New(http.StatusNotFound, fmt.Sprintf("deck with id %s wasn't found", id))
+	}
+	if err = s.checkNotGameDeck(id); err != nil {
+		return nil, err
+	}
+	return deck, nil
+}
+
+// checkNotGameDeck refuses the deck when a game deals from it and the service doesn't hand out the games' decks
+func (s *deckService) checkNotGameDeck(id string) error {
+	if s.games == nil {
+		return nil
+	}
+	games, err := s.games.GetGamesByDeckId(id)
+	if err != nil {
+		return customErr.Wrap(http.StatusInternalServerError, "couldn't get the deck's games from the database", err)
+	}
+	if len(games) > 0 {
+		return customErr.New(http.StatusForbidden, fmt.Sprintf("deck with id %s belongs to game %s", id, games[0].Id))
+	}
+	return nil
+}
+
+// saveDeck persists a deck read by getDeck and records the operation on its history. It fails with a conflict when
+// another request changed the deck meanwhile, so no card can be handed out twice.
+func (s *deckService) saveDeck(deck repo.Deck, eventType repo.EventType, payload map[string]interface{}) error {
+	event, err := s.event(eventType, payload)
+	if err != nil {
+		return err
+	}
+	err = s.repo.UpdateDeck(deck, event)
+	if err == repo.ErrVersionConflict {
+		return customErr.Wrap(http.StatusConflict, "deck was modified by another request, please retry", err)
+	}
+	if err != nil {
+		return customErr.Wrap(http.StatusInternalServerError, "couldn't update deck", err)
+	}
+	return nil
+}
+
+// event describes an operation of the service's actor
+func (s *deckService) event(eventType repo.EventType, payload map[string]interface{}) (repo.DeckEvent, error) {
+	payloadJson, err := json.Marshal(payload)
+	if err != nil {
+		return repo.DeckEvent{}, customErr.Wrap(http.StatusInternalServerError, "couldn't encode event", err)
+	}
+	return repo.DeckEvent{Type: eventType, Actor: s.actor, Payload: string(payloadJson)}, nil
 }
 
 func GenerateDefaultDeck() []string {
+	return GenerateDeck(repo.Standard, false)
+}
+
+// GenerateDeck builds one sequential deck of the given type, with the jokers at the end if asked for
+func GenerateDeck(deckType repo.DeckType, jokers bool) []string {
+	composition := repo.Compositions[deckType]
 	var deck []string
-	for _, s := range repo.SequentialSuits {
-		for _, v := range repo.SequentialValues {
-			deck = append(deck, fmt.Sprintf("%s%s", v, s))
+	for i := 0; i < composition.Copies; i++ {
+		for _, s := range repo.SequentialSuits {
+			for _, v := range composition.Values {
+				deck = append(deck, fmt.Sprintf("%s%s", v, s))
+			}
+		}
+	}
+	if jokers {
+		for _, j := range repo.SequentialJokers {
+			deck = append(deck, string(j))
 		}
 	}
 	return deck
 }
 
-func ShuffleCards(cards []string) {
-	r := rand.New(rand.NewSource(time.Now().Unix()))
-	r.Seed(time.Now().UnixNano())
-	r.Shuffle(len(cards), func(i, j int) {
-		cards[i], cards[j] = cards[j], cards[i]
-	})
+// buildShoe repeats the cards of one deck decksCount times
+func buildShoe(cards []string, decksCount int) []string {
+	shoe := make([]string, 0, len(cards)*decksCount)
+	for i := 0; i < decksCount; i++ {
+		shoe = append(shoe, cards...)
+	}
+	return shoe
+}
+
+// decksCount treats decks stored before shoes existed as single decks
+func decksCount(deck repo.Deck) int {
+	if deck.DecksCount < 1 {
+		return 1
+	}
+	return deck.DecksCount
+}
+
+// deckType treats decks stored before deck types existed as standard decks
+func deckType(deck repo.Deck) string {
+	if len(deck.DeckType) == 0 {
+		return string(repo.Standard)
+	}
+	return string(deck.DeckType)
+}
+
+// shuffleDeck shuffles the remaining cards and records when it happened
+func shuffleDeck(random RandomSource, deck *repo.Deck) {
+	shuffleWith(random, deck.Cards)
+	now := time.Now().UTC()
+	deck.Shuffled = true
+	deck.ShuffledAt = &now
+}
+
+// updateDeck moves the drawn cards to the deck's drawn cards, leaving the rest in the deck
+func updateDeck(deck repo.Deck, drawn []string, rest []string) repo.Deck {
+	cardCodes := make([]string, len(rest))
+	copy(cardCodes, rest)
+	deck.Drawn = append(append([]string{}, deck.Drawn...), drawn...)
+	deck.Cards = cardCodes
+	deck.Remaining = len(cardCodes)
+	return deck
 }
 
-func updateDeck(deck model.OpenDeckResponse, count int) repo.Deck {
-	cardCodes := make([]string, len(deck.Cards)-count)
-	for i, c := range deck.Cards[count:] {
-		cardCodes[i] = c.Code
+// takeCards splits count cards off the top, the bottom or random places of the cards. Cards drawn from the bottom
+// come bottom card first, the rest keeps its order.
+func takeCards(random RandomSource, cards []string, count int, from model.Position) ([]string, []string) {
+	switch from {
+	case model.Bottom:
+		drawn := make([]string, count)
+		for i := range drawn {
+			drawn[i] = cards[len(cards)-1-i]
+		}
+		return drawn, cards[:len(cards)-count]
+	case model.Random:
+		rest := append([]string{}, cards...)
+		drawn := make([]string, count)
+		for i := range drawn {
+			j := random.Intn(len(rest))
+			drawn[i] = rest[j]
+			rest = append(rest[:j], rest[j+1:]...)
+		}
+		return drawn, rest
+	default:
+		return cards[:count], cards[count:]
+	}
+}
+
+// allCards returns every card of the deck wherever it is: in the deck, drawn, discarded or in a pile
+func allCards(deck repo.Deck) []string {
+	cards := append(append(append([]string{}, deck.Cards...), deck.Drawn...), deck.Discarded...)
+	for _, p := range deck.Piles {
+		cards = append(cards, p.Cards...)
 	}
-	updatedDeck := repo.Deck{
-		Id:        deck.DeckId,
-		Remaining: deck.Remaining - count,
-		Shuffled:  deck.Shuffled,
-		Cards:     cardCodes,
+	return cards
+}
+
+// removeCard takes one copy of the card out of the given cards and reports whether it was there
+func removeCard(cards *pq.StringArray, code string) bool {
+	for i, c := range *cards {
+		if c == code {
+			*cards = append((*cards)[:i:i], (*cards)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// insertCards puts the cards on the top, at the bottom or at random places of the deck, keeping their given order
+// for top and bottom
+func insertCards(random RandomSource, deck []string, cards []string, position model.Position) ([]string, error) {
+	switch position {
+	case model.Top, "":
+		return append(append([]string{}, cards...), deck...), nil
+	case model.Bottom:
+		return append(append([]string{}, deck...), cards...), nil
+	case model.Random:
+		result := append([]string{}, deck...)
+		for _, c := range cards {
+			i := random.Intn(len(result) + 1)
+			result = append(result[:i], append([]string{c}, result[i:]...)...)
+		}
+		return result, nil
+	default:
+		return nil, customErr.New(http.StatusBadRequest, "position must be top, bottom or random")
 	}
-	return updatedDeck
 }
 
 func validateCards(cards []string) error {
-	checkDuplicates := make(map[string]bool, len(cards))
+	return validateCardsOf(cards, repo.Standard, false)
+}
+
+// validateCardsOf checks that the cards are valid and can all be part of one deck of the given type
+func validateCardsOf(cards []string, deckType repo.DeckType, jokers bool) error {
+	available := countCards(GenerateDeck(deckType, jokers))
 	for _, c := range cards {
 		if !isValidCardCode(c) {
 			return customErr.New(http.StatusBadRequest, "contains invalid card code")
 		}
-		if _, found := checkDuplicates[c]; found {
+		copies, found := available[strings.ToUpper(c)]
+		if !found {
+			return customErr.New(http.StatusBadRequest, fmt.Sprintf("contains card %s which isn't part of a %s deck", c, deckType))
+		}
+		if copies == 0 {
 			return customErr.New(http.StatusBadRequest, "contains duplicate")
 		}
-		checkDuplicates[c] = true
+		available[strings.ToUpper(c)] = copies - 1
 	}
 	return nil
 }
 
-func isValidCardCode(code string) bool {
-	validRanks := fmt.Sprintf("%s|%s|%s|%s|%s|%s|%s|%s|%s|%s|%s|%s|%s",
-		repo.Ace, repo.Two, repo.Three, repo.Four, repo.Five, repo.Six, repo.Seven, repo.Eight, repo.Nine, repo.Ten, repo.Jack, repo.Queen, repo.King)
-	validSuits := fmt.Sprintf("%s|%s|%s|%s", repo.Spades, repo.Hearts, repo.Diamonds, repo.Clubs)
+func countCards(cards []string) map[string]int {
+	counts := make(map[string]int, len(cards))
+	for _, c := range cards {
+		counts[c]++
+	}
+	return counts
+}
 
-	validCardPattern := regexp.MustCompile(fmt.Sprintf("^(%s)(%s)$", validRanks, validSuits))
+// validateCodes checks card codes referring to cards of an existing deck, so duplicates are fine
+func validateCodes(codes []string) error {
+	for _, c := range codes {
+		if !isValidCardCode(c) {
+			return customErr.New(http.StatusBadRequest, "contains invalid card code")
+		}
+	}
+	return nil
+}
+
+func isValidCardCode(code string) bool {
 	return validCardPattern.MatchString(strings.ToUpper(code))
 }
 
-func getValueAndSuit(code string) (*model.Card, error) {
+func getValueAndSuit(code string, aceHigh bool) (*model.Card, error) {
 	if !isValidCardCode(code) {
 		return nil, customErr.New(http.StatusInternalServerError, "code is not valid")
 	}
-	valueCode := code[:len(code)-1]
-	suitCode := string(code[len(code)-1])
-
-	fullValue := repo.Values[repo.CardCode(valueCode)]
-	suit := repo.Suites[repo.SuitCode(suitCode)]
+	code = strings.ToUpper(code)
+	if color, found := repo.Jokers[repo.CardCode(code)]; found {
+		return &model.Card{
+			Value:  "JOKER",
+			Suit:   color,
+			Code:   code,
+			Color:  color,
+			Symbol: repo.JokerSymbols[repo.CardCode(code)],
+		}, nil
+	}
+	valueCode := repo.CardCode(code[:len(code)-1])
+	suitCode := repo.SuitCode(code[len(code)-1:])
+	rank := repo.Rank(valueCode)
+	if aceHigh {
+		rank = repo.AceHighRank(valueCode)
+	}
 
 	return &model.Card{
-		Value: fullValue,
-		Suit:  suit,
-		Code:  code,
+		Value:  repo.Values[valueCode],
+		Suit:   repo.Suites[suitCode],
+		Code:   code,
+		Rank:   rank,
+		Color:  string(repo.SuitColors[suitCode]),
+		Symbol: repo.Symbol(valueCode, suitCode),
 	}, nil
 }
 
-func drawFirstCards(deck model.OpenDeckResponse, count int) []model.Card {
-	return deck.Cards[:count]
+func joinCodes[T repo.CardCode | repo.SuitCode](codes []T) string {
+	parts := make([]string, len(codes))
+	for i, c := range codes {
+		parts[i] = string(c)
+	}
+	return strings.Join(parts, "|")
+}
+
+func toOpenDeckResponse(deck repo.Deck, aceHigh bool) (*model.OpenDeckResponse, error) {
+	cards, err := toRankedCards(deck.Cards, aceHigh)
+	if err != nil {
+		return nil, err
+	}
+	drawn, err := toRankedCards(deck.Drawn, aceHigh)
+	if err != nil {
+		return nil, err
+	}
+	discarded, err := toRankedCards(deck.Discarded, aceHigh)
+	if err != nil {
+		return nil, err
+	}
+	dealt := deck.Size - deck.Remaining
+	penetration := 0.0
+	if deck.Size > 0 && dealt > 0 {
+		penetration = float64(dealt) / float64(deck.Size)
+	}
+	serverSeed := ""
+	if deck.Remaining == 0 {
+		serverSeed = deck.ServerSeed
+	}
+	return &model.OpenDeckResponse{
+		DeckId:         deck.Id,
+		Shuffled:       deck.Shuffled,
+		ShuffledAt:     deck.ShuffledAt,
+		Remaining:      deck.Remaining,
+		DeckType:       deckType(deck),
+		Jokers:         deck.Jokers,
+		DecksCount:     decksCount(deck),
+		CutCard:        deck.CutCard,
+		Penetration:    penetration,
+		CutCardReached: deck.CutCard > 0 && dealt >= deck.CutCard,
+		ServerSeedHash: hashSeed(deck.ServerSeed),
+		ServerSeed:     serverSeed,
+		ClientSeed:     deck.ClientSeed,
+		Owner:          deck.Owner,
+		ParentId:       deck.ParentId,
+		ExpiresAt:      deck.ExpiresAt,
+		Version:        deck.Version,
+		Cards:          cards,
+		Drawn:          drawn,
+		Discarded:      discarded,
+	}, nil
+}
+
+// toCards turns the codes into cards with the aces ranked low
+func toCards(codes []string) ([]model.Card, error) {
+	return toRankedCards(codes, false)
+}
+
+// toRankedCards turns the codes into cards, with aceHigh the aces rank above the kings
+func toRankedCards(codes []string, aceHigh bool) ([]model.Card, error) {
+	cards := make([]model.Card, len(codes))
+	for i, c := range codes {
+		card, err := getValueAndSuit(c, aceHigh)
+		if err != nil {
+			return nil, err
+		}
+		cards[i] = *card
+	}
+	return cards, nil
 }
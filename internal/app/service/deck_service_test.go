@@ -7,9 +7,10 @@ import (
 	customErr "github.com/deck/internal/app/error"
 	"github.com/deck/internal/app/model"
 	"github.com/deck/internal/app/repo"
+	"github.com/deck/internal/app/repo/repotest"
 	"github.com/stretchr/testify/assert"
 	"net/http"
-	"sort"
+	"strings"
 	"testing"
 	"time"
 )
@@ -21,37 +22,121 @@ var sequentialDeck = []string{
 	"AH", "2H", "3H", "4H", "5H", "6H", "7H", "8H", "9H", "10H", "JH", "QH", "KH",
 }
 
-// MockRepo is a mock implementation of the DeckRepo interface
+// MockRepo is the in-memory repository of the service tests, DeckError makes its methods fail
 type MockRepo struct {
-	Decks     map[string]repo.Deck
+	repo.DeckRepo
 	DeckError error
+	// EventsLoaded counts the deck events read from the repository
+	EventsLoaded int
 }
 
-// Implement the DeckRepo interface methods for the mock
-func (m *MockRepo) CreateDeck(deck repo.Deck) error {
-	m.Decks[deck.Id] = deck
-	return nil
+// newMockRepos returns a deck and a game repository sharing one in-memory store, like they share the database
+func newMockRepos() (*MockRepo, *MockGameRepo) {
+	deckRepo, gameRepo := repo.NewMemoryRepos()
+	return &MockRepo{DeckRepo: deckRepo}, &MockGameRepo{GameRepo: gameRepo}
+}
+
+func newMockRepo() *MockRepo {
+	mockRepo, _ := newMockRepos()
+	return mockRepo
+}
+
+// putDeck stores the deck as it is, replacing the stored deck of the same id
+func (m *MockRepo) putDeck(deck repo.Deck) {
+	_ = m.DeckRepo.DeleteDeck(deck.Id)
+	_ = m.DeckRepo.CreateDeck(deck, repo.DeckEvent{Type: repo.CreateEvent})
+}
+
+// deck returns the stored deck, an empty one if there is none
+func (m *MockRepo) deck(id string) repo.Deck {
+	deck, err := m.DeckRepo.GetDeckById(id)
+	if err != nil {
+		return repo.Deck{}
+	}
+	return *deck
 }
 
 func (m *MockRepo) GetDeckById(id string) (*repo.Deck, error) {
-	deck, found := m.Decks[id]
 	if m.DeckError != nil {
 		return nil, m.DeckError
 	}
-	if !found {
-		return nil, sql.ErrNoRows
+	return m.DeckRepo.GetDeckById(id)
+}
+
+func (m *MockRepo) UpdateDeck(deck repo.Deck, event repo.DeckEvent) error {
+	if m.DeckError != nil {
+		return m.DeckError
+	}
+	return m.DeckRepo.UpdateDeck(deck, event)
+}
+
+func (m *MockRepo) GetDeckEvents(id string, after int, limit int) ([]repo.DeckEvent, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	events, err := m.DeckRepo.GetDeckEvents(id, after, limit)
+	m.EventsLoaded += len(events)
+	return events, err
+}
+
+func (m *MockRepo) ListDecks(filter repo.DeckFilter) ([]repo.Deck, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return m.DeckRepo.ListDecks(filter)
+}
+
+func (m *MockRepo) DeleteDeck(id string) error {
+	if m.DeckError != nil {
+		return m.DeckError
+	}
+	return m.DeckRepo.DeleteDeck(id)
+}
+
+func (m *MockRepo) DeleteExpiredDecks(now time.Time, idleSince *time.Time, limit int) (int, error) {
+	if m.DeckError != nil {
+		return 0, m.DeckError
+	}
+	return m.DeckRepo.DeleteExpiredDecks(now, idleSince, limit)
+}
+
+func (m *MockRepo) SaveSnapshot(snapshot repo.DeckSnapshot, limit int) error {
+	if m.DeckError != nil {
+		return m.DeckError
+	}
+	return m.DeckRepo.SaveSnapshot(snapshot, limit)
+}
+
+func (m *MockRepo) GetSnapshot(deckId, name string) (*repo.DeckSnapshot, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return m.DeckRepo.GetSnapshot(deckId, name)
+}
+
+func (m *MockRepo) ListSnapshots(deckId string) ([]repo.DeckSnapshot, error) {
+	if m.DeckError != nil {
+		return nil, m.DeckError
+	}
+	return m.DeckRepo.ListSnapshots(deckId)
+}
+
+func (m *MockRepo) DeleteSnapshot(deckId, name string) error {
+	if m.DeckError != nil {
+		return m.DeckError
 	}
-	return &deck, nil
+	return m.DeckRepo.DeleteSnapshot(deckId, name)
 }
 
-func (m *MockRepo) UpdateDeck(deck repo.Deck) error {
-	m.Decks[deck.Id] = deck
-	return m.DeckError
+func TestMockRepoContract(t *testing.T) {
+	repotest.DeckRepoContract(t, func(t *testing.T) repo.DeckRepo {
+		return newMockRepo()
+	})
 }
 
 func TestCreateDeck(t *testing.T) {
-	mockRepo := &MockRepo{Decks: make(map[string]repo.Deck)}
-	deckService := NewDeckService(mockRepo)
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
 
 	// Test case: default deck creation
 	req := model.CreateDeckRequest{Shuffled: false}
@@ -89,12 +174,306 @@ func TestCreateDeck(t *testing.T) {
 	res, err = deckService.CreateDeck(req)
 	assert.Error(t, err)
 	assert.Nil(t, res)
+
+	// Test case: the owner is stored with the deck
+	res, err = deckService.CreateDeck(model.CreateDeckRequest{Owner: "table-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "table-1", res.Owner)
+	assert.Equal(t, "table-1", mockRepo.deck(res.DeckId).Owner)
+
+	// Test case: a ttl sets when the deck expires
+	res, err = deckService.CreateDeck(model.CreateDeckRequest{TTL: time.Hour})
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *res.ExpiresAt, time.Minute)
+	res, err = deckService.CreateDeck(model.CreateDeckRequest{TTL: -time.Hour})
+	assert.EqualError(t, err, "ttl must be positive and at most 720h0m0s")
+	assert.Nil(t, res)
+
+	// Test case: an owner too long for the database
+	res, err = deckService.CreateDeck(model.CreateDeckRequest{Owner: strings.Repeat("a", 101)})
+	assert.EqualError(t, err, "owner must be at most 100 characters")
+	assert.Nil(t, res)
+}
+
+func TestCreateDeckWithRandomSource(t *testing.T) {
+	// Test case: services with the same deterministic source shuffle the same way
+	firstRepo := newMockRepo()
+	first, err := NewDeckService(firstRepo, NewSeededSource("table-1")).CreateDeck(model.CreateDeckRequest{Shuffled: true})
+	assert.NoError(t, err)
+	secondRepo := newMockRepo()
+	second, err := NewDeckService(secondRepo, NewSeededSource("table-1")).CreateDeck(model.CreateDeckRequest{Shuffled: true})
+	assert.NoError(t, err)
+
+	assert.Equal(t, first.ServerSeedHash, second.ServerSeedHash)
+	assert.Equal(t, firstRepo.deck(first.DeckId).Cards, secondRepo.deck(second.DeckId).Cards)
+	assert.NotEqual(t, sequentialDeck, []string(firstRepo.deck(first.DeckId).Cards))
+
+	// Test case: a different source gives another order
+	thirdRepo := newMockRepo()
+	third, err := NewDeckService(thirdRepo, NewSeededSource("table-2")).CreateDeck(model.CreateDeckRequest{Shuffled: true})
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, firstRepo.deck(first.DeckId).Cards, thirdRepo.deck(third.DeckId).Cards)
+}
+
+func TestShuffleDeck(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewSeededSource("reshuffle"))
+	deckID := "existing_deck_id"
+	mockRepo.putDeck(repo.Deck{
+		Id:        deckID,
+		Remaining: 5,
+		Cards:     []string{"AH", "2C", "3D", "4S", "5H"},
+		Drawn:     []string{"6H"},
+		Discarded: []string{"7H"},
+		Piles:     []repo.Pile{{DeckId: deckID, Name: "hand", Cards: []string{"8H"}}},
+	})
+
+	// Test case: reshuffle only the remaining cards
+	res, err := deckService.ShuffleDeck(deckID, false)
+
+	assert.NoError(t, err)
+	assert.True(t, res.Shuffled)
+	assert.NotNil(t, res.ShuffledAt)
+	assert.Equal(t, 5, res.Remaining)
+	deck := mockRepo.deck(deckID)
+	assert.ElementsMatch(t, []string{"AH", "2C", "3D", "4S", "5H"}, []string(deck.Cards))
+	assert.NotEqual(t, []string{"AH", "2C", "3D", "4S", "5H"}, []string(deck.Cards))
+	assert.Equal(t, []string{"6H"}, []string(deck.Drawn))
+
+	// Test case: fold drawn and discarded cards back in, piles keep their cards
+	res, err = deckService.ShuffleDeck(deckID, true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 7, res.Remaining)
+	deck = mockRepo.deck(deckID)
+	assert.ElementsMatch(t, []string{"AH", "2C", "3D", "4S", "5H", "6H", "7H"}, []string(deck.Cards))
+	assert.Empty(t, deck.Drawn)
+	assert.Empty(t, deck.Discarded)
+	assert.Equal(t, []string{"8H"}, []string(deck.Piles[0].Cards))
+
+	// Test case: reshuffling a missing deck
+	res, err = deckService.ShuffleDeck("non_existing_deck_id", true)
+
+	assert.Error(t, err)
+	assert.Nil(t, res)
+}
+
+func TestSeededDeck(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+
+	// Test case: the same seed recreates the exact order
+	first, err := deckService.CreateDeck(model.CreateDeckRequest{Shuffled: true, Seed: "replay-42"})
+	assert.NoError(t, err)
+	second, err := deckService.CreateDeck(model.CreateDeckRequest{Shuffled: true, Seed: "replay-42"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, mockRepo.deck(first.DeckId).Cards, mockRepo.deck(second.DeckId).Cards)
+	assert.Equal(t, hashSeed("replay-42"), first.ServerSeedHash)
+
+	// Test case: later random operations replay the same way
+	for _, id := range []string{first.DeckId, second.DeckId} {
+		_, err = deckService.DrawCards(id, 10)
+		assert.NoError(t, err)
+		_, err = deckService.ReturnCards(id, model.ReturnCardsRequest{Cards: []string{mockRepo.deck(id).Drawn[3]}, Position: model.Random})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, mockRepo.deck(first.DeckId).Cards, mockRepo.deck(second.DeckId).Cards)
+
+	// Test case: the seed is reported for seeded decks
+	seed, err := deckService.GetDeckSeed(first.DeckId)
+	assert.NoError(t, err)
+	assert.Equal(t, "replay-42", seed.Seed)
+	assert.True(t, seed.Seeded)
+
+	// Test case: generated seeds stay secret
+	res, err := deckService.CreateDeck(model.CreateDeckRequest{Shuffled: true})
+	assert.NoError(t, err)
+	seed, err = deckService.GetDeckSeed(res.DeckId)
+	assert.EqualError(t, err, "server seed is revealed once the deck is finished")
+	assert.Nil(t, seed)
+
+	// Test case: a seed needs a shuffled deck
+	res, err = deckService.CreateDeck(model.CreateDeckRequest{Seed: "replay-42"})
+	assert.EqualError(t, err, "seed can only be given for shuffled decks")
+	assert.Nil(t, res)
+}
+
+func TestCreateDeckTypes(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+
+	// Test case: every preset with and without jokers
+	sizes := map[string]int{"standard": 52, "piquet": 32, "euchre": 24, "pinochle": 48, "stripped": 36}
+	for deckType, size := range sizes {
+		res, err := deckService.CreateDeck(model.CreateDeckRequest{DeckType: deckType})
+		assert.NoError(t, err)
+		assert.Equal(t, size, res.Remaining)
+		assert.Equal(t, deckType, res.DeckType)
+
+		res, err = deckService.CreateDeck(model.CreateDeckRequest{DeckType: deckType, Jokers: true})
+		assert.NoError(t, err)
+		assert.Equal(t, size+2, res.Remaining)
+		assert.True(t, res.Jokers)
+	}
+
+	// Test case: the deck type is returned when opening the deck
+	res, err := deckService.CreateDeck(model.CreateDeckRequest{DeckType: "euchre", Jokers: true})
+	assert.NoError(t, err)
+	deck, err := deckService.GetDeckById(res.DeckId)
+	assert.NoError(t, err)
+	assert.Equal(t, "euchre", deck.DeckType)
+	assert.True(t, deck.Jokers)
+	assert.Equal(t, model.Card{Value: "JOKER", Suit: "RED", Code: "X1", Color: "RED", Symbol: "🂿"}, deck.Cards[24])
+
+	// Test case: custom pinochle cards can contain two copies
+	res, err = deckService.CreateDeck(model.CreateDeckRequest{DeckType: "pinochle", Cards: "AS,AS,9H"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, res.Remaining)
+
+	res, err = deckService.CreateDeck(model.CreateDeckRequest{DeckType: "pinochle", Cards: "AS,AS,AS"})
+	assert.EqualError(t, err, "contains duplicate")
+	assert.Nil(t, res)
+
+	// Test case: custom cards must belong to the deck type
+	res, err = deckService.CreateDeck(model.CreateDeckRequest{DeckType: "piquet", Cards: "AS,2S"})
+	assert.EqualError(t, err, "contains card 2S which isn't part of a piquet deck")
+	assert.Nil(t, res)
+
+	// Test case: jokers in custom cards need jokers
+	res, err = deckService.CreateDeck(model.CreateDeckRequest{Cards: "AS,X2"})
+	assert.Error(t, err)
+	res, err = deckService.CreateDeck(model.CreateDeckRequest{Cards: "AS,X2", Jokers: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res.Remaining)
+
+	// Test case: unknown deck type
+	res, err = deckService.CreateDeck(model.CreateDeckRequest{DeckType: "tarot"})
+	assert.EqualError(t, err, "unknown deck type tarot")
+	assert.Nil(t, res)
+}
+
+func TestCreateShoe(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+
+	// Test case: six deck shoe with a cut card
+	req := model.CreateDeckRequest{Shuffled: true, DecksCount: 6, CutCard: 234}
+	res, err := deckService.CreateDeck(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 312, res.Remaining)
+	assert.Equal(t, 6, res.DecksCount)
+	deck := mockRepo.deck(res.DeckId)
+	assert.Equal(t, 312, deck.Size)
+	copies := make(map[string]int)
+	for _, c := range deck.Cards {
+		copies[c]++
+	}
+	assert.Len(t, copies, 52)
+	for _, count := range copies {
+		assert.Equal(t, 6, count)
+	}
+
+	// Test case: shoe of custom cards
+	req = model.CreateDeckRequest{Cards: "AS,KD", DecksCount: 2}
+	res, err = deckService.CreateDeck(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"AS", "KD", "AS", "KD"}, []string(mockRepo.deck(res.DeckId).Cards))
+
+	// Test case: too many decks
+	req = model.CreateDeckRequest{DecksCount: 9}
+	res, err = deckService.CreateDeck(req)
+
+	assert.EqualError(t, err, "decks count must be between 1 - 8")
+	assert.Nil(t, res)
+
+	// Test case: cut card outside of the shoe
+	req = model.CreateDeckRequest{DecksCount: 1, CutCard: 53}
+	res, err = deckService.CreateDeck(req)
+
+	assert.EqualError(t, err, "cut card must be within the shoe")
+	assert.Nil(t, res)
+}
+
+func TestShoePenetration(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+	res, err := deckService.CreateDeck(model.CreateDeckRequest{DecksCount: 2, CutCard: 78})
+	assert.NoError(t, err)
+
+	// Test case: draws above a single deck are allowed from a shoe
+	_, err = deckService.DrawCards(res.DeckId, 60)
+	assert.NoError(t, err)
+
+	deck, err := deckService.GetDeckById(res.DeckId)
+	assert.NoError(t, err)
+	assert.Equal(t, 44, deck.Remaining)
+	assert.InDelta(t, 60.0/104.0, deck.Penetration, 0.0001)
+	assert.False(t, deck.CutCardReached)
+
+	// Test case: the cut card is reached
+	_, err = deckService.DrawCards(res.DeckId, 18)
+	assert.NoError(t, err)
+
+	deck, err = deckService.GetDeckById(res.DeckId)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.75, deck.Penetration, 0.0001)
+	assert.True(t, deck.CutCardReached)
+
+	// Test case: draws are limited by the shoe size
+	_, err = deckService.DrawCards(res.DeckId, 105)
+	assert.EqualError(t, err, "count must be between 1 - 104")
+}
+
+func TestVerifyDeck(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+	res, err := deckService.CreateDeck(model.CreateDeckRequest{Shuffled: true, ClientSeed: "lucky"})
+	assert.NoError(t, err)
+	assert.Len(t, res.ServerSeedHash, 64)
+	assert.Equal(t, "lucky", res.ClientSeed)
+
+	// Test case: the server seed stays secret while cards are left
+	deck, err := deckService.GetDeckById(res.DeckId)
+	assert.NoError(t, err)
+	assert.Empty(t, deck.ServerSeed)
+
+	verified, err := deckService.VerifyDeck(res.DeckId)
+	assert.EqualError(t, err, "server seed is revealed once the deck is finished")
+	assert.Nil(t, verified)
+
+	// Test case: the finished deck reveals its seed and the replayed shuffle matches the dealt cards
+	dealt, err := deckService.DrawCards(res.DeckId, 52)
+	assert.NoError(t, err)
+	deck, err = deckService.GetDeckById(res.DeckId)
+	assert.NoError(t, err)
+	assert.Equal(t, res.ServerSeedHash, hashSeed(deck.ServerSeed))
+
+	verified, err = deckService.VerifyDeck(res.DeckId)
+	assert.NoError(t, err)
+	assert.Equal(t, deck.ServerSeed, verified.ServerSeed)
+	assert.Equal(t, "lucky", verified.ClientSeed)
+	assert.Equal(t, dealt, verified.Cards)
+	initial, _ := toCards(sequentialDeck)
+	assert.Equal(t, initial, verified.InitialCards)
+
+	// Test case: unshuffled decks have nothing to verify
+	res, err = deckService.CreateDeck(model.CreateDeckRequest{Cards: "AS"})
+	assert.NoError(t, err)
+	_, err = deckService.DrawCards(res.DeckId, 1)
+	assert.NoError(t, err)
+	verified, err = deckService.VerifyDeck(res.DeckId)
+	assert.EqualError(t, err, "deck wasn't shuffled with a server seed")
+	assert.Nil(t, verified)
 }
 
 func TestGetDeckById(t *testing.T) {
 	// Set up the DeckService with the mock repository
-	mockRepo := &MockRepo{Decks: make(map[string]repo.Deck)}
-	deckService := NewDeckService(mockRepo)
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
 	now := time.Now().UTC()
 
 	// Test case: get a deck by ID successfully
@@ -107,7 +486,7 @@ func TestGetDeckById(t *testing.T) {
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
-	mockRepo.Decks[deckID] = mockDeck
+	mockRepo.putDeck(mockDeck)
 
 	res, err := deckService.GetDeckById(deckID)
 
@@ -126,6 +505,14 @@ func TestGetDeckById(t *testing.T) {
 	assert.EqualError(t, err, fmt.Sprintf("deck with id %s wasn't found", nonExistingDeckID))
 	assert.Nil(t, res)
 
+	// Test case: an expired deck isn't found before the reaper deletes it
+	expired := now.Add(-time.Minute)
+	mockRepo.putDeck(repo.Deck{Id: "expired_deck_id", ExpiresAt: &expired, CreatedAt: now, UpdatedAt: now})
+	res, err = deckService.GetDeckById("expired_deck_id")
+
+	assert.EqualError(t, err, "deck with id expired_deck_id wasn't found")
+	assert.Nil(t, res)
+
 	// Test case: error while retrieving deck from the database
 	errMessage := "database error"
 	mockRepo.DeckError = errors.New(errMessage)
@@ -136,10 +523,95 @@ func TestGetDeckById(t *testing.T) {
 	assert.Nil(t, res)
 }
 
+func TestWithAceHigh(t *testing.T) {
+	mockRepo := newMockRepo()
+	mockRepo.putDeck(repo.Deck{Id: "deck", Remaining: 3, Cards: []string{"AS", "KS", "AD"}, Size: 3})
+	deckService := NewDeckService(mockRepo, NewCryptoSource()).WithAceHigh(true)
+
+	// Test case: the aces of an opened deck rank above the kings
+	deck, err := deckService.GetDeckById("deck")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{14, 13, 14}, []int{deck.Cards[0].Rank, deck.Cards[1].Rank, deck.Cards[2].Rank})
+
+	// Test case: so do the aces drawn
+	cards, err := deckService.DrawCards("deck", 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 14, cards[0].Rank)
+
+	// Test case: without the option the aces rank low
+	cards, err = deckService.WithAceHigh(false).DrawSpecificCards("deck", []string{"AD"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, cards[0].Rank)
+}
+
+func TestDeleteDeck(t *testing.T) {
+	mockRepo := newMockRepo()
+	mockRepo.putDeck(repo.Deck{Id: "existing_deck_id"})
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+
+	// Test case: delete a deck
+	assert.NoError(t, deckService.DeleteDeck("existing_deck_id"))
+	_, err := mockRepo.GetDeckById("existing_deck_id")
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	// Test case: delete it again
+	err = deckService.DeleteDeck("existing_deck_id")
+	assert.EqualError(t, err, "deck with id existing_deck_id wasn't found")
+	assert.Equal(t, http.StatusNotFound, err.(*customErr.Error).Kind())
+
+	// Test case: the database fails
+	mockRepo.DeckError = errors.New("connection refused")
+	err = deckService.DeleteDeck("existing_deck_id")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, err.(*customErr.Error).Kind())
+}
+
+func TestWithoutGameDecks(t *testing.T) {
+	deckService, mockRepo, gameRepo := newGameDecks()
+	mockRepo.putDeck(repo.Deck{Id: "shoe", Remaining: 2, Cards: []string{"AS", "KS"}, Size: 2})
+	mockRepo.putDeck(repo.Deck{Id: "free", Remaining: 2, Cards: []string{"AS", "KS"}, Size: 2})
+	assert.NoError(t, gameRepo.CreateGame(repo.Game{Id: "game", GameType: repo.BlackjackGame, DeckId: "shoe"}))
+	players := deckService.WithoutGameDecks(gameRepo)
+
+	// Test case: a game's deck can't be read, changed nor deleted
+	res, err := players.GetDeckById("shoe")
+
+	assert.EqualError(t, err, "deck with id shoe belongs to game game")
+	assert.Equal(t, http.StatusForbidden, err.(*customErr.Error).Kind())
+	assert.Nil(t, res)
+	_, err = players.DrawCards("shoe", 1)
+	assert.Equal(t, http.StatusForbidden, err.(*customErr.Error).Kind())
+	err = players.DeleteDeck("shoe")
+	assert.Equal(t, http.StatusForbidden, err.(*customErr.Error).Kind())
+	assert.Equal(t, 2, mockRepo.deck("shoe").Remaining)
+
+	// Test case: other decks are served as usual
+	res, err = players.GetDeckById("free")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res.Remaining)
+
+	// Test case: the game still deals from its deck
+	cards, err := deckService.DrawCards("shoe", 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"AS"}, cardCodes(cards))
+
+	// Test case: the database fails
+	gameRepo.GameError = errors.New("connection refused")
+	res, err = players.GetDeckById("free")
+
+	assert.Equal(t, http.StatusInternalServerError, err.(*customErr.Error).Kind())
+	assert.Nil(t, res)
+}
+
 func TestDrawCards(t *testing.T) {
 	// Set up the DeckService with the mock repository
-	mockRepo := &MockRepo{Decks: make(map[string]repo.Deck)}
-	deckService := NewDeckService(mockRepo)
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
 	now := time.Now().UTC()
 
 	// Test case: draw cards successfully
@@ -153,16 +625,17 @@ func TestDrawCards(t *testing.T) {
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
-	mockRepo.Decks[deckID] = mockDeck
+	mockRepo.putDeck(mockDeck)
 
 	count := 3
 	cards, err := deckService.DrawCards(deckID, count)
 
 	assert.NoError(t, err)
 	assert.Len(t, cards, count)
-	updatedDeck, found := mockRepo.Decks[deckID]
-	assert.True(t, found)
+	updatedDeck, err := mockRepo.GetDeckById(deckID)
+	assert.NoError(t, err)
 	assert.Equal(t, initialRemaining-count, updatedDeck.Remaining)
+	assert.Equal(t, []string{"AH", "2C", "3D"}, []string(updatedDeck.Drawn))
 
 	// Test case: draw cards with count exceeding remaining
 	count = 15
@@ -188,6 +661,299 @@ func TestDrawCards(t *testing.T) {
 
 	assert.Error(t, err)
 	assert.Nil(t, cards)
+
+	// Test case: the deck was modified by a concurrent draw
+	mockRepo.DeckError = nil
+	mockDeck.Version = 5
+	mockRepo.putDeck(mockDeck)
+	conflictRepo := &conflictingRepo{MockRepo: mockRepo}
+	cards, err = NewDeckService(conflictRepo, NewCryptoSource()).DrawCards(deckID, 1)
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, err.(*customErr.Error).Kind())
+	assert.Nil(t, cards)
+	assert.Equal(t, initialRemaining, mockRepo.deck(deckID).Remaining)
+}
+
+// conflictingRepo simulates another request updating the deck between reading and saving it
+type conflictingRepo struct {
+	*MockRepo
+}
+
+func (m *conflictingRepo) GetDeckById(id string) (*repo.Deck, error) {
+	deck, err := m.MockRepo.GetDeckById(id)
+	if err != nil {
+		return nil, err
+	}
+	deck.Version--
+	return deck, nil
+}
+
+func TestDrawCardsFrom(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewSeededSource("draw"))
+	deckID := "existing_deck_id"
+	mockRepo.putDeck(repo.Deck{
+		Id:        deckID,
+		Remaining: 6,
+		Cards:     []string{"AH", "2C", "3D", "4S", "5H", "6H"},
+	})
+
+	// Test case: draw from the bottom, bottom card first
+	cards, err := deckService.DrawCardsFrom(deckID, 2, model.Bottom)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "6H", cards[0].Code)
+	assert.Equal(t, "5H", cards[1].Code)
+	assert.Equal(t, []string{"AH", "2C", "3D", "4S"}, []string(mockRepo.deck(deckID).Cards))
+	assert.Equal(t, 4, mockRepo.deck(deckID).Remaining)
+
+	// Test case: draw random cards, the rest keeps its order
+	cards, err = deckService.DrawCardsFrom(deckID, 2, model.Random)
+
+	assert.NoError(t, err)
+	assert.Len(t, cards, 2)
+	deck := mockRepo.deck(deckID)
+	assert.Equal(t, 2, deck.Remaining)
+	assert.ElementsMatch(t, []string{"AH", "2C", "3D", "4S"}, append([]string{cards[0].Code, cards[1].Code}, deck.Cards...))
+	assert.ElementsMatch(t, []string{"6H", "5H", cards[0].Code, cards[1].Code}, []string(deck.Drawn))
+
+	// Test case: invalid position
+	cards, err = deckService.DrawCardsFrom(deckID, 1, "middle")
+
+	assert.EqualError(t, err, "from must be top, bottom or random")
+	assert.Nil(t, cards)
+}
+
+func TestDrawSpecificCards(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+	deckID := "existing_deck_id"
+	mockRepo.putDeck(repo.Deck{
+		Id:        deckID,
+		Remaining: 4,
+		Cards:     []string{"AH", "7H", "3D", "4S"},
+	})
+
+	// Test case: pull named cards out of the deck
+	cards, err := deckService.DrawSpecificCards(deckID, []string{"7H", "4S"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "7H", cards[0].Code)
+	assert.Equal(t, "4S", cards[1].Code)
+	assert.Equal(t, []string{"AH", "3D"}, []string(mockRepo.deck(deckID).Cards))
+	assert.Equal(t, []string{"7H", "4S"}, []string(mockRepo.deck(deckID).Drawn))
+	assert.Equal(t, 2, mockRepo.deck(deckID).Remaining)
+
+	// Test case: the card isn't in the deck any more
+	cards, err = deckService.DrawSpecificCards(deckID, []string{"7H"})
+
+	assert.EqualError(t, err, "card 7H isn't in the deck")
+	assert.Equal(t, http.StatusNotFound, err.(*customErr.Error).Kind())
+	assert.Nil(t, cards)
+	assert.Equal(t, []string{"AH", "3D"}, []string(mockRepo.deck(deckID).Cards))
+
+	// Test case: no cards given
+	cards, err = deckService.DrawSpecificCards(deckID, nil)
+
+	assert.EqualError(t, err, "cards to draw must be given")
+	assert.Nil(t, cards)
+}
+
+func TestPeekCards(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+	deckID := "existing_deck_id"
+	mockRepo.putDeck(repo.Deck{Id: deckID, Remaining: 3, Cards: []string{"AH", "2C", "3D"}})
+
+	// Test case: peek doesn't change the deck
+	res, err := deckService.PeekCards(deckID, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, res.Remaining)
+	assert.Equal(t, "AH", res.Cards[0].Code)
+	assert.Equal(t, "2C", res.Cards[1].Code)
+	assert.Equal(t, 0, mockRepo.deck(deckID).Version)
+
+	// Test case: peek more than the deck has
+	res, err = deckService.PeekCards(deckID, 4)
+
+	assert.EqualError(t, err, "count must be less or equal than deck's remaining")
+	assert.Nil(t, res)
+}
+
+func TestCutDeck(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewSeededSource("cut"))
+	deckID := "existing_deck_id"
+	mockRepo.putDeck(repo.Deck{Id: deckID, Remaining: 5, Cards: []string{"AH", "2C", "3D", "4S", "5H"}})
+
+	// Test case: cut at a position
+	res, err := deckService.CutDeck(deckID, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, res.Remaining)
+	assert.Equal(t, []string{"3D", "4S", "5H", "AH", "2C"}, []string(mockRepo.deck(deckID).Cards))
+
+	// Test case: cut at a random position within bounds
+	for i := 0; i < 20; i++ {
+		res, err = deckService.CutDeck(deckID, 0)
+		assert.NoError(t, err)
+		assert.True(t, res.Position >= 1 && res.Position <= 4)
+	}
+	assert.ElementsMatch(t, []string{"AH", "2C", "3D", "4S", "5H"}, []string(mockRepo.deck(deckID).Cards))
+
+	// Test case: cut outside of the deck
+	res, err = deckService.CutDeck(deckID, 5)
+
+	assert.EqualError(t, err, "position must be between 1 - 4")
+	assert.Nil(t, res)
+}
+
+func TestInsertCard(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+	deckID := "existing_deck_id"
+	mockRepo.putDeck(repo.Deck{
+		Id:        deckID,
+		Remaining: 3,
+		Cards:     []string{"AH", "2C", "3D"},
+		Drawn:     []string{"4S"},
+		Piles:     []repo.Pile{{DeckId: deckID, Name: "hand", Cards: []string{"5H"}}},
+		Size:      5,
+	})
+
+	// Test case: insert a drawn card
+	res, err := deckService.InsertCard(deckID, "4S", 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, res.Remaining)
+	assert.Equal(t, []string{"AH", "4S", "2C", "3D"}, []string(mockRepo.deck(deckID).Cards))
+	assert.Empty(t, mockRepo.deck(deckID).Drawn)
+
+	// Test case: insert a card the deck doesn't have yet at the bottom
+	res, err = deckService.InsertCard(deckID, "KS", 4)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, res.Remaining)
+	assert.Equal(t, "KS", mockRepo.deck(deckID).Cards[4])
+	assert.Equal(t, 6, mockRepo.deck(deckID).Size)
+
+	// Test case: a card can't be in the deck twice
+	res, err = deckService.InsertCard(deckID, "AH", 0)
+
+	assert.EqualError(t, err, "card AH is already in the deck")
+	assert.Nil(t, res)
+
+	// Test case: cards in piles count as well
+	res, err = deckService.InsertCard(deckID, "5H", 0)
+
+	assert.EqualError(t, err, "card 5H is already in the deck")
+	assert.Nil(t, res)
+
+	// Test case: jokers aren't part of the deck
+	res, err = deckService.InsertCard(deckID, "X1", 0)
+
+	assert.EqualError(t, err, "card X1 isn't part of a standard deck")
+	assert.Nil(t, res)
+
+	// Test case: index outside of the deck
+	res, err = deckService.InsertCard(deckID, "QS", 6)
+
+	assert.EqualError(t, err, "index must be between 0 - 5")
+	assert.Nil(t, res)
+}
+
+func TestDiscardCards(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+	deckID := "existing_deck_id"
+	mockRepo.putDeck(repo.Deck{
+		Id:        deckID,
+		Remaining: 2,
+		Cards:     []string{"4S", "5H"},
+		Drawn:     []string{"AH", "2C", "3D"},
+	})
+
+	// Test case: discard drawn cards
+	res, err := deckService.DiscardCards(deckID, []string{"2C", "AH"})
+
+	assert.NoError(t, err)
+	assert.Len(t, res.Drawn, 1)
+	assert.Equal(t, []string{"3D"}, []string(mockRepo.deck(deckID).Drawn))
+	assert.Equal(t, []string{"2C", "AH"}, []string(mockRepo.deck(deckID).Discarded))
+
+	// Test case: discard a card which is still in the deck
+	res, err = deckService.DiscardCards(deckID, []string{"4S"})
+
+	assert.EqualError(t, err, "card 4S wasn't drawn from the deck")
+	assert.Nil(t, res)
+
+	// Test case: discard an invalid card
+	res, err = deckService.DiscardCards(deckID, []string{"XYZ"})
+
+	assert.EqualError(t, err, "contains invalid card code")
+	assert.Nil(t, res)
+}
+
+func TestReturnCards(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+	deckID := "existing_deck_id"
+	mockRepo.putDeck(repo.Deck{
+		Id:        deckID,
+		Remaining: 2,
+		Cards:     []string{"4S", "5H"},
+		Drawn:     []string{"AH"},
+		Discarded: []string{"2C", "3D"},
+	})
+
+	// Test case: return a drawn card to the top
+	res, err := deckService.ReturnCards(deckID, model.ReturnCardsRequest{Cards: []string{"AH"}, Position: model.Top})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, res.Remaining)
+	assert.Equal(t, []string{"AH", "4S", "5H"}, []string(mockRepo.deck(deckID).Cards))
+	assert.Empty(t, mockRepo.deck(deckID).Drawn)
+
+	// Test case: return the whole discard pile to the bottom
+	res, err = deckService.ReturnCards(deckID, model.ReturnCardsRequest{Position: model.Bottom})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, res.Remaining)
+	assert.Equal(t, []string{"AH", "4S", "5H", "2C", "3D"}, []string(mockRepo.deck(deckID).Cards))
+	assert.Empty(t, mockRepo.deck(deckID).Discarded)
+
+	// Test case: nothing left to return
+	res, err = deckService.ReturnCards(deckID, model.ReturnCardsRequest{})
+
+	assert.EqualError(t, err, "there are no cards to return")
+	assert.Nil(t, res)
+
+	// Test case: return a card which is in the deck
+	res, err = deckService.ReturnCards(deckID, model.ReturnCardsRequest{Cards: []string{"4S"}})
+
+	assert.EqualError(t, err, "card 4S is neither drawn nor discarded")
+	assert.Nil(t, res)
+
+	// Test case: return to a random position and reshuffle
+	deck := mockRepo.deck(deckID)
+	deck.Cards, deck.Drawn, deck.Remaining = deck.Cards[1:], []string{"AH"}, 4
+	mockRepo.putDeck(deck)
+	res, err = deckService.ReturnCards(deckID, model.ReturnCardsRequest{Cards: []string{"AH"}, Position: model.Random, Shuffle: true})
+
+	assert.NoError(t, err)
+	assert.True(t, res.Shuffled)
+	assert.ElementsMatch(t, []string{"AH", "4S", "5H", "2C", "3D"}, []string(mockRepo.deck(deckID).Cards))
+
+	// Test case: invalid position
+	deck = mockRepo.deck(deckID)
+	deck.Cards, deck.Discarded, deck.Remaining = deck.Cards[1:], deck.Cards[:1], 4
+	mockRepo.putDeck(deck)
+	res, err = deckService.ReturnCards(deckID, model.ReturnCardsRequest{Position: "middle"})
+
+	assert.EqualError(t, err, "position must be top, bottom or random")
+	assert.Nil(t, res)
 }
 
 func TestGenerateDefaultDeck(t *testing.T) {
@@ -197,23 +963,35 @@ func TestGenerateDefaultDeck(t *testing.T) {
 	assert.Equal(t, sequentialDeck, result)
 }
 
-func TestShuffleCards(t *testing.T) {
-	// Test case: shuffle a deck of cards
-	// Make a copy of the original deck to compare
-	expectedDeck := make([]string, len(sequentialDeck))
-	copy(expectedDeck, sequentialDeck)
+func TestGenerateDeck(t *testing.T) {
+	// Test case: piquet deck keeps the sequential order of suits and values
+	result := GenerateDeck(repo.Piquet, false)
+
+	assert.Len(t, result, 32)
+	assert.Equal(t, []string{"AS", "7S", "8S", "9S", "10S", "JS", "QS", "KS", "AD"}, result[:9])
+
+	// Test case: jokers are added at the end
+	result = GenerateDeck(repo.Standard, true)
 
-	ShuffleCards(sequentialDeck)
+	assert.Equal(t, sequentialDeck, result[:52])
+	assert.Equal(t, []string{"X1", "X2"}, result[52:])
 
-	// Assert that the deck is shuffled by comparing it with the original deck
-	assert.NotEqual(t, expectedDeck, sequentialDeck)
+	// Test case: pinochle deck has two copies of each card
+	result = GenerateDeck(repo.Pinochle, false)
 
-	// Sort both decks to compare the elements
-	sort.Strings(expectedDeck)
-	sort.Strings(sequentialDeck)
+	assert.Len(t, result, 48)
+	assert.Equal(t, result[:24], result[24:])
+}
+
+func TestShuffleCards(t *testing.T) {
+	// Test case: a seeded source gives an exact order and the shuffle is recorded
+	deck := repo.Deck{Cards: append([]string{}, sequentialDeck[:13]...)}
 
-	// Assert that the elements are the same after sorting, indicating a valid shuffle
-	assert.Equal(t, expectedDeck, sequentialDeck)
+	shuffleDeck(NewSeededSource("shuffle"), &deck)
+
+	assert.Equal(t, []string{"10S", "JS", "5S", "6S", "3S", "4S", "KS", "7S", "9S", "QS", "AS", "2S", "8S"}, []string(deck.Cards))
+	assert.True(t, deck.Shuffled)
+	assert.NotNil(t, deck.ShuffledAt)
 }
 
 func TestValidateCards(t *testing.T) {
@@ -265,21 +1043,49 @@ func TestIsValidCardCode(t *testing.T) {
 	result = isValidCardCode(invalidSuitCard)
 
 	assert.False(t, result)
+
+	// Test case: jokers
+	assert.True(t, isValidCardCode("X1"))
+	assert.True(t, isValidCardCode("X2"))
+	assert.False(t, isValidCardCode("X3"))
 }
 
 func TestGetValueAndSuit(t *testing.T) {
 	// Test case: valid card code
 	validCard := "2H"
 
-	card, err := getValueAndSuit(validCard)
+	card, err := getValueAndSuit(validCard, false)
 
 	// Assert that there is no error
 	assert.NoError(t, err)
 
 	expectedCard := &model.Card{
-		Value: repo.Values[("2")],
-		Suit:  repo.Suites[("H")],
-		Code:  validCard,
+		Value:  repo.Values[("2")],
+		Suit:   repo.Suites[("H")],
+		Code:   validCard,
+		Rank:   2,
+		Color:  "RED",
+		Symbol: "🂲",
 	}
 	assert.Equal(t, expectedCard, card)
+
+	// Test case: court cards skip the knight of the Unicode block
+	card, err = getValueAndSuit("QC", false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &model.Card{Value: "QUEEN", Suit: "CLUBS", Code: "QC", Rank: 12, Color: "BLACK", Symbol: "🃝"}, card)
+
+	// Test case: the ace ranks low or high
+	card, err = getValueAndSuit("AS", false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, card.Rank)
+	card, err = getValueAndSuit("AS", true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 14, card.Rank)
+	card, err = getValueAndSuit("KS", true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 13, card.Rank)
 }
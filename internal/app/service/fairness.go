@@ -0,0 +1,93 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+)
+
+// Shuffled decks are dealt provably fair with a commit-reveal scheme:
+//
+//  1. On creation the server picks a random 32 byte server seed and only publishes its SHA-256 hash.
+//     The player can add a client seed, so the server can't pick a favourable order alone.
+//  2. The permutation is derived from a byte stream of HMAC-SHA256 blocks, keyed with the hex server seed,
+//     over the messages "<client seed>:0", "<client seed>:1", ...
+//  3. A Fisher-Yates shuffle runs from the last card down: for position i it takes the next 4 bytes as a big-endian
+//     uint32, rejects values at or above the largest multiple of i+1 below 2^32, and swaps card i with card value mod (i+1).
+//  4. Once the deck is finished the server seed is revealed, so anyone can check its hash and replay the shuffle.
+
+// fairStream is the deterministic byte stream of step 2, usable as a RandomSource
+type fairStream struct {
+	serverSeed string
+	clientSeed string
+	counter    int
+	buffer     []byte
+}
+
+func newFairStream(serverSeed, clientSeed string) *fairStream {
+	return &fairStream{serverSeed: serverSeed, clientSeed: clientSeed}
+}
+
+// refill appends the next HMAC-SHA256 block to the buffer
+func (f *fairStream) refill() {
+	mac := hmac.New(sha256.New, []byte(f.serverSeed))
+	mac.Write([]byte(fmt.Sprintf("%s:%d", f.clientSeed, f.counter)))
+	f.counter++
+	f.buffer = append(f.buffer, mac.Sum(nil)...)
+}
+
+func (f *fairStream) next() uint32 {
+	if len(f.buffer) < 4 {
+		f.refill()
+	}
+	value := binary.BigEndian.Uint32(f.buffer[:4])
+	f.buffer = f.buffer[4:]
+	return value
+}
+
+// Intn returns a uniform number in [0, n) without modulo bias
+func (f *fairStream) Intn(n int) int {
+	limit := uint32((1 << 32) - (1<<32)%uint64(n))
+	for {
+		value := f.next()
+		if limit == 0 || value < limit {
+			return int(value % uint32(n))
+		}
+	}
+}
+
+func (f *fairStream) Read(p []byte) (int, error) {
+	for i := range p {
+		if len(f.buffer) == 0 {
+			f.refill()
+		}
+		p[i] = f.buffer[0]
+		f.buffer = f.buffer[1:]
+	}
+	return len(p), nil
+}
+
+// fairShuffle shuffles the cards in place as described above
+func fairShuffle(cards []string, serverSeed, clientSeed string) {
+	shuffleWith(newFairStream(serverSeed, clientSeed), cards)
+}
+
+// newServerSeed returns a random hex encoded 32 byte seed
+func newServerSeed(source RandomSource) (string, error) {
+	seed := make([]byte, 32)
+	if _, err := source.Read(seed); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(seed), nil
+}
+
+// hashSeed is the commitment published for a server seed
+func hashSeed(seed string) string {
+	if len(seed) == 0 {
+		return ""
+	}
+	hash := sha256.Sum256([]byte(seed))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFairShuffle(t *testing.T) {
+	serverSeed := "4f1c0e3b9a6d2c7e8b5a1f0d3c6e9b2a7d4c1e8f5b2a9c6d3e0f7a4b1c8d5e2f"
+
+	// Test case: the same seeds always give the same order
+	first := GenerateDefaultDeck()
+	fairShuffle(first, serverSeed, "player-seed")
+	second := GenerateDefaultDeck()
+	fairShuffle(second, serverSeed, "player-seed")
+
+	assert.Equal(t, first, second)
+	assert.NotEqual(t, GenerateDefaultDeck(), first)
+	assert.ElementsMatch(t, GenerateDefaultDeck(), first)
+
+	// Test case: the client seed changes the order
+	other := GenerateDefaultDeck()
+	fairShuffle(other, serverSeed, "another-seed")
+
+	assert.NotEqual(t, first, other)
+	assert.ElementsMatch(t, first, other)
+}
+
+func TestFairStreamIntn(t *testing.T) {
+	// Test case: numbers stay within bounds, including ranges dividing 2^32
+	stream := newFairStream("server", "client")
+	for _, n := range []int{1, 2, 3, 52, 256, 1000} {
+		for i := 0; i < 100; i++ {
+			value := stream.Intn(n)
+			assert.True(t, value >= 0 && value < n)
+		}
+	}
+}
+
+func TestNewServerSeed(t *testing.T) {
+	// Test case: seeds are random 32 byte hex strings
+	first, err := newServerSeed(NewCryptoSource())
+	assert.NoError(t, err)
+	second, err := newServerSeed(NewCryptoSource())
+	assert.NoError(t, err)
+
+	assert.Len(t, first, 64)
+	assert.NotEqual(t, first, second)
+}
+
+func TestHashSeed(t *testing.T) {
+	// Test case: the commitment is the hex SHA-256 of the seed
+	assert.Equal(t, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", hashSeed("abc"))
+
+	// Test case: decks without a seed have no commitment
+	assert.Equal(t, "", hashSeed(""))
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	customErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/golang/glog"
+	"net/http"
+)
+
+// dealer deals the cards of a game from the top of its deck with the deck service, like any other draw from the deck.
+// Every draw is saved on the deck right away, so the dealer remembers the cards it dealt to give them back when the
+// game can't be saved.
+type dealer struct {
+	decks  DeckService
+	deckId string
+	dealt  []string
+}
+
+func newDealer(decks DeckService, deckId string) *dealer {
+	return &dealer{decks: decks, deckId: deckId}
+}
+
+func (d *dealer) draw(count int) ([]string, error) {
+	cards, err := d.decks.DrawCards(d.deckId, count)
+	if err != nil {
+		return nil, err
+	}
+	codes := toCodes(cards)
+	d.dealt = append(d.dealt, codes...)
+	return codes, nil
+}
+
+// giveBack puts the dealt cards back on top of the deck in their order, as if they were never drawn, and returns err,
+// the reason the game couldn't go on
+func (d *dealer) giveBack(err error) error {
+	if len(d.dealt) == 0 {
+		return err
+	}
+	_, returnErr := d.decks.ReturnCards(d.deckId, model.ReturnCardsRequest{Cards: d.dealt, Position: model.Top})
+	if returnErr != nil {
+		glog.Errorf("couldn't give back cards %v to deck with id %s: %s", d.dealt, d.deckId, returnErr)
+	} else {
+		d.dealt = nil
+	}
+	return err
+}
+
+func toCodes(cards []model.Card) []string {
+	codes := make([]string, len(cards))
+	for i, c := range cards {
+		codes[i] = c.Code
+	}
+	return codes
+}
+
+// toGameError keeps the errors of the deck service, like an empty shoe, turns the errors of the game telling it can't
+// go on, like a finished game, into conflicts and rule violations into bad requests
+func toGameError(err error, conflicts ...error) error {
+	if _, ok := err.(*customErr.Error); ok {
+		return err
+	}
+	for _, conflict := range conflicts {
+		if err == conflict {
+			return customErr.Wrap(http.StatusConflict, err.Error(), err)
+		}
+	}
+	return customErr.Wrap(http.StatusBadRequest, err.Error(), err)
+}
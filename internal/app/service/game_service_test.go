@@ -0,0 +1,76 @@
+package service
+
+import (
+	"github.com/deck/internal/app/repo"
+	"github.com/deck/internal/app/repo/repotest"
+	"testing"
+)
+
+// MockGameRepo is the in-memory game repository of the service tests, GameError makes its methods fail
+type MockGameRepo struct {
+	repo.GameRepo
+	GameError error
+}
+
+func (m *MockGameRepo) CreateGame(game repo.Game) error {
+	if m.GameError != nil {
+		return m.GameError
+	}
+	return m.GameRepo.CreateGame(game)
+}
+
+func (m *MockGameRepo) GetGameById(id string) (*repo.Game, error) {
+	if m.GameError != nil {
+		return nil, m.GameError
+	}
+	return m.GameRepo.GetGameById(id)
+}
+
+func (m *MockGameRepo) GetGamesByDeckId(deckId string) ([]repo.Game, error) {
+	if m.GameError != nil {
+		return nil, m.GameError
+	}
+	return m.GameRepo.GetGamesByDeckId(deckId)
+}
+
+func (m *MockGameRepo) UpdateGame(game repo.Game) error {
+	if m.GameError != nil {
+		return m.GameError
+	}
+	return m.GameRepo.UpdateGame(game)
+}
+
+// game returns the stored game, an empty one if there is none
+func (m *MockGameRepo) game(id string) repo.Game {
+	game, err := m.GameRepo.GetGameById(id)
+	if err != nil {
+		return repo.Game{}
+	}
+	return *game
+}
+
+// conflictingGameRepo simulates another request updating the game between reading and saving it
+type conflictingGameRepo struct {
+	*MockGameRepo
+}
+
+func (m *conflictingGameRepo) GetGameById(id string) (*repo.Game, error) {
+	game, err := m.MockGameRepo.GetGameById(id)
+	if err != nil {
+		return nil, err
+	}
+	game.Version--
+	return game, nil
+}
+
+// newGameDecks returns the deck service the games deal from, with the repositories behind it
+func newGameDecks() (DeckService, *MockRepo, *MockGameRepo) {
+	mockRepo, gameRepo := newMockRepos()
+	return NewDeckService(mockRepo, NewCryptoSource()), mockRepo, gameRepo
+}
+
+func TestMockGameRepoContract(t *testing.T) {
+	repotest.GameRepoContract(t, func(t *testing.T) (repo.DeckRepo, repo.GameRepo) {
+		return newMockRepos()
+	})
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	customErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/repo"
+	"net/http"
+)
+
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+// GetDeckHistory returns up to limit events of the deck numbered after the given number, oldest first
+func (s *deckService) GetDeckHistory(id string, after, limit int) (*model.DeckHistoryResponse, error) {
+	if limit == 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit < 1 || limit > maxHistoryLimit {
+		return nil, customErr.New(http.StatusBadRequest, fmt.Sprintf("limit must be between 1 - %d", maxHistoryLimit))
+	}
+	if after < 0 {
+		return nil, customErr.New(http.StatusBadRequest, "after can't be negative")
+	}
+	deck, err := s.getDeck(id)
+	if err != nil {
+		return nil, err
+	}
+	// one more event than asked for tells if there's a next page
+	events, err := s.repo.GetDeckEvents(deck.Id, after, limit+1)
+	if err != nil {
+		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't get deck history from the database", err)
+	}
+	res := &model.DeckHistoryResponse{DeckId: deck.Id, Events: []model.DeckEventResponse{}}
+	if len(events) > limit {
+		events = events[:limit]
+		res.NextAfter = events[limit-1].Number
+	}
+	for _, e := range events {
+		res.Events = append(res.Events, toDeckEventResponse(e))
+	}
+	return res, nil
+}
+
+// GetDeckAt rebuilds the deck as it was right after the event with the given number
+func (s *deckService) GetDeckAt(id string, number int) (*model.DeckStateResponse, error) {
+	deck, err := s.getDeck(id)
+	if err != nil {
+		return nil, err
+	}
+	if number < 1 || number > deck.Version+1 {
+		return nil, customErr.New(http.StatusNotFound, fmt.Sprintf("event %d of deck %s wasn't found", number, id))
+	}
+	events, err := s.getEvents(*deck, number)
+	if err != nil {
+		return nil, err
+	}
+	state, err := replay(*deck, events)
+	if err != nil {
+		return nil, err
+	}
+	return toDeckStateResponse(state, events[len(events)-1], s.aceHigh)
+}
+
+// getEvents returns the events of the deck up to the given number
+func (s *deckService) getEvents(deck repo.Deck, number int) ([]repo.DeckEvent, error) {
+	events, err := s.repo.GetDeckEvents(deck.Id, 0, number)
+	if err != nil {
+		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't get deck history from the database", err)
+	}
+	// events are numbered without gaps, those up to number are among the first number ones
+	for len(events) > 0 && events[len(events)-1].Number > number {
+		events = events[:len(events)-1]
+	}
+	if len(events) == 0 || events[len(events)-1].Number != number {
+		// decks created before their history was recorded start with an import of their state back then
+		return nil, customErr.New(http.StatusNotFound, fmt.Sprintf("event %d of deck %s wasn't recorded", number, deck.Id))
+	}
+	return events, nil
+}
+
+// replay rebuilds the deck after the last of the events by applying the changes of each of them in order. Settings
+// of the deck never change, only its cards are replayed.
+func replay(deck repo.Deck, events []repo.DeckEvent) (repo.Deck, error) {
+	state := deck
+	state.Version = events[len(events)-1].Number - 1
+	for _, e := range events {
+		if e.Type.StartsHistory() {
+			state.Cards, state.Drawn, state.Discarded, state.Piles = nil, nil, nil, nil
+			state.Remaining, state.Size, state.Shuffled, state.ShuffledAt = 0, 0, false, nil
+		}
+		var changes repo.DeckChanges
+		if err := json.Unmarshal([]byte(e.Changes), &changes); err != nil {
+			return state, customErr.Wrap(http.StatusInternalServerError, "couldn't decode deck event", err)
+		}
+		changes.Apply(&state, e.CreatedAt)
+	}
+	return state, nil
+}
+
+// restoreState puts the cards of state back into the deck and its piles. Piles the state doesn't have were created
+// later, they are emptied.
+func restoreState(deck repo.Deck, state repo.Deck) repo.Deck {
+	deck.Cards, deck.Drawn, deck.Discarded = state.Cards, state.Drawn, state.Discarded
+	deck.Remaining, deck.Size = state.Remaining, state.Size
+	deck.Shuffled, deck.ShuffledAt = state.Shuffled, state.ShuffledAt
+	deck.Piles = append([]repo.Pile{}, deck.Piles...)
+	for i, p := range deck.Piles {
+		deck.Piles[i].Cards = nil
+		if restored := findPile(&state, p.Name); restored != nil {
+			deck.Piles[i].Cards = restored.Cards
+		}
+	}
+	return deck
+}
+
+func toDeckStateResponse(deck repo.Deck, event repo.DeckEvent, aceHigh bool) (*model.DeckStateResponse, error) {
+	open, err := toOpenDeckResponse(deck, aceHigh)
+	if err != nil {
+		return nil, err
+	}
+	piles := make([]model.PileResponse, len(deck.Piles))
+	for i, p := range deck.Piles {
+		pile, err := toPileResponse(p, aceHigh)
+		if err != nil {
+			return nil, err
+		}
+		piles[i] = *pile
+	}
+	return &model.DeckStateResponse{Event: toDeckEventResponse(event), Deck: *open, Piles: piles}, nil
+}
+
+func toDeckEventResponse(event repo.DeckEvent) model.DeckEventResponse {
+	return model.DeckEventResponse{
+		Number:    event.Number,
+		Type:      string(event.Type),
+		Actor:     event.Actor,
+		Payload:   json.RawMessage(event.Payload),
+		CreatedAt: event.CreatedAt,
+	}
+}
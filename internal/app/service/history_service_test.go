@@ -0,0 +1,142 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	customErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/repo"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDeckHistory(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewSeededSource("history"))
+	alice := deckService.WithActor("alice")
+
+	created, err := alice.CreateDeck(model.CreateDeckRequest{Shuffled: true, Seed: "history"})
+	assert.NoError(t, err)
+	id := created.DeckId
+	var states []*model.OpenDeckResponse
+	var piles [][]string
+	record := func() {
+		deck, err := deckService.GetDeckById(id)
+		assert.NoError(t, err)
+		states = append(states, deck)
+		pile, err := deckService.GetPile(id, "hand")
+		if err != nil {
+			piles = append(piles, nil)
+			return
+		}
+		piles = append(piles, cardCodes(pile.Cards))
+	}
+	record()
+	_, err = alice.DrawCards(id, 3)
+	assert.NoError(t, err)
+	record()
+	_, err = deckService.WithActor("bob").CutDeck(id, 10)
+	assert.NoError(t, err)
+	record()
+	_, err = deckService.CreatePile(id, "hand")
+	assert.NoError(t, err)
+	record()
+	_, err = deckService.MoveCards(id, "hand", model.MoveCardsRequest{Count: 2})
+	assert.NoError(t, err)
+	record()
+	_, err = alice.ShuffleDeck(id, true)
+	assert.NoError(t, err)
+	record()
+
+	// Test case: every operation is recorded in order with its actor
+	history, err := deckService.GetDeckHistory(id, 0, 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, history.Events, 6)
+	var types, actors []string
+	for i, e := range history.Events {
+		assert.Equal(t, i+1, e.Number)
+		types = append(types, e.Type)
+		actors = append(actors, e.Actor)
+	}
+	assert.Equal(t, []string{"create", "draw", "cut", "create_pile", "move_cards", "shuffle"}, types)
+	assert.Equal(t, []string{"alice", "alice", "bob", "", "", "alice"}, actors)
+	var draw struct {
+		Count int      `json:"count"`
+		Cards []string `json:"cards"`
+	}
+	assert.NoError(t, json.Unmarshal(history.Events[1].Payload, &draw))
+	assert.Equal(t, 3, draw.Count)
+	assert.Equal(t, cardCodes(states[1].Drawn), draw.Cards)
+	assert.Zero(t, history.NextAfter)
+
+	// Test case: the history is paged
+	history, err = deckService.GetDeckHistory(id, 2, 3)
+
+	assert.NoError(t, err)
+	assert.Len(t, history.Events, 3)
+	assert.Equal(t, 3, history.Events[0].Number)
+	assert.Equal(t, 5, history.NextAfter)
+
+	// Test case: replaying the events rebuilds the deck after each of them
+	for i, expected := range states {
+		state, err := deckService.GetDeckAt(id, i+1)
+
+		assert.NoError(t, err, fmt.Sprintf("event %d", i+1))
+		assert.Equal(t, i+1, state.Event.Number)
+		assert.Equal(t, cardCodes(expected.Cards), cardCodes(state.Deck.Cards), fmt.Sprintf("event %d", i+1))
+		assert.Equal(t, cardCodes(expected.Drawn), cardCodes(state.Deck.Drawn), fmt.Sprintf("event %d", i+1))
+		assert.Equal(t, expected.Remaining, state.Deck.Remaining)
+		if piles[i] == nil {
+			assert.Empty(t, state.Piles)
+		} else {
+			assert.Equal(t, piles[i], cardCodes(state.Piles[0].Cards))
+		}
+	}
+
+	// Test case: events which don't exist
+	for _, number := range []int{0, 7} {
+		state, err := deckService.GetDeckAt(id, number)
+
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusNotFound, err.(*customErr.Error).Kind())
+		assert.Nil(t, state)
+	}
+
+	// Test case: invalid pages
+	_, err = deckService.GetDeckHistory(id, -1, 0)
+	assert.EqualError(t, err, "after can't be negative")
+	_, err = deckService.GetDeckHistory(id, 0, 1001)
+	assert.EqualError(t, err, "limit must be between 1 - 1000")
+}
+
+func TestDeckHistoryImport(t *testing.T) {
+	now := time.Now().UTC()
+	mockRepo := newMockRepo()
+	// the deck is imported at its version like the migration does, the event holds its whole state
+	err := mockRepo.CreateDeck(repo.Deck{Id: "old", Version: 4, Remaining: 3, Cards: []string{"AS", "KD", "QH"}, Size: 3,
+		CreatedAt: now, UpdatedAt: now}, repo.DeckEvent{Type: repo.ImportEvent})
+	assert.NoError(t, err)
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+	_, err = deckService.DrawCards("old", 1)
+	assert.NoError(t, err)
+
+	// Test case: the history of a deck created before it was recorded starts with its imported state
+	state, err := deckService.GetDeckAt("old", 5)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"AS", "KD", "QH"}, cardCodes(state.Deck.Cards))
+	state, err = deckService.GetDeckAt("old", 6)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"KD", "QH"}, cardCodes(state.Deck.Cards))
+	assert.Equal(t, []string{"AS"}, cardCodes(state.Deck.Drawn))
+
+	// Test case: events before the import weren't recorded
+	state, err = deckService.GetDeckAt("old", 4)
+
+	assert.EqualError(t, err, "event 4 of deck old wasn't recorded")
+	assert.Nil(t, state)
+}
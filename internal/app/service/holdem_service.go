@@ -0,0 +1,301 @@
+package service
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	customErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/holdem"
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/repo"
+	"github.com/golang/glog"
+	"github.com/google/uuid"
+	"net/http"
+	"time"
+)
+
+type HoldemService interface {
+	CreateTable(req model.CreateHoldemRequest) (*model.HoldemTableResponse, error)
+	GetTable(id string, token string) (*model.HoldemTableResponse, error)
+	StartHand(id string, token string) (*model.HoldemTableResponse, error)
+	Act(id string, req model.HoldemActionRequest) (*model.HoldemTableResponse, error)
+}
+
+type holdemService struct {
+	repo  repo.GameRepo
+	decks DeckService
+}
+
+func NewHoldemService(repo repo.GameRepo, decks DeckService) HoldemService {
+	return &holdemService{repo: repo, decks: decks}
+}
+
+// CreateTable seats the players and deals the first hand. Every player gets a token to show the table and act with,
+// only its hash is kept so the tokens are handed out just this once.
+func (s *holdemService) CreateTable(req model.CreateHoldemRequest) (*model.HoldemTableResponse, error) {
+	players := make([]holdem.Player, len(req.Players))
+	tokens := make(map[string]string, len(req.Players))
+	for i, p := range req.Players {
+		tokens[p.Name] = uuid.New().String()
+		players[i] = holdem.Player{Name: p.Name, Stack: p.Stack, TokenHash: hashToken(tokens[p.Name])}
+	}
+	table, err := holdem.NewTable(req.SmallBlind, req.BigBlind, players)
+	if err != nil {
+		return nil, toGameError(err, holdem.ErrNoHand)
+	}
+	// the table is the actor of the operations on the decks of its hands
+	id := uuid.New().String()
+	deckId, err := s.dealHand(s.decks.WithActor(id), table)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := json.Marshal(table)
+	if err != nil {
+		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't encode table", err)
+	}
+	now := time.Now().UTC()
+	game := repo.Game{
+		Id:        id,
+		GameType:  repo.HoldemGame,
+		DeckId:    deckId,
+		State:     string(state),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err = s.repo.CreateGame(game); err != nil {
+		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't save table", err)
+	}
+	res, err := toHoldemResponse(game, table, "")
+	if err != nil {
+		return nil, err
+	}
+	res.Tokens = tokens
+	return res, nil
+}
+
+// GetTable shows the table to the player of the token, without a token no hole cards are shown before the showdown
+func (s *holdemService) GetTable(id string, token string) (*model.HoldemTableResponse, error) {
+	game, table, err := s.getTable(id)
+	if err != nil {
+		return nil, err
+	}
+	player, err := playerOf(table, token)
+	if err != nil {
+		return nil, err
+	}
+	return toHoldemResponse(*game, table, player)
+}
+
+// StartHand deals the next hand from a new deck once the last one is finished, for a player at the table only. The
+// last hand's deck is deleted then, as it would show the cards folded and never dealt once no table deals from it.
+func (s *holdemService) StartHand(id string, token string) (*model.HoldemTableResponse, error) {
+	game, table, err := s.getTable(id)
+	if err != nil {
+		return nil, err
+	}
+	player, err := playerOf(table, token)
+	if err != nil {
+		return nil, err
+	}
+	if len(player) == 0 {
+		return nil, customErr.New(http.StatusForbidden, "starting a hand needs the token of a player at the table")
+	}
+	if table.Hand != nil && !table.Hand.Finished {
+		return nil, customErr.New(http.StatusConflict, fmt.Sprintf("hand %d isn't finished yet", table.Hand.Number))
+	}
+	lastDeckId := game.DeckId
+	if game.DeckId, err = s.dealHand(s.decks.WithActor(game.Id), table); err != nil {
+		return nil, err
+	}
+	res, err := s.updateTable(game, table, player)
+	if err != nil {
+		s.deleteDeck(game.DeckId)
+		return nil, err
+	}
+	s.deleteDeck(lastDeckId)
+	return res, nil
+}
+
+// Act plays the move of the player the token belongs to, the next streets are dealt from the hand's deck when a
+// betting round is over. The cards dealt go back to the deck when the table can't be saved.
+func (s *holdemService) Act(id string, req model.HoldemActionRequest) (*model.HoldemTableResponse, error) {
+	game, table, err := s.getTable(id)
+	if err != nil {
+		return nil, err
+	}
+	player, err := playerOf(table, req.Token)
+	if err != nil {
+		return nil, err
+	}
+	if len(player) == 0 || player != req.Player {
+		return nil, customErr.New(http.StatusForbidden, fmt.Sprintf("acting for %s needs their token", req.Player))
+	}
+	deal := newDealer(s.decks.WithActor(game.Id), game.DeckId)
+	err = table.Act(req.Player, holdem.Action(req.Action), req.Amount, deal.draw)
+	if err != nil {
+		return nil, deal.giveBack(toGameError(err, holdem.ErrNoHand))
+	}
+	res, err := s.updateTable(game, table, req.Player)
+	if err != nil {
+		return nil, deal.giveBack(err)
+	}
+	return res, nil
+}
+
+// dealHand starts the next hand of the table with a freshly shuffled deck and returns the deck's id
+func (s *holdemService) dealHand(decks DeckService, table *holdem.Table) (string, error) {
+	deck, err := decks.CreateDeck(model.CreateDeckRequest{Shuffled: true})
+	if err != nil {
+		return "", err
+	}
+	if err = table.StartHand(deck.DeckId, newDealer(decks, deck.DeckId).draw); err != nil {
+		return "", toGameError(err, holdem.ErrNoHand)
+	}
+	return deck.DeckId, nil
+}
+
+// deleteDeck deletes a deck no hand is dealt from anymore, the table is fine when it fails
+func (s *holdemService) deleteDeck(deckId string) {
+	if err := s.decks.DeleteDeck(deckId); err != nil {
+		glog.Errorf("couldn't delete deck with id %s: %s", deckId, err)
+	}
+}
+
+// playerOf returns the player of the table the token belongs to, nobody without a token
+func playerOf(table *holdem.Table, token string) (string, error) {
+	if len(token) == 0 {
+		return "", nil
+	}
+	hash := hashToken(token)
+	for _, p := range table.Players {
+		if subtle.ConstantTimeCompare([]byte(p.TokenHash), []byte(hash)) == 1 {
+			return p.Name, nil
+		}
+	}
+	return "", customErr.New(http.StatusForbidden, "the token doesn't belong to a player at the table")
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *holdemService) getTable(id string) (*repo.Game, *holdem.Table, error) {
+	game, err := s.repo.GetGameById(id)
+	if err == sql.ErrNoRows {
+		return nil, nil, customErr.New(http.StatusNotFound, fmt.Sprintf("table with id %s wasn't found", id))
+	}
+	if err != nil {
+		return nil, nil, customErr.Wrap(http.StatusInternalServerError, "couldn't get table from the database", err)
+	}
+	if game.GameType != repo.HoldemGame {
+		return nil, nil, customErr.New(http.StatusNotFound, fmt.Sprintf("hold'em table with id %s wasn't found", id))
+	}
+	var table holdem.Table
+	if err = json.Unmarshal([]byte(game.State), &table); err != nil {
+		return nil, nil, customErr.Wrap(http.StatusInternalServerError, "couldn't decode table", err)
+	}
+	return game, &table, nil
+}
+
+func (s *holdemService) updateTable(game *repo.Game, table *holdem.Table, player string) (*model.HoldemTableResponse, error) {
+	state, err := json.Marshal(table)
+	if err != nil {
+		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't encode table", err)
+	}
+	game.State = string(state)
+	err = s.repo.UpdateGame(*game)
+	if err == repo.ErrVersionConflict {
+		return nil, customErr.Wrap(http.StatusConflict, "table was modified by another request, please retry", err)
+	}
+	if err != nil {
+		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't update table", err)
+	}
+	game.Version++
+	return toHoldemResponse(*game, table, player)
+}
+
+func toHoldemResponse(game repo.Game, table *holdem.Table, player string) (*model.HoldemTableResponse, error) {
+	players := make([]model.HoldemPlayer, len(table.Players))
+	for i, p := range table.Players {
+		players[i] = model.HoldemPlayer{Name: p.Name, Stack: p.Stack}
+	}
+	h := table.Hand
+	board, err := toCards(h.Board)
+	if err != nil {
+		return nil, err
+	}
+
+	pot := 0
+	seats := make([]model.HoldemSeatResponse, len(h.Seats))
+	for i, seat := range h.Seats {
+		seats[i] = model.HoldemSeatResponse{
+			Name:      seat.Name,
+			Stack:     table.Players[seat.Player].Stack,
+			Bet:       seat.Bet,
+			Committed: seat.Committed,
+			Folded:    seat.Folded,
+			AllIn:     seat.AllIn,
+		}
+		// everybody sees the cards of the players who went to showdown
+		if seat.Name == player || (h.Street == holdem.Showdown && !seat.Folded) {
+			if seats[i].Cards, err = toCards(seat.Cards); err != nil {
+				return nil, err
+			}
+		}
+		pot += seat.Committed
+	}
+
+	pots := make([]model.HoldemPotResponse, len(h.Pots))
+	for i, p := range h.Pots {
+		pots[i] = model.HoldemPotResponse{Amount: p.Amount, Eligible: p.Eligible, Winners: p.Winners, Hand: p.Hand}
+	}
+	history := make([]model.HoldemEventResponse, len(table.History))
+	for i, e := range table.History {
+		history[i] = model.HoldemEventResponse{
+			Hand:   e.Hand,
+			Street: string(e.Street),
+			Player: e.Player,
+			Action: e.Action,
+			Amount: e.Amount,
+		}
+		if len(e.Cards) > 0 {
+			if history[i].Cards, err = toCards(e.Cards); err != nil {
+				return nil, err
+			}
+		}
+	}
+	actions := make([]string, 0)
+	for _, a := range table.Actions() {
+		actions = append(actions, string(a))
+	}
+	toAct := ""
+	if !h.Finished {
+		toAct = h.Seats[h.ToAct].Name
+	}
+
+	return &model.HoldemTableResponse{
+		TableId:    game.Id,
+		DeckId:     game.DeckId,
+		SmallBlind: table.SmallBlind,
+		BigBlind:   table.BigBlind,
+		Players:    players,
+		Button:     table.Players[table.Button].Name,
+		HandNumber: h.Number,
+		Street:     string(h.Street),
+		Board:      board,
+		Seats:      seats,
+		ToAct:      toAct,
+		CurrentBet: h.CurrentBet,
+		MinRaiseTo: h.CurrentBet + h.MinRaise,
+		Pot:        pot,
+		Pots:       pots,
+		Finished:   h.Finished,
+		Actions:    actions,
+		History:    history,
+	}, nil
+}
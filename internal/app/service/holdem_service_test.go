@@ -0,0 +1,230 @@
+package service
+
+import (
+	"database/sql"
+	customErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/repo"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+var headsUp = model.CreateHoldemRequest{
+	SmallBlind: 1,
+	BigBlind:   2,
+	Players:    []model.HoldemPlayer{{Name: "alice", Stack: 100}, {Name: "bob", Stack: 100}},
+}
+
+// move is the player's action, made with the token they got when the table was created
+func move(tokens map[string]string, player, action string, amount int) model.HoldemActionRequest {
+	return model.HoldemActionRequest{Player: player, Token: tokens[player], Action: action, Amount: amount}
+}
+
+func TestCreateHoldemTable(t *testing.T) {
+	decks, mockRepo, gameRepo := newGameDecks()
+	holdemService := NewHoldemService(gameRepo, decks)
+
+	// Test case: the first hand is dealt from a new shuffled deck
+	table, err := holdemService.CreateTable(headsUp)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, table.HandNumber)
+	assert.Equal(t, "preflop", table.Street)
+	assert.Equal(t, "alice", table.Button)
+	assert.Equal(t, "alice", table.ToAct)
+	assert.Equal(t, []string{"fold", "call", "raise", "all_in"}, table.Actions)
+	assert.Equal(t, 3, table.Pot)
+	assert.Equal(t, 4, table.MinRaiseTo)
+	assert.Equal(t, []model.HoldemPlayer{{Name: "alice", Stack: 99}, {Name: "bob", Stack: 98}}, table.Players)
+	deck := mockRepo.deck(table.DeckId)
+	assert.True(t, deck.Shuffled)
+	assert.Equal(t, 48, deck.Remaining)
+	assert.Equal(t, repo.HoldemGame, gameRepo.game(table.TableId).GameType)
+
+	// Test case: hole cards are hidden from the other players
+	for _, seat := range table.Seats {
+		assert.Empty(t, seat.Cards)
+	}
+	view, err := holdemService.GetTable(table.TableId, table.Tokens["bob"])
+
+	assert.NoError(t, err)
+	assert.Empty(t, view.Seats[1].Cards)
+	assert.Len(t, view.Seats[0].Cards, 2)
+	assert.Equal(t, "bob", view.Seats[0].Name)
+	assert.Empty(t, view.Tokens)
+
+	// Test case: the tokens are handed out once and only their hashes are stored
+	assert.Len(t, table.Tokens, 2)
+	assert.NotEqual(t, table.Tokens["alice"], table.Tokens["bob"])
+	assert.NotContains(t, gameRepo.game(table.TableId).State, table.Tokens["alice"])
+
+	// Test case: an unknown token is refused
+	view, err = holdemService.GetTable(table.TableId, "guessed")
+
+	assert.EqualError(t, err, "the token doesn't belong to a player at the table")
+	assert.Equal(t, http.StatusForbidden, err.(*customErr.Error).Kind())
+	assert.Nil(t, view)
+
+	// Test case: invalid table
+	table, err = holdemService.CreateTable(model.CreateHoldemRequest{SmallBlind: 1, BigBlind: 2, Players: headsUp.Players[:1]})
+
+	assert.EqualError(t, err, "a table needs 2 - 10 players")
+	assert.Equal(t, http.StatusBadRequest, err.(*customErr.Error).Kind())
+	assert.Nil(t, table)
+
+	// Test case: a blackjack game isn't a table
+	assert.NoError(t, gameRepo.CreateGame(repo.Game{Id: "blackjack", GameType: repo.BlackjackGame, DeckId: deck.Id}))
+	table, err = holdemService.GetTable("blackjack", "")
+
+	assert.EqualError(t, err, "hold'em table with id blackjack wasn't found")
+	assert.Nil(t, table)
+
+	// Test case: unknown table
+	table, err = holdemService.GetTable("unknown", "")
+
+	assert.EqualError(t, err, "table with id unknown wasn't found")
+	assert.Nil(t, table)
+}
+
+func TestHoldemAct(t *testing.T) {
+	decks, mockRepo, gameRepo := newGameDecks()
+	holdemService := NewHoldemService(gameRepo, decks)
+	table, err := holdemService.CreateTable(headsUp)
+	assert.NoError(t, err)
+	tokens := table.Tokens
+	id := table.TableId
+
+	// Test case: the betting round ends and the flop is dealt after a burn card
+	_, err = holdemService.Act(id, move(tokens, "alice", "call", 0))
+	assert.NoError(t, err)
+	table, err = holdemService.Act(id, move(tokens, "bob", "check", 0))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "flop", table.Street)
+	assert.Len(t, table.Board, 3)
+	assert.Equal(t, "bob", table.ToAct)
+	assert.Equal(t, 44, mockRepo.deck(table.DeckId).Remaining)
+	assert.Equal(t, 2, gameRepo.game(id).Version)
+
+	// Test case: invalid actions
+	table, err = holdemService.Act(id, move(tokens, "alice", "check", 0))
+
+	assert.EqualError(t, err, "it's bob's turn")
+	assert.Equal(t, http.StatusBadRequest, err.(*customErr.Error).Kind())
+	assert.Nil(t, table)
+
+	// Test case: a player can't act for another one
+	table, err = holdemService.Act(id, model.HoldemActionRequest{Player: "bob", Token: tokens["alice"], Action: "check"})
+
+	assert.EqualError(t, err, "acting for bob needs their token")
+	assert.Equal(t, http.StatusForbidden, err.(*customErr.Error).Kind())
+	assert.Nil(t, table)
+	table, err = holdemService.Act(id, model.HoldemActionRequest{Player: "bob", Action: "check"})
+
+	assert.EqualError(t, err, "acting for bob needs their token")
+	assert.Nil(t, table)
+
+	// Test case: the next hand can't start before this one is finished
+	table, err = holdemService.StartHand(id, tokens["bob"])
+
+	assert.EqualError(t, err, "hand 1 isn't finished yet")
+	assert.Equal(t, http.StatusConflict, err.(*customErr.Error).Kind())
+	assert.Nil(t, table)
+
+	// Test case: a bet and a fold end the hand
+	_, err = holdemService.Act(id, move(tokens, "bob", "raise", 10))
+	assert.NoError(t, err)
+	table, err = holdemService.Act(id, move(tokens, "alice", "fold", 0))
+
+	assert.NoError(t, err)
+	assert.True(t, table.Finished)
+	assert.Empty(t, table.ToAct)
+	assert.Empty(t, table.Actions)
+	assert.Equal(t, []model.HoldemPotResponse{{Amount: 4, Eligible: []string{"bob"}, Winners: []string{"bob"}}}, table.Pots)
+	assert.Equal(t, []model.HoldemPlayer{{Name: "alice", Stack: 98}, {Name: "bob", Stack: 102}}, table.Players)
+	// nobody shows their cards after a fold
+	assert.Empty(t, table.Seats[0].Cards)
+	last := table.History[len(table.History)-1]
+	assert.Equal(t, model.HoldemEventResponse{Hand: 1, Street: "flop", Player: "bob", Action: "win", Amount: 4}, last)
+
+	// Test case: the game is over
+	table, err = holdemService.Act(id, move(tokens, "bob", "check", 0))
+
+	assert.EqualError(t, err, "no hand is being played")
+	assert.Equal(t, http.StatusConflict, err.(*customErr.Error).Kind())
+	assert.Nil(t, table)
+
+	// Test case: only a player at the table starts the next hand
+	firstDeck := gameRepo.game(id).DeckId
+	table, err = holdemService.StartHand(id, "")
+
+	assert.EqualError(t, err, "starting a hand needs the token of a player at the table")
+	assert.Equal(t, http.StatusForbidden, err.(*customErr.Error).Kind())
+	assert.Nil(t, table)
+	table, err = holdemService.StartHand(id, "not-a-token")
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, err.(*customErr.Error).Kind())
+	assert.Nil(t, table)
+	assert.Equal(t, firstDeck, gameRepo.game(id).DeckId)
+
+	// Test case: the next hand is dealt from a new deck and the button moves
+	table, err = holdemService.StartHand(id, tokens["alice"])
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, table.HandNumber)
+	assert.Equal(t, "bob", table.Button)
+	assert.NotEqual(t, firstDeck, table.DeckId)
+	assert.Equal(t, table.DeckId, gameRepo.game(id).DeckId)
+	assert.Equal(t, 48, mockRepo.deck(table.DeckId).Remaining)
+	assert.Len(t, table.Seats[0].Cards, 2)
+	assert.Equal(t, "alice", table.Seats[0].Name)
+	_, err = mockRepo.GetDeckById(firstDeck)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func TestHoldemShowdown(t *testing.T) {
+	decks, _, gameRepo := newGameDecks()
+	holdemService := NewHoldemService(gameRepo, decks)
+	table, err := holdemService.CreateTable(headsUp)
+	assert.NoError(t, err)
+	tokens := table.Tokens
+
+	// Test case: both players go all in, the board is run out and the cards are shown
+	_, err = holdemService.Act(table.TableId, move(tokens, "alice", "all_in", 0))
+	assert.NoError(t, err)
+	table, err = holdemService.Act(table.TableId, move(tokens, "bob", "call", 0))
+
+	assert.NoError(t, err)
+	assert.True(t, table.Finished)
+	assert.Equal(t, "showdown", table.Street)
+	assert.Len(t, table.Board, 5)
+	assert.Len(t, table.Seats[0].Cards, 2)
+	assert.Len(t, table.Seats[1].Cards, 2)
+	assert.Equal(t, 200, table.Pots[0].Amount)
+	assert.NotEmpty(t, table.Pots[0].Hand)
+	assert.Equal(t, 200, table.Players[0].Stack+table.Players[1].Stack)
+}
+
+func TestHoldemActConcurrently(t *testing.T) {
+	decks, mockRepo, gameRepo := newGameDecks()
+	holdemService := NewHoldemService(gameRepo, decks)
+	table, err := holdemService.CreateTable(headsUp)
+	assert.NoError(t, err)
+	tokens := table.Tokens
+	_, err = holdemService.Act(table.TableId, move(tokens, "alice", "call", 0))
+	assert.NoError(t, err)
+	upcoming := mockRepo.deck(table.DeckId).Cards
+
+	// Test case: another request changed the table meanwhile, the flop dealt goes back to the deck
+	conflictRepo := &conflictingGameRepo{MockGameRepo: gameRepo}
+	res, err := NewHoldemService(conflictRepo, decks).
+		Act(table.TableId, move(tokens, "bob", "check", 0))
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, err.(*customErr.Error).Kind())
+	assert.Nil(t, res)
+	assert.Equal(t, 1, gameRepo.game(table.TableId).Version)
+	assert.Equal(t, upcoming, mockRepo.deck(table.DeckId).Cards)
+}
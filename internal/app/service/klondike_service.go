@@ -0,0 +1,184 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	customErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/klondike"
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/repo"
+	"github.com/google/uuid"
+	"net/http"
+	"time"
+)
+
+type KlondikeService interface {
+	CreateGame(req model.CreateKlondikeRequest) (*model.KlondikeGameResponse, error)
+	GetGame(id string) (*model.KlondikeGameResponse, error)
+	Draw(id string) (*model.KlondikeGameResponse, error)
+	Move(id string, req model.KlondikeMoveRequest) (*model.KlondikeGameResponse, error)
+	Undo(id string) (*model.KlondikeGameResponse, error)
+}
+
+type klondikeService struct {
+	repo  repo.GameRepo
+	decks DeckService
+}
+
+func NewKlondikeService(repo repo.GameRepo, decks DeckService) KlondikeService {
+	return &klondikeService{repo: repo, decks: decks}
+}
+
+// CreateGame shuffles a new deck and deals the whole of it into the layout
+func (s *klondikeService) CreateGame(req model.CreateKlondikeRequest) (*model.KlondikeGameResponse, error) {
+	drawCount := req.DrawCount
+	if drawCount == 0 {
+		drawCount = 1
+	}
+	if drawCount != 1 && drawCount != 3 {
+		return nil, customErr.New(http.StatusBadRequest, "draw count must be 1 or 3")
+	}
+	// the game is the actor of the operations on its deck
+	id := uuid.New().String()
+	decks := s.decks.WithActor(id)
+	deck, err := decks.CreateDeck(model.CreateDeckRequest{Shuffled: true, Seed: req.Seed})
+	if err != nil {
+		return nil, err
+	}
+	cards, err := newDealer(decks, deck.DeckId).draw(klondike.DeckSize)
+	if err != nil {
+		return nil, err
+	}
+	state, err := klondike.Deal(cards, drawCount)
+	if err != nil {
+		return nil, toGameError(err, klondike.ErrGameWon)
+	}
+
+	stateJson, err := json.Marshal(state)
+	if err != nil {
+		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't encode game", err)
+	}
+	now := time.Now().UTC()
+	game := repo.Game{
+		Id:        id,
+		GameType:  repo.KlondikeGame,
+		DeckId:    deck.DeckId,
+		State:     string(stateJson),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err = s.repo.CreateGame(game); err != nil {
+		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't save game", err)
+	}
+	return toKlondikeResponse(game, state)
+}
+
+func (s *klondikeService) GetGame(id string) (*model.KlondikeGameResponse, error) {
+	game, state, err := s.getGame(id)
+	if err != nil {
+		return nil, err
+	}
+	return toKlondikeResponse(*game, state)
+}
+
+func (s *klondikeService) Draw(id string) (*model.KlondikeGameResponse, error) {
+	return s.play(id, func(state *klondike.Game) error {
+		return state.Draw()
+	})
+}
+
+func (s *klondikeService) Move(id string, req model.KlondikeMoveRequest) (*model.KlondikeGameResponse, error) {
+	return s.play(id, func(state *klondike.Game) error {
+		return state.Move(req.From, req.To, req.Count)
+	})
+}
+
+func (s *klondikeService) Undo(id string) (*model.KlondikeGameResponse, error) {
+	return s.play(id, func(state *klondike.Game) error {
+		return state.Undo()
+	})
+}
+
+// play applies the move to the game and saves it, unless another request changed the game meanwhile
+func (s *klondikeService) play(id string, move func(state *klondike.Game) error) (*model.KlondikeGameResponse, error) {
+	game, state, err := s.getGame(id)
+	if err != nil {
+		return nil, err
+	}
+	if err = move(state); err != nil {
+		return nil, toGameError(err, klondike.ErrGameWon)
+	}
+	stateJson, err := json.Marshal(state)
+	if err != nil {
+		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't encode game", err)
+	}
+	game.State = string(stateJson)
+	err = s.repo.UpdateGame(*game)
+	if err == repo.ErrVersionConflict {
+		return nil, customErr.Wrap(http.StatusConflict, "game was modified by another request, please retry", err)
+	}
+	if err != nil {
+		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't update game", err)
+	}
+	game.Version++
+	return toKlondikeResponse(*game, state)
+}
+
+func (s *klondikeService) getGame(id string) (*repo.Game, *klondike.Game, error) {
+	game, err := s.repo.GetGameById(id)
+	if err == sql.ErrNoRows {
+		return nil, nil, customErr.New(http.StatusNotFound, fmt.Sprintf("game with id %s wasn't found", id))
+	}
+	if err != nil {
+		return nil, nil, customErr.Wrap(http.StatusInternalServerError, "couldn't get game from the database", err)
+	}
+	if game.GameType != repo.KlondikeGame {
+		return nil, nil, customErr.New(http.StatusNotFound, fmt.Sprintf("klondike game with id %s wasn't found", id))
+	}
+	var state klondike.Game
+	if err = json.Unmarshal([]byte(game.State), &state); err != nil {
+		return nil, nil, customErr.Wrap(http.StatusInternalServerError, "couldn't decode game", err)
+	}
+	return game, &state, nil
+}
+
+// toKlondikeResponse hides the face down cards of the tableau and the stock
+func toKlondikeResponse(game repo.Game, state *klondike.Game) (*model.KlondikeGameResponse, error) {
+	visible := state.Waste
+	if len(visible) > state.DrawCount {
+		visible = visible[len(visible)-state.DrawCount:]
+	}
+	waste, err := toCards(visible)
+	if err != nil {
+		return nil, err
+	}
+	foundations := make([][]model.Card, len(state.Foundations))
+	for i, f := range state.Foundations {
+		if foundations[i], err = toCards(f); err != nil {
+			return nil, err
+		}
+	}
+	tableau := make([]model.KlondikeColumnResponse, len(state.Tableau))
+	for i, column := range state.Tableau {
+		hidden := len(column.Cards) - column.FaceUp
+		cards, err := toCards(column.Cards[hidden:])
+		if err != nil {
+			return nil, err
+		}
+		tableau[i] = model.KlondikeColumnResponse{Hidden: hidden, Cards: cards}
+	}
+	return &model.KlondikeGameResponse{
+		GameId:      game.Id,
+		DeckId:      game.DeckId,
+		DrawCount:   state.DrawCount,
+		Stock:       len(state.Stock),
+		Waste:       waste,
+		WasteCount:  len(state.Waste),
+		Foundations: foundations,
+		Tableau:     tableau,
+		Moves:       state.Moves,
+		CanUndo:     len(state.History) > 0,
+		Won:         state.Won,
+	}, nil
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"encoding/json"
+	customErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/klondike"
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/repo"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+// saveKlondikeGame stores a game with the given layout on a deck of its own
+func saveKlondikeGame(mockRepo *MockRepo, gameRepo *MockGameRepo, id string, state klondike.Game) {
+	stateJson, _ := json.Marshal(state)
+	mockRepo.putDeck(repo.Deck{Id: id + "-deck"})
+	_ = gameRepo.CreateGame(repo.Game{Id: id, GameType: repo.KlondikeGame, DeckId: id + "-deck", State: string(stateJson)})
+}
+
+func TestCreateKlondikeGame(t *testing.T) {
+	decks, mockRepo, gameRepo := newGameDecks()
+	klondikeService := NewKlondikeService(gameRepo, decks)
+
+	// Test case: the whole deck is dealt, only the top card of each column is face up
+	game, err := klondikeService.CreateGame(model.CreateKlondikeRequest{DrawCount: 3, Seed: "replay"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, game.DrawCount)
+	assert.Equal(t, 24, game.Stock)
+	assert.Empty(t, game.Waste)
+	for i, column := range game.Tableau {
+		assert.Equal(t, i, column.Hidden)
+		assert.Len(t, column.Cards, 1)
+	}
+	assert.Len(t, game.Foundations, 4)
+	assert.False(t, game.CanUndo)
+	assert.Equal(t, 0, mockRepo.deck(game.DeckId).Remaining)
+	assert.Equal(t, repo.KlondikeGame, gameRepo.game(game.GameId).GameType)
+
+	// Test case: the same seed deals the same game
+	other, err := klondikeService.CreateGame(model.CreateKlondikeRequest{DrawCount: 3, Seed: "replay"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, game.Tableau, other.Tableau)
+
+	// Test case: invalid draw count
+	game, err = klondikeService.CreateGame(model.CreateKlondikeRequest{DrawCount: 2})
+
+	assert.EqualError(t, err, "draw count must be 1 or 3")
+	assert.Nil(t, game)
+
+	// Test case: unknown game
+	game, err = klondikeService.GetGame("unknown")
+
+	assert.EqualError(t, err, "game with id unknown wasn't found")
+	assert.Nil(t, game)
+}
+
+func TestPlayKlondike(t *testing.T) {
+	decks, mockRepo, gameRepo := newGameDecks()
+	klondikeService := NewKlondikeService(gameRepo, decks)
+	saveKlondikeGame(mockRepo, gameRepo, "game-id", klondike.Game{DrawCount: 1, Layout: klondike.Layout{
+		Stock: []string{"AH", "2C"},
+		Tableau: [klondike.Columns]klondike.Column{
+			{Cards: []string{"5S", "KD"}, FaceUp: 1},
+			{Cards: []string{"QS"}, FaceUp: 1},
+		},
+	}})
+
+	// Test case: draw from the stock
+	game, err := klondikeService.Draw("game-id")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, game.Stock)
+	assert.Equal(t, []string{"AH"}, cardCodes(game.Waste))
+
+	// Test case: move a card, the card below is turned over
+	game, err = klondikeService.Move("game-id", model.KlondikeMoveRequest{From: "waste", To: "foundation1", Count: 1})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"AH"}, cardCodes(game.Foundations[0]))
+	game, err = klondikeService.Move("game-id", model.KlondikeMoveRequest{From: "tableau1", To: "tableau3", Count: 1})
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.KlondikeColumnResponse{Hidden: 0, Cards: []model.Card{{Value: "5", Suit: "SPADES", Code: "5S", Rank: 5, Color: "BLACK", Symbol: "🂥"}}},
+		game.Tableau[0])
+	assert.Equal(t, 3, game.Moves)
+	assert.Equal(t, 3, gameRepo.game("game-id").Version)
+
+	// Test case: invalid move
+	game, err = klondikeService.Move("game-id", model.KlondikeMoveRequest{From: "tableau2", To: "tableau1", Count: 1})
+
+	assert.EqualError(t, err, "QS can't be moved onto 5S")
+	assert.Equal(t, http.StatusBadRequest, err.(*customErr.Error).Kind())
+	assert.Nil(t, game)
+
+	// Test case: undo
+	game, err = klondikeService.Undo("game-id")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, game.Tableau[0].Hidden)
+	assert.Equal(t, 2, game.Moves)
+	assert.True(t, game.CanUndo)
+}
+
+func TestPlayKlondikeWonGame(t *testing.T) {
+	decks, mockRepo, gameRepo := newGameDecks()
+	klondikeService := NewKlondikeService(gameRepo, decks)
+	saveKlondikeGame(mockRepo, gameRepo, "game-id", klondike.Game{DrawCount: 1, Won: true})
+
+	// Test case: a won game can't be played on
+	game, err := klondikeService.Draw("game-id")
+
+	assert.EqualError(t, err, "the game is already won")
+	assert.Equal(t, http.StatusConflict, err.(*customErr.Error).Kind())
+	assert.Nil(t, game)
+
+	// Test case: another request changed the game meanwhile
+	saveKlondikeGame(mockRepo, gameRepo, "other-id", klondike.Game{DrawCount: 1, Layout: klondike.Layout{Stock: []string{"AH"}}})
+	conflictRepo := &conflictingGameRepo{MockGameRepo: gameRepo}
+	game, err = NewKlondikeService(conflictRepo, decks).Draw("other-id")
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, err.(*customErr.Error).Kind())
+	assert.Nil(t, game)
+}
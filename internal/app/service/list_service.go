@@ -0,0 +1,107 @@
+package service
+
+import (
+	"encoding/base64"
+	"fmt"
+	customErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/repo"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+// ListDecks pages through the decks matching the request, oldest first, leaving out the expired ones. A page is read
+// from the position its cursor points to, so decks created meanwhile never shift it.
+func (s *deckService) ListDecks(req model.ListDecksRequest) (*model.ListDecksResponse, error) {
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultListLimit
+	}
+	if limit < 1 || limit > maxListLimit {
+		return nil, customErr.New(http.StatusBadRequest, fmt.Sprintf("limit must be between 1 - %d", maxListLimit))
+	}
+	if req.CreatedFrom != nil && req.CreatedTo != nil && !req.CreatedFrom.Before(*req.CreatedTo) {
+		return nil, customErr.New(http.StatusBadRequest, "created_from must be before created_to")
+	}
+	if req.UpdatedFrom != nil && req.UpdatedTo != nil && !req.UpdatedFrom.Before(*req.UpdatedTo) {
+		return nil, customErr.New(http.StatusBadRequest, "updated_from must be before updated_to")
+	}
+	now := time.Now().UTC()
+	filter := repo.DeckFilter{
+		CreatedFrom: req.CreatedFrom,
+		CreatedTo:   req.CreatedTo,
+		UpdatedFrom: req.UpdatedFrom,
+		UpdatedTo:   req.UpdatedTo,
+		Shuffled:    req.Shuffled,
+		Empty:       req.Empty,
+		Owner:       req.Owner,
+		ParentId:    req.ParentId,
+		ActiveAt:    &now,
+		// one more deck than asked for tells if there's a next page
+		Limit: limit + 1,
+	}
+	if len(req.Cursor) > 0 {
+		after, err := decodeCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = after
+	}
+
+	decks, err := s.repo.ListDecks(filter)
+	if err != nil {
+		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't list decks", err)
+	}
+	res := &model.ListDecksResponse{Decks: []model.DeckSummary{}}
+	if len(decks) > limit {
+		decks = decks[:limit]
+		last := decks[limit-1]
+		res.NextCursor = encodeCursor(repo.DeckCursor{CreatedAt: last.CreatedAt, Id: last.Id})
+	}
+	for _, d := range decks {
+		res.Decks = append(res.Decks, model.DeckSummary{
+			DeckId:     d.Id,
+			Owner:      d.Owner,
+			ParentId:   d.ParentId,
+			Shuffled:   d.Shuffled,
+			Remaining:  d.Remaining,
+			Size:       d.Size,
+			DeckType:   string(d.DeckType),
+			Jokers:     d.Jokers,
+			DecksCount: decksCount(d),
+			ExpiresAt:  d.ExpiresAt,
+			CreatedAt:  d.CreatedAt,
+			UpdatedAt:  d.UpdatedAt,
+		})
+	}
+	return res, nil
+}
+
+// encodeCursor makes an opaque token of the position of a deck in the listing
+func encodeCursor(cursor repo.DeckCursor) string {
+	position := cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.Id
+	return base64.RawURLEncoding.EncodeToString([]byte(position))
+}
+
+func decodeCursor(token string) (*repo.DeckCursor, error) {
+	invalid := customErr.New(http.StatusBadRequest, "cursor is invalid")
+	position, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, invalid
+	}
+	createdAt, id, found := strings.Cut(string(position), "|")
+	if !found || len(id) == 0 {
+		return nil, invalid
+	}
+	at, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, invalid
+	}
+	return &repo.DeckCursor{CreatedAt: at, Id: id}, nil
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"errors"
+	customErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/repo"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestListDecks(t *testing.T) {
+	start := time.Date(2023, 12, 31, 10, 0, 0, 0, time.UTC)
+	mockRepo := newMockRepo()
+	for i, d := range []repo.Deck{
+		{Id: "a", Owner: "alice", Shuffled: true, Remaining: 52},
+		{Id: "b", Owner: "bob", Remaining: 0},
+		{Id: "c", Owner: "alice", Remaining: 10},
+		{Id: "d", Owner: "alice", Shuffled: true, Remaining: 0},
+		{Id: "e", Owner: "bob", Shuffled: true, Remaining: 5},
+	} {
+		d.DeckType = repo.Standard
+		d.Size = 52
+		d.CreatedAt = start.Add(time.Duration(i) * time.Hour)
+		d.UpdatedAt = d.CreatedAt.Add(time.Duration(5-i) * time.Hour)
+		mockRepo.putDeck(d)
+	}
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+
+	// Test case: every deck, oldest first and on one page
+	res, err := deckService.ListDecks(model.ListDecksRequest{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, summaryIds(res.Decks))
+	assert.Empty(t, res.NextCursor)
+	assert.Equal(t, "alice", res.Decks[0].Owner)
+	assert.Equal(t, 1, res.Decks[0].DecksCount)
+
+	// Test case: the cursor continues where the previous page ended
+	res, err = deckService.ListDecks(model.ListDecksRequest{Limit: 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, summaryIds(res.Decks))
+	assert.NotEmpty(t, res.NextCursor)
+	res, err = deckService.ListDecks(model.ListDecksRequest{Limit: 2, Cursor: res.NextCursor})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c", "d"}, summaryIds(res.Decks))
+	res, err = deckService.ListDecks(model.ListDecksRequest{Limit: 2, Cursor: res.NextCursor})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e"}, summaryIds(res.Decks))
+	assert.Empty(t, res.NextCursor)
+
+	// Test case: filters combine
+	yes, no := true, false
+	res, err = deckService.ListDecks(model.ListDecksRequest{Owner: "alice", Shuffled: &yes})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "d"}, summaryIds(res.Decks))
+	res, err = deckService.ListDecks(model.ListDecksRequest{Empty: &yes})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "d"}, summaryIds(res.Decks))
+	res, err = deckService.ListDecks(model.ListDecksRequest{Empty: &no, Owner: "bob"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e"}, summaryIds(res.Decks))
+
+	// Test case: time ranges include their start and exclude their end
+	from, to := start.Add(time.Hour), start.Add(3*time.Hour)
+	res, err = deckService.ListDecks(model.ListDecksRequest{CreatedFrom: &from, CreatedTo: &to})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, summaryIds(res.Decks))
+	updatedFrom := start.Add(5 * time.Hour)
+	res, err = deckService.ListDecks(model.ListDecksRequest{UpdatedFrom: &updatedFrom})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, summaryIds(res.Decks))
+	res, err = deckService.ListDecks(model.ListDecksRequest{UpdatedTo: &updatedFrom})
+
+	assert.NoError(t, err)
+	assert.Empty(t, res.Decks)
+
+	// Test case: invalid requests
+	for _, req := range []model.ListDecksRequest{
+		{Limit: -1},
+		{Limit: 101},
+		{Cursor: "not a cursor"},
+		{Cursor: encodeCursor(repo.DeckCursor{CreatedAt: start})},
+		{CreatedFrom: &to, CreatedTo: &from},
+		{UpdatedFrom: &to, UpdatedTo: &to},
+	} {
+		res, err = deckService.ListDecks(req)
+
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusBadRequest, err.(*customErr.Error).Kind())
+		assert.Nil(t, res)
+	}
+
+	// Test case: the database fails
+	mockRepo.DeckError = errors.New("connection refused")
+	res, err = deckService.ListDecks(model.ListDecksRequest{})
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, err.(*customErr.Error).Kind())
+	assert.Nil(t, res)
+}
+
+func TestListDecksCursor(t *testing.T) {
+	at := time.Date(2023, 12, 31, 10, 0, 0, 123456000, time.UTC)
+
+	// Test case: a cursor decodes to the position it was made of
+	cursor, err := decodeCursor(encodeCursor(repo.DeckCursor{CreatedAt: at, Id: "deck|id"}))
+
+	assert.NoError(t, err)
+	assert.True(t, at.Equal(cursor.CreatedAt))
+	assert.Equal(t, "deck|id", cursor.Id)
+}
+
+func summaryIds(decks []model.DeckSummary) []string {
+	ids := make([]string, len(decks))
+	for i, d := range decks {
+		ids[i] = d.DeckId
+	}
+	return ids
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"fmt"
+	customErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/repo"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+var validPileName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,50}$`)
+
+func (s *deckService) CreatePile(id, name string) (*model.PileResponse, error) {
+	if !validPileName.MatchString(name) {
+		return nil, customErr.New(http.StatusBadRequest, "pile name must be 1 - 50 letters, digits, - or _")
+	}
+	deck, err := s.getDeck(id)
+	if err != nil {
+		return nil, err
+	}
+	if findPile(deck, name) != nil {
+		return nil, customErr.New(http.StatusConflict, fmt.Sprintf("pile %s already exists", name))
+	}
+	now := time.Now().UTC()
+	deck.Piles = append(deck.Piles, repo.Pile{DeckId: deck.Id, Name: name, Cards: []string{}, CreatedAt: now, UpdatedAt: now})
+	if err = s.saveDeck(*deck, repo.CreatePileEvent, map[string]interface{}{"pile": name}); err != nil {
+		return nil, err
+	}
+	return toPileResponse(deck.Piles[len(deck.Piles)-1], s.aceHigh)
+}
+
+func (s *deckService) GetPile(id, name string) (*model.PileResponse, error) {
+	_, pile, err := s.getPile(id, name)
+	if err != nil {
+		return nil, err
+	}
+	return toPileResponse(*pile, s.aceHigh)
+}
+
+// MoveCards moves cards into the pile, on its top. They are taken from the deck's remaining cards or from another pile.
+func (s *deckService) MoveCards(id, name string, req model.MoveCardsRequest) (*model.PileResponse, error) {
+	if len(req.Cards) == 0 && req.Count <= 0 {
+		return nil, customErr.New(http.StatusBadRequest, "either cards or a positive count must be given")
+	}
+	if err := validateCodes(req.Cards); err != nil {
+		return nil, err
+	}
+	if req.From == name {
+		return nil, customErr.New(http.StatusBadRequest, "cards can't be moved to the same pile")
+	}
+	deck, pile, err := s.getPile(id, name)
+	if err != nil {
+		return nil, err
+	}
+
+	source := &deck.Cards
+	sourceName := "the deck"
+	if len(req.From) > 0 {
+		from := findPile(deck, req.From)
+		if from == nil {
+			return nil, customErr.New(http.StatusNotFound, fmt.Sprintf("pile %s wasn't found", req.From))
+		}
+		source = &from.Cards
+		sourceName = fmt.Sprintf("pile %s", req.From)
+	}
+
+	moved := req.Cards
+	if len(moved) == 0 {
+		if req.Count > len(*source) {
+			return nil, customErr.New(http.StatusBadRequest, fmt.Sprintf("count must be less or equal than the cards in %s", sourceName))
+		}
+		moved = append([]string{}, (*source)[:req.Count]...)
+		*source = (*source)[req.Count:]
+	} else {
+		for _, c := range moved {
+			if !removeCard(source, c) {
+				return nil, customErr.New(http.StatusNotFound, fmt.Sprintf("card %s isn't in %s", c, sourceName))
+			}
+		}
+	}
+	pile.Cards = append(append([]string{}, moved...), pile.Cards...)
+	deck.Remaining = len(deck.Cards)
+
+	err = s.saveDeck(*deck, repo.MoveCardsEvent, map[string]interface{}{"pile": name, "from": req.From, "cards": moved})
+	if err != nil {
+		return nil, err
+	}
+	return toPileResponse(*pile, s.aceHigh)
+}
+
+func (s *deckService) ShufflePile(id, name string) (*model.PileResponse, error) {
+	deck, pile, err := s.getPile(id, name)
+	if err != nil {
+		return nil, err
+	}
+	shuffleWith(s.sourceFor(*deck), pile.Cards)
+	if err = s.saveDeck(*deck, repo.ShufflePileEvent, map[string]interface{}{"pile": name}); err != nil {
+		return nil, err
+	}
+	return toPileResponse(*pile, s.aceHigh)
+}
+
+// DrawFromPile draws cards from the top of the pile, like DrawCards does for the deck
+func (s *deckService) DrawFromPile(id, name string, count int) ([]model.Card, error) {
+	if count <= 0 {
+		return nil, customErr.New(http.StatusBadRequest, "count must be positive")
+	}
+	deck, pile, err := s.getPile(id, name)
+	if err != nil {
+		return nil, err
+	}
+	if count > len(pile.Cards) {
+		return nil, customErr.New(http.StatusBadRequest, "count must be less or equal than pile's remaining")
+	}
+	drawn := append([]string{}, pile.Cards[:count]...)
+	pile.Cards = pile.Cards[count:]
+	deck.Drawn = append(deck.Drawn, drawn...)
+	if err = s.saveDeck(*deck, repo.DrawPileEvent, map[string]interface{}{"pile": name, "cards": drawn}); err != nil {
+		return nil, err
+	}
+	return toRankedCards(drawn, s.aceHigh)
+}
+
+// getPile returns the deck and a pointer to its pile, so changes on the pile are saved with the deck
+func (s *deckService) getPile(id, name string) (*repo.Deck, *repo.Pile, error) {
+	deck, err := s.getDeck(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	pile := findPile(deck, name)
+	if pile == nil {
+		return nil, nil, customErr.New(http.StatusNotFound, fmt.Sprintf("pile %s wasn't found", name))
+	}
+	return deck, pile, nil
+}
+
+func findPile(deck *repo.Deck, name string) *repo.Pile {
+	for i := range deck.Piles {
+		if deck.Piles[i].Name == name {
+			return &deck.Piles[i]
+		}
+	}
+	return nil
+}
+
+func toPileResponse(pile repo.Pile, aceHigh bool) (*model.PileResponse, error) {
+	cards, err := toRankedCards(pile.Cards, aceHigh)
+	if err != nil {
+		return nil, err
+	}
+	return &model.PileResponse{
+		DeckId:    pile.DeckId,
+		Name:      pile.Name,
+		Remaining: len(pile.Cards),
+		Cards:     cards,
+	}, nil
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/repo"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCreatePile(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+	deckID := "existing_deck_id"
+	mockRepo.putDeck(repo.Deck{Id: deckID, Remaining: 3, Cards: []string{"AH", "2C", "3D"}})
+
+	// Test case: create an empty pile
+	res, err := deckService.CreatePile(deckID, "player-1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "player-1", res.Name)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Len(t, mockRepo.deck(deckID).Piles, 1)
+
+	// Test case: create a pile twice
+	res, err = deckService.CreatePile(deckID, "player-1")
+
+	assert.EqualError(t, err, "pile player-1 already exists")
+	assert.Nil(t, res)
+
+	// Test case: invalid pile name
+	res, err = deckService.CreatePile(deckID, "player 1")
+
+	assert.Error(t, err)
+	assert.Nil(t, res)
+}
+
+func TestMoveCards(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+	deckID := "existing_deck_id"
+	now := time.Now().UTC()
+	mockRepo.putDeck(repo.Deck{
+		Id:        deckID,
+		Remaining: 5,
+		Cards:     []string{"AH", "2C", "3D", "4S", "5H"},
+		Piles: []repo.Pile{{DeckId: deckID, Name: "hand", CreatedAt: now},
+			{DeckId: deckID, Name: "board", CreatedAt: now.Add(time.Second)}},
+	})
+
+	// Test case: move cards from the top of the deck
+	res, err := deckService.MoveCards(deckID, "hand", model.MoveCardsRequest{Count: 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res.Remaining)
+	deck := mockRepo.deck(deckID)
+	assert.Equal(t, 3, deck.Remaining)
+	assert.Equal(t, []string{"3D", "4S", "5H"}, []string(deck.Cards))
+	assert.Equal(t, []string{"AH", "2C"}, []string(deck.Piles[0].Cards))
+
+	// Test case: move a named card from the deck
+	res, err = deckService.MoveCards(deckID, "board", model.MoveCardsRequest{Cards: []string{"4S"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"3D", "5H"}, []string(mockRepo.deck(deckID).Cards))
+	assert.Equal(t, []string{"4S"}, []string(mockRepo.deck(deckID).Piles[1].Cards))
+
+	// Test case: move a card from another pile
+	res, err = deckService.MoveCards(deckID, "board", model.MoveCardsRequest{From: "hand", Cards: []string{"2C"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res.Remaining)
+	assert.Equal(t, []string{"AH"}, []string(mockRepo.deck(deckID).Piles[0].Cards))
+	assert.Equal(t, []string{"2C", "4S"}, []string(mockRepo.deck(deckID).Piles[1].Cards))
+
+	// Test case: move a card which isn't in the source pile
+	res, err = deckService.MoveCards(deckID, "board", model.MoveCardsRequest{From: "hand", Cards: []string{"5H"}})
+
+	assert.EqualError(t, err, "card 5H isn't in pile hand")
+	assert.Nil(t, res)
+	assert.Equal(t, []string{"AH"}, []string(mockRepo.deck(deckID).Piles[0].Cards))
+
+	// Test case: move more cards than the deck has
+	res, err = deckService.MoveCards(deckID, "hand", model.MoveCardsRequest{Count: 3})
+
+	assert.EqualError(t, err, "count must be less or equal than the cards in the deck")
+	assert.Nil(t, res)
+
+	// Test case: move to a missing pile
+	res, err = deckService.MoveCards(deckID, "muck", model.MoveCardsRequest{Count: 1})
+
+	assert.EqualError(t, err, "pile muck wasn't found")
+	assert.Nil(t, res)
+
+	// Test case: every card is still in exactly one place
+	deck = mockRepo.deck(deckID)
+	all := append(append(append([]string{}, deck.Cards...), deck.Piles[0].Cards...), deck.Piles[1].Cards...)
+	assert.ElementsMatch(t, []string{"AH", "2C", "3D", "4S", "5H"}, all)
+}
+
+func TestShufflePile(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+	deckID := "existing_deck_id"
+	mockRepo.putDeck(repo.Deck{
+		Id:    deckID,
+		Piles: []repo.Pile{{DeckId: deckID, Name: "hand", Cards: []string{"AH", "2C", "3D"}}},
+	})
+
+	// Test case: shuffle keeps the cards of the pile
+	res, err := deckService.ShufflePile(deckID, "hand")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, res.Remaining)
+	assert.ElementsMatch(t, []string{"AH", "2C", "3D"}, []string(mockRepo.deck(deckID).Piles[0].Cards))
+}
+
+func TestDrawFromPile(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+	deckID := "existing_deck_id"
+	mockRepo.putDeck(repo.Deck{
+		Id:    deckID,
+		Piles: []repo.Pile{{DeckId: deckID, Name: "hand", Cards: []string{"AH", "2C", "3D"}}},
+	})
+
+	// Test case: draw from the top of the pile
+	cards, err := deckService.DrawFromPile(deckID, "hand", 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "AH", cards[0].Code)
+	assert.Equal(t, "2C", cards[1].Code)
+	assert.Equal(t, []string{"3D"}, []string(mockRepo.deck(deckID).Piles[0].Cards))
+	assert.Equal(t, []string{"AH", "2C"}, []string(mockRepo.deck(deckID).Drawn))
+
+	// Test case: draw more than the pile has
+	cards, err = deckService.DrawFromPile(deckID, "hand", 2)
+
+	assert.EqualError(t, err, "count must be less or equal than pile's remaining")
+	assert.Nil(t, cards)
+
+	// Test case: get the pile
+	pile, err := deckService.GetPile(deckID, "hand")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, pile.Remaining)
+	assert.Equal(t, "3D", pile.Cards[0].Code)
+}
+
+func cardCodes(cards []model.Card) []string {
+	codes := make([]string, len(cards))
+	for i, c := range cards {
+		codes[i] = c.Code
+	}
+	return codes
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"fmt"
+	customErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/poker"
+	"net/http"
+)
+
+const (
+	defaultEquityIterations = 10000
+	maxEquityIterations     = 200000
+)
+
+// EvaluateHand ranks the best 5 card poker hand out of 5 - 7 card codes
+func (s *deckService) EvaluateHand(codes []string) (*model.HandResponse, error) {
+	return evaluateHand(codes)
+}
+
+// EvaluatePile ranks the cards held in a pile of the deck, e.g. a player's hand
+func (s *deckService) EvaluatePile(id, name string) (*model.HandResponse, error) {
+	_, pile, err := s.getPile(id, name)
+	if err != nil {
+		return nil, err
+	}
+	return evaluateHand(pile.Cards)
+}
+
+// Showdown evaluates every player's hole cards with the board and picks the winners of the pot
+func (s *deckService) Showdown(req model.ShowdownRequest) (*model.ShowdownResponse, error) {
+	if len(req.Players) < 2 {
+		return nil, customErr.New(http.StatusBadRequest, "a showdown needs at least 2 players")
+	}
+	names := make(map[string]bool, len(req.Players))
+	holeCards := make([][]poker.Card, len(req.Players))
+	for i, p := range req.Players {
+		if len(p.Name) == 0 || names[p.Name] {
+			return nil, customErr.New(http.StatusBadRequest, "every player needs a unique name")
+		}
+		names[p.Name] = true
+		cards, err := poker.ParseCards(p.Cards)
+		if err != nil {
+			return nil, customErr.New(http.StatusBadRequest, fmt.Sprintf("player %s: %s", p.Name, err.Error()))
+		}
+		holeCards[i] = cards
+	}
+	board, err := poker.ParseCards(req.Board)
+	if err != nil {
+		return nil, customErr.New(http.StatusBadRequest, fmt.Sprintf("board: %s", err.Error()))
+	}
+
+	hands, err := poker.Showdown(holeCards, board)
+	if err != nil {
+		return nil, customErr.New(http.StatusBadRequest, err.Error())
+	}
+	winners := poker.Winners(hands)
+	return toShowdownResponse(req, hands, winners)
+}
+
+// DeckShowdown resolves a showdown from cards dealt into piles of the deck, one pile per player and an optional board
+func (s *deckService) DeckShowdown(id string, players []string, board string) (*model.ShowdownResponse, error) {
+	deck, err := s.getDeck(id)
+	if err != nil {
+		return nil, err
+	}
+	req := model.ShowdownRequest{Players: make([]model.PlayerCards, len(players))}
+	for i, name := range players {
+		pile := findPile(deck, name)
+		if pile == nil {
+			return nil, customErr.New(http.StatusNotFound, fmt.Sprintf("pile %s wasn't found", name))
+		}
+		req.Players[i] = model.PlayerCards{Name: name, Cards: pile.Cards}
+	}
+	if len(board) > 0 {
+		pile := findPile(deck, board)
+		if pile == nil {
+			return nil, customErr.New(http.StatusNotFound, fmt.Sprintf("pile %s wasn't found", board))
+		}
+		req.Board = pile.Cards
+	}
+
+	res, err := s.Showdown(req)
+	if err != nil {
+		return nil, err
+	}
+	res.DeckId = deck.Id
+	return res, nil
+}
+
+// CalculateEquity computes the Hold'em win and tie probabilities of the players. The unseen cards are the default deck
+// without the known hole and board cards.
+func (s *deckService) CalculateEquity(req model.EquityRequest) (*model.EquityResponse, error) {
+	iterations := req.Iterations
+	if iterations == 0 {
+		iterations = defaultEquityIterations
+	}
+	if iterations < 0 || iterations > maxEquityIterations {
+		return nil, customErr.New(http.StatusBadRequest, fmt.Sprintf("iterations must be between 1 and %d", maxEquityIterations))
+	}
+	if len(req.Seed) > maxSeedLength {
+		return nil, customErr.New(http.StatusBadRequest, fmt.Sprintf("seeds must be at most %d characters", maxSeedLength))
+	}
+
+	seats := make([]poker.Seat, len(req.Players))
+	known := append([]string{}, req.Board...)
+	for i, p := range req.Players {
+		if len(p.Cards) > 0 {
+			known = append(known, p.Cards...)
+			continue
+		}
+		if len(p.Range) == 0 {
+			return nil, customErr.New(http.StatusBadRequest, fmt.Sprintf("player %s needs hole cards or a range", p.Name))
+		}
+		combos, err := poker.ParseRange(p.Range)
+		if err != nil {
+			return nil, customErr.New(http.StatusBadRequest, fmt.Sprintf("player %s: %s", p.Name, err.Error()))
+		}
+		seats[i].Range = combos
+	}
+	knownCards, err := poker.ParseCards(known)
+	if err != nil {
+		return nil, customErr.New(http.StatusBadRequest, err.Error())
+	}
+	board := knownCards[:len(req.Board)]
+	dealt := knownCards[len(req.Board):]
+	for i, p := range req.Players {
+		if len(p.Cards) > 0 {
+			seats[i].Cards, dealt = dealt[:len(p.Cards)], dealt[len(p.Cards):]
+		}
+	}
+
+	unseen, err := unseenCards(knownCards)
+	if err != nil {
+		return nil, err
+	}
+	random := s.random
+	if len(req.Seed) > 0 {
+		random = NewSeededSource(req.Seed)
+	}
+	result, err := poker.CalculateEquity(seats, board, unseen, random, iterations)
+	if err != nil {
+		return nil, customErr.New(http.StatusBadRequest, err.Error())
+	}
+	return toEquityResponse(req, result)
+}
+
+// unseenCards is the default deck without the known cards
+func unseenCards(known []poker.Card) ([]poker.Card, error) {
+	deck, err := poker.ParseCards(GenerateDefaultDeck())
+	if err != nil {
+		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't build the default deck", err)
+	}
+	dealt := make(map[poker.Card]bool, len(known))
+	for _, c := range known {
+		dealt[c] = true
+	}
+	unseen := make([]poker.Card, 0, len(deck))
+	for _, c := range deck {
+		if !dealt[c] {
+			unseen = append(unseen, c)
+		}
+	}
+	return unseen, nil
+}
+
+func evaluateHand(codes []string) (*model.HandResponse, error) {
+	cards, err := poker.ParseCards(codes)
+	if err != nil {
+		return nil, customErr.New(http.StatusBadRequest, err.Error())
+	}
+	hand, err := poker.Evaluate(cards)
+	if err != nil {
+		return nil, customErr.New(http.StatusBadRequest, err.Error())
+	}
+	return toHandResponse(hand)
+}
+
+func toHandResponse(hand poker.Hand) (*model.HandResponse, error) {
+	codes := make([]string, len(hand.Cards))
+	for i, c := range hand.Cards {
+		codes[i] = c.Code()
+	}
+	cards, err := toCards(codes)
+	if err != nil {
+		return nil, err
+	}
+	return &model.HandResponse{
+		Category:    hand.Category.String(),
+		Description: hand.Description(),
+		Score:       hand.Score,
+		Cards:       cards,
+	}, nil
+}
+
+func toShowdownResponse(req model.ShowdownRequest, hands []poker.Hand, winners []int) (*model.ShowdownResponse, error) {
+	board, err := toCards(req.Board)
+	if err != nil {
+		return nil, err
+	}
+	res := &model.ShowdownResponse{
+		Board:   board,
+		Players: make([]model.PlayerHandResponse, len(req.Players)),
+		Winners: make([]string, len(winners)),
+		Split:   len(winners) > 1,
+	}
+	for i, p := range req.Players {
+		holeCards, err := toCards(p.Cards)
+		if err != nil {
+			return nil, err
+		}
+		hand, err := toHandResponse(hands[i])
+		if err != nil {
+			return nil, err
+		}
+		res.Players[i] = model.PlayerHandResponse{Name: p.Name, HoleCards: holeCards, Hand: *hand}
+	}
+	for i, w := range winners {
+		res.Players[w].Winner = true
+		res.Winners[i] = req.Players[w].Name
+	}
+	return res, nil
+}
+
+func toEquityResponse(req model.EquityRequest, result poker.EquityResult) (*model.EquityResponse, error) {
+	res := &model.EquityResponse{
+		Exact:   result.Exact,
+		Runouts: result.Runouts,
+		Players: make([]model.PlayerEquityResponse, len(req.Players)),
+	}
+	for i, p := range req.Players {
+		cards, err := toCards(p.Cards)
+		if err != nil {
+			return nil, err
+		}
+		equity := result.Seats[i]
+		res.Players[i] = model.PlayerEquityResponse{
+			Name:   p.Name,
+			Cards:  cards,
+			Range:  p.Range,
+			Win:    equity.Win,
+			Tie:    equity.Tie,
+			Equity: equity.Equity,
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/repo"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEvaluatePile(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+	deckID := "existing_deck_id"
+	mockRepo.putDeck(repo.Deck{
+		Id:        deckID,
+		Remaining: 1,
+		Cards:     []string{"2C"},
+		Piles: []repo.Pile{
+			{DeckId: deckID, Name: "hand", Cards: []string{"KH", "AS", "KD", "9C", "AD", "3S", "4S"}},
+			{DeckId: deckID, Name: "short", Cards: []string{"KH", "AS"}},
+		},
+	})
+
+	// Test case: evaluate the best 5 of the pile's 7 cards
+	res, err := deckService.EvaluatePile(deckID, "hand")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "TWO_PAIR", res.Category)
+	assert.Equal(t, []string{"AS", "AD", "KH", "KD", "9C"}, cardCodes(res.Cards))
+
+	// Test case: a pile with too few cards
+	res, err = deckService.EvaluatePile(deckID, "short")
+
+	assert.EqualError(t, err, "a hand needs 5 - 7 cards, got 2")
+	assert.Nil(t, res)
+
+	// Test case: unknown pile
+	res, err = deckService.EvaluatePile(deckID, "board")
+
+	assert.EqualError(t, err, "pile board wasn't found")
+	assert.Nil(t, res)
+
+	// Test case: evaluate card codes
+	res, err = deckService.EvaluateHand([]string{"AS", "KS", "QS", "JS", "10S"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ROYAL_FLUSH", res.Category)
+
+	// Test case: jokers can't be evaluated
+	res, err = deckService.EvaluateHand([]string{"AS", "KS", "QS", "JS", "X1"})
+
+	assert.Error(t, err)
+	assert.Nil(t, res)
+}
+
+func TestShowdown(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+	board := []string{"AH", "KD", "7C", "7S", "2H"}
+
+	// Test case: the better hand wins
+	res, err := deckService.Showdown(model.ShowdownRequest{
+		Players: []model.PlayerCards{{Name: "alice", Cards: []string{"AS", "QC"}}, {Name: "bob", Cards: []string{"KS", "KC"}}},
+		Board:   board,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bob"}, res.Winners)
+	assert.False(t, res.Split)
+	assert.False(t, res.Players[0].Winner)
+	assert.True(t, res.Players[1].Winner)
+	assert.Equal(t, "FULL_HOUSE", res.Players[1].Hand.Category)
+	assert.Equal(t, "full house, kings full of 7s", res.Players[1].Hand.Description)
+	assert.Equal(t, []string{"AS", "QC"}, cardCodes(res.Players[0].HoleCards))
+	assert.Len(t, res.Board, 5)
+
+	// Test case: the same hand splits the pot
+	res, err = deckService.Showdown(model.ShowdownRequest{
+		Players: []model.PlayerCards{{Name: "alice", Cards: []string{"AS", "QC"}}, {Name: "bob", Cards: []string{"AD", "JC"}}},
+		Board:   board,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, res.Winners)
+	assert.True(t, res.Split)
+
+	// Test case: a single player
+	res, err = deckService.Showdown(model.ShowdownRequest{Players: []model.PlayerCards{{Name: "alice"}}, Board: board})
+
+	assert.EqualError(t, err, "a showdown needs at least 2 players")
+	assert.Nil(t, res)
+
+	// Test case: players with the same name
+	res, err = deckService.Showdown(model.ShowdownRequest{
+		Players: []model.PlayerCards{{Name: "alice", Cards: []string{"AS", "QC"}}, {Name: "alice", Cards: []string{"AD", "JC"}}},
+		Board:   board,
+	})
+
+	assert.EqualError(t, err, "every player needs a unique name")
+	assert.Nil(t, res)
+
+	// Test case: invalid hole cards
+	res, err = deckService.Showdown(model.ShowdownRequest{
+		Players: []model.PlayerCards{{Name: "alice", Cards: []string{"AS", "X1"}}, {Name: "bob", Cards: []string{"AD", "JC"}}},
+		Board:   board,
+	})
+
+	assert.EqualError(t, err, "player alice: invalid card code X1")
+	assert.Nil(t, res)
+
+	// Test case: a card dealt twice
+	res, err = deckService.Showdown(model.ShowdownRequest{
+		Players: []model.PlayerCards{{Name: "alice", Cards: []string{"AH", "QC"}}, {Name: "bob", Cards: []string{"AD", "JC"}}},
+		Board:   board,
+	})
+
+	assert.EqualError(t, err, "card AH is dealt more than once")
+	assert.Nil(t, res)
+}
+
+func TestDeckShowdown(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+	deckID := "existing_deck_id"
+	mockRepo.putDeck(repo.Deck{
+		Id:        deckID,
+		Remaining: 1,
+		Cards:     []string{"3C"},
+		Piles: []repo.Pile{
+			{DeckId: deckID, Name: "alice", Cards: []string{"AS", "AD"}},
+			{DeckId: deckID, Name: "bob", Cards: []string{"9H", "10H"}},
+			{DeckId: deckID, Name: "board", Cards: []string{"JH", "QH", "KH", "2C", "2D"}},
+		},
+	})
+
+	// Test case: showdown of the piles dealt from the deck
+	res, err := deckService.DeckShowdown(deckID, []string{"alice", "bob"}, "board")
+
+	assert.NoError(t, err)
+	assert.Equal(t, deckID, res.DeckId)
+	assert.Equal(t, []string{"bob"}, res.Winners)
+	assert.Equal(t, "STRAIGHT_FLUSH", res.Players[1].Hand.Category)
+	assert.Equal(t, "TWO_PAIR", res.Players[0].Hand.Category)
+
+	// Test case: unknown player pile
+	res, err = deckService.DeckShowdown(deckID, []string{"alice", "carol"}, "board")
+
+	assert.EqualError(t, err, "pile carol wasn't found")
+	assert.Nil(t, res)
+
+	// Test case: unknown deck
+	res, err = deckService.DeckShowdown("unknown", []string{"alice", "bob"}, "board")
+
+	assert.Error(t, err)
+	assert.Nil(t, res)
+}
+
+func TestCalculateEquity(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+
+	// Test case: the runouts of the turn are enumerated exactly
+	res, err := deckService.CalculateEquity(model.EquityRequest{
+		Players: []model.EquityPlayer{{Name: "alice", Cards: []string{"AS", "AD"}}, {Name: "bob", Cards: []string{"KS", "KD"}}},
+		Board:   []string{"2C", "7H", "9D", "JC"},
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, res.Exact)
+	assert.Equal(t, 44, res.Runouts)
+	assert.InDelta(t, 42.0/44, res.Players[0].Equity, 1e-9)
+	assert.InDelta(t, 2.0/44, res.Players[1].Equity, 1e-9)
+	assert.Equal(t, []string{"KS", "KD"}, cardCodes(res.Players[1].Cards))
+
+	// Test case: a seeded Monte Carlo run against a range is reproducible
+	req := model.EquityRequest{
+		Players:    []model.EquityPlayer{{Name: "alice", Cards: []string{"AS", "AD"}}, {Name: "bob", Range: "random"}},
+		Iterations: 2000,
+		Seed:       "replay",
+	}
+	res, err = deckService.CalculateEquity(req)
+
+	assert.NoError(t, err)
+	assert.False(t, res.Exact)
+	assert.Equal(t, 2000, res.Runouts)
+	assert.InDelta(t, 0.85, res.Players[0].Equity, 0.04)
+	assert.Equal(t, "random", res.Players[1].Range)
+	again, err := deckService.CalculateEquity(req)
+	assert.NoError(t, err)
+	assert.Equal(t, res, again)
+
+	// Test case: a card dealt twice
+	res, err = deckService.CalculateEquity(model.EquityRequest{
+		Players: []model.EquityPlayer{{Name: "alice", Cards: []string{"AS", "AD"}}, {Name: "bob", Cards: []string{"AS", "KD"}}},
+	})
+
+	assert.EqualError(t, err, "card AS is given more than once")
+	assert.Nil(t, res)
+
+	// Test case: a player without hole cards or range
+	res, err = deckService.CalculateEquity(model.EquityRequest{
+		Players: []model.EquityPlayer{{Name: "alice", Cards: []string{"AS", "AD"}}, {Name: "bob"}},
+	})
+
+	assert.EqualError(t, err, "player bob needs hole cards or a range")
+	assert.Nil(t, res)
+
+	// Test case: an invalid range
+	res, err = deckService.CalculateEquity(model.EquityRequest{
+		Players: []model.EquityPlayer{{Name: "alice", Cards: []string{"AS", "AD"}}, {Name: "bob", Range: "AX"}},
+	})
+
+	assert.EqualError(t, err, "player bob: invalid range AX")
+	assert.Nil(t, res)
+
+	// Test case: too many iterations
+	res, err = deckService.CalculateEquity(model.EquityRequest{Iterations: maxEquityIterations + 1})
+
+	assert.Error(t, err)
+	assert.Nil(t, res)
+}
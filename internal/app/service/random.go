@@ -0,0 +1,47 @@
+package service
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+// RandomSource provides the randomness of the service: shuffles, random positions and server seeds
+type RandomSource interface {
+	// Intn returns a uniform number in [0, n)
+	Intn(n int) int
+	// Read fills p with random bytes
+	Read(p []byte) (int, error)
+}
+
+// cryptoSource is the default source, backed by crypto/rand
+type cryptoSource struct{}
+
+func NewCryptoSource() RandomSource {
+	return cryptoSource{}
+}
+
+func (cryptoSource) Intn(n int) int {
+	value, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		// crypto/rand only fails if the operating system can't provide randomness, nothing can be dealt then
+		panic(err)
+	}
+	return int(value.Int64())
+}
+
+func (cryptoSource) Read(p []byte) (int, error) {
+	return rand.Read(p)
+}
+
+// NewSeededSource returns a deterministic source, the same seed always gives the same numbers
+func NewSeededSource(seed string) RandomSource {
+	return newFairStream(seed, "")
+}
+
+// shuffleWith is a Fisher-Yates shuffle from the last card down, the algorithm every shuffle of the service uses
+func shuffleWith(source RandomSource, cards []string) {
+	for i := len(cards) - 1; i > 0; i-- {
+		j := source.Intn(i + 1)
+		cards[i], cards[j] = cards[j], cards[i]
+	}
+}
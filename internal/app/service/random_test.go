@@ -0,0 +1,43 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestShuffleWith(t *testing.T) {
+	// Test case: a seeded source gives an exact order
+	cards := []string{"AS", "2S", "3S", "4S", "5S", "6S", "7S", "8S", "9S", "10S"}
+
+	shuffleWith(NewSeededSource("test-seed"), cards)
+
+	assert.Equal(t, []string{"7S", "3S", "4S", "6S", "2S", "10S", "5S", "8S", "AS", "9S"}, cards)
+
+	// Test case: the crypto source keeps every card
+	cards = GenerateDefaultDeck()
+
+	shuffleWith(NewCryptoSource(), cards)
+
+	assert.ElementsMatch(t, GenerateDefaultDeck(), cards)
+}
+
+func TestSeededSource(t *testing.T) {
+	// Test case: the same seed gives the same numbers and bytes
+	source := NewSeededSource("test-seed")
+	assert.Equal(t, []int{48, 8, 43}, []int{source.Intn(100), source.Intn(100), source.Intn(100)})
+
+	bytes := make([]byte, 4)
+	n, err := NewSeededSource("test-seed").Read(bytes)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, []byte{0xe7, 0x6b, 0x90, 0xec}, bytes)
+}
+
+func TestCryptoSource(t *testing.T) {
+	// Test case: numbers stay within bounds
+	source := NewCryptoSource()
+	for i := 0; i < 100; i++ {
+		value := source.Intn(52)
+		assert.True(t, value >= 0 && value < 52)
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"github.com/deck/internal/app/repo"
+	"github.com/golang/glog"
+	"time"
+)
+
+// DeckReaper periodically deletes the decks whose ttl passed and, with a positive maxIdle, the decks nobody played
+// with for that long. It deletes batchSize decks per statement so no single delete holds locks on millions of rows.
+type DeckReaper struct {
+	repo      repo.DeckRepo
+	interval  time.Duration
+	maxIdle   time.Duration
+	batchSize int
+}
+
+func NewDeckReaper(repo repo.DeckRepo, interval, maxIdle time.Duration, batchSize int) *DeckReaper {
+	return &DeckReaper{repo: repo, interval: interval, maxIdle: maxIdle, batchSize: batchSize}
+}
+
+// Run reaps every interval until the context is cancelled
+func (r *DeckReaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			glog.Infoln("deck reaper stopped")
+			return
+		case <-ticker.C:
+			deleted, err := r.Reap(ctx)
+			if err != nil {
+				glog.Errorf("couldn't reap decks: %s", err)
+			}
+			if deleted > 0 {
+				glog.Infof("reaped %d decks", deleted)
+			}
+		}
+	}
+}
+
+// Reap deletes batches of expired decks until a batch comes back short, or the context is cancelled between batches
+func (r *DeckReaper) Reap(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	var idleSince *time.Time
+	if r.maxIdle > 0 {
+		since := now.Add(-r.maxIdle)
+		idleSince = &since
+	}
+	total := 0
+	for ctx.Err() == nil {
+		deleted, err := r.repo.DeleteExpiredDecks(now, idleSince, r.batchSize)
+		total += deleted
+		if err != nil {
+			return total, err
+		}
+		if deleted < r.batchSize {
+			break
+		}
+	}
+	return total, nil
+}
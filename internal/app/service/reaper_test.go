@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/deck/internal/app/repo"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// storedDeckIds lists the ids of the decks left in the repository
+func storedDeckIds(mockRepo *MockRepo) []string {
+	decks, _ := mockRepo.ListDecks(repo.DeckFilter{Limit: 100})
+	ids := make([]string, len(decks))
+	for i, d := range decks {
+		ids[i] = d.Id
+	}
+	return ids
+}
+
+func TestReap(t *testing.T) {
+	now := time.Now().UTC()
+	expired, later, idle := now.Add(-time.Minute), now.Add(time.Hour), now.Add(-48*time.Hour)
+	mockRepo := newMockRepo()
+	for i := 0; i < 7; i++ {
+		id := fmt.Sprintf("expired-%d", i)
+		mockRepo.putDeck(repo.Deck{Id: id, ExpiresAt: &expired, CreatedAt: now, UpdatedAt: now})
+	}
+	mockRepo.putDeck(repo.Deck{Id: "live", ExpiresAt: &later, CreatedAt: now, UpdatedAt: now})
+	mockRepo.putDeck(repo.Deck{Id: "idle", CreatedAt: idle, UpdatedAt: idle})
+
+	// Test case: without a max idle time only expired decks go, batch after batch
+	deleted, err := NewDeckReaper(mockRepo, time.Minute, 0, 3).Reap(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 7, deleted)
+	assert.ElementsMatch(t, []string{"live", "idle"}, storedDeckIds(mockRepo))
+
+	// Test case: decks untouched for longer than the max idle time go too
+	deleted, err = NewDeckReaper(mockRepo, time.Minute, 24*time.Hour, 3).Reap(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	assert.Equal(t, []string{"live"}, storedDeckIds(mockRepo))
+
+	// Test case: a cancelled context stops before the next batch
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	mockRepo.putDeck(repo.Deck{Id: "expired", ExpiresAt: &expired})
+	deleted, err = NewDeckReaper(mockRepo, time.Minute, 0, 3).Reap(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, deleted)
+
+	// Test case: the database fails
+	mockRepo.DeckError = errors.New("connection refused")
+	_, err = NewDeckReaper(mockRepo, time.Minute, 0, 3).Reap(context.Background())
+
+	assert.EqualError(t, err, "connection refused")
+}
+
+func TestReaperRun(t *testing.T) {
+	expired := time.Now().UTC().Add(-time.Minute)
+	mockRepo := newMockRepo()
+	mockRepo.putDeck(repo.Deck{Id: "expired", ExpiresAt: &expired})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	// Test case: the reaper runs on its schedule and returns once cancelled
+	go func() {
+		NewDeckReaper(mockRepo, 10*time.Millisecond, 0, 10).Run(ctx)
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+
+	_, err := mockRepo.GetDeckById("expired")
+	assert.Equal(t, sql.ErrNoRows, err)
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	customErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/repo"
+	"github.com/google/uuid"
+	"net/http"
+	"time"
+)
+
+// maxSnapshots is the number of snapshots a deck can have
+const maxSnapshots = 20
+
+// CloneDeck creates a new deck with the cards of the deck in their order, its piles and its settings. The clone starts
+// its own history at version 0 and remembers the deck it was made from as its parent, games aren't cloned.
+func (s *deckService) CloneDeck(id string, req model.CloneDeckRequest) (*model.CreateDeckResponse, error) {
+	if err := validateOwnerAndTTL(req.Owner, req.TTL); err != nil {
+		return nil, err
+	}
+	parent, err := s.getDeck(id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	clone := *parent
+	clone.Id = uuid.New().String()
+	clone.ParentId = &parent.Id
+	clone.Version = 0
+	clone.ExpiresAt = expiryOf(req.TTL, now)
+	clone.CreatedAt, clone.UpdatedAt = now, now
+	if len(req.Owner) > 0 {
+		clone.Owner = req.Owner
+	}
+	for i := range clone.Piles {
+		clone.Piles[i].DeckId = clone.Id
+		clone.Piles[i].UpdatedAt = now
+	}
+	event, err := s.event(repo.CloneEvent, map[string]interface{}{"parent": parent.Id, "parent_version": parent.Version})
+	if err != nil {
+		return nil, err
+	}
+	if err = s.repo.CreateDeck(clone, event); err != nil {
+		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't save deck", err)
+	}
+	return toCreateDeckResponse(clone), nil
+}
+
+// SaveSnapshot saves the current state of the deck and its piles under the name, replacing an older snapshot of the
+// same name
+func (s *deckService) SaveSnapshot(id, name string) (*model.SnapshotResponse, error) {
+	if !validPileName.MatchString(name) {
+		return nil, customErr.New(http.StatusBadRequest, "snapshot name must be 1 - 50 letters, digits, - or _")
+	}
+	deck, err := s.getDeck(id)
+	if err != nil {
+		return nil, err
+	}
+	state, err := json.Marshal(repo.SnapshotState(*deck))
+	if err != nil {
+		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't encode snapshot", err)
+	}
+	snapshot := repo.DeckSnapshot{
+		DeckId:    deck.Id,
+		Name:      name,
+		Version:   deck.Version,
+		Actor:     s.actor,
+		State:     string(state),
+		CreatedAt: time.Now().UTC(),
+	}
+	err = s.repo.SaveSnapshot(snapshot, maxSnapshots)
+	if err == repo.ErrTooManySnapshots {
+		return nil, customErr.New(http.StatusBadRequest, fmt.Sprintf("a deck can have at most %d snapshots", maxSnapshots))
+	}
+	if err != nil {
+		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't save snapshot", err)
+	}
+	return toSnapshotResponse(snapshot), nil
+}
+
+func (s *deckService) ListSnapshots(id string) (*model.ListSnapshotsResponse, error) {
+	deck, err := s.getDeck(id)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := s.repo.ListSnapshots(deck.Id)
+	if err != nil {
+		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't get snapshots from the database", err)
+	}
+	res := &model.ListSnapshotsResponse{DeckId: deck.Id, Snapshots: []model.SnapshotResponse{}}
+	for _, snapshot := range snapshots {
+		res.Snapshots = append(res.Snapshots, *toSnapshotResponse(snapshot))
+	}
+	return res, nil
+}
+
+// RestoreSnapshot puts the cards of the deck and its piles back as they were when the snapshot was saved. Piles
+// created since stay, without cards. The restore is recorded on the deck's history, so it can be undone.
+func (s *deckService) RestoreSnapshot(id, name string) (*model.DeckStateResponse, error) {
+	deck, err := s.getDeck(id)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := s.getSnapshot(deck.Id, name)
+	if err != nil {
+		return nil, err
+	}
+	var changes repo.DeckChanges
+	if err = json.Unmarshal([]byte(snapshot.State), &changes); err != nil {
+		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't decode snapshot", err)
+	}
+	now := time.Now().UTC()
+	state := repo.Deck{Id: deck.Id}
+	changes.Apply(&state, now)
+	updated := restoreState(*deck, state)
+
+	payload := map[string]interface{}{"snapshot": snapshot.Name, "version": snapshot.Version}
+	event, err := s.event(repo.RestoreEvent, payload)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.saveDeck(updated, repo.RestoreEvent, payload); err != nil {
+		return nil, err
+	}
+	updated.Version++
+	event.DeckId, event.Number, event.CreatedAt = deck.Id, updated.Version+1, now
+	return toDeckStateResponse(updated, event, s.aceHigh)
+}
+
+func (s *deckService) DeleteSnapshot(id, name string) error {
+	deck, err := s.getDeck(id)
+	if err != nil {
+		return err
+	}
+	err = s.repo.DeleteSnapshot(deck.Id, name)
+	if err == sql.ErrNoRows {
+		return customErr.New(http.StatusNotFound, fmt.Sprintf("snapshot %s wasn't found", name))
+	}
+	if err != nil {
+		return customErr.Wrap(http.StatusInternalServerError, "couldn't delete snapshot", err)
+	}
+	return nil
+}
+
+func (s *deckService) getSnapshot(deckId, name string) (*repo.DeckSnapshot, error) {
+	snapshot, err := s.repo.GetSnapshot(deckId, name)
+	if err == sql.ErrNoRows {
+		return nil, customErr.New(http.StatusNotFound, fmt.Sprintf("snapshot %s wasn't found", name))
+	}
+	if err != nil {
+		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't get snapshot from the database", err)
+	}
+	return snapshot, nil
+}
+
+func toSnapshotResponse(snapshot repo.DeckSnapshot) *model.SnapshotResponse {
+	return &model.SnapshotResponse{
+		DeckId:    snapshot.DeckId,
+		Name:      snapshot.Name,
+		Version:   snapshot.Version,
+		Actor:     snapshot.Actor,
+		CreatedAt: snapshot.CreatedAt,
+	}
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"fmt"
+	customErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCloneDeck(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewSeededSource("clone"))
+	created, err := deckService.CreateDeck(model.CreateDeckRequest{Shuffled: true, Owner: "alice"})
+	assert.NoError(t, err)
+	id := created.DeckId
+	_, err = deckService.DrawCards(id, 2)
+	assert.NoError(t, err)
+	_, err = deckService.CreatePile(id, "hand")
+	assert.NoError(t, err)
+	_, err = deckService.MoveCards(id, "hand", model.MoveCardsRequest{Count: 3})
+	assert.NoError(t, err)
+	parent, err := deckService.GetDeckById(id)
+	assert.NoError(t, err)
+
+	// Test case: the clone has the remaining cards in their order, the piles and the owner of its parent
+	clone, err := deckService.WithActor("bob").CloneDeck(id, model.CloneDeckRequest{})
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, id, clone.DeckId)
+	assert.Equal(t, id, *clone.ParentId)
+	assert.Equal(t, "alice", clone.Owner)
+	assert.Nil(t, clone.ExpiresAt)
+	opened, err := deckService.GetDeckById(clone.DeckId)
+	assert.NoError(t, err)
+	assert.Equal(t, cardCodes(parent.Cards), cardCodes(opened.Cards))
+	assert.Equal(t, cardCodes(parent.Drawn), cardCodes(opened.Drawn))
+	assert.Equal(t, 0, opened.Version)
+	pile, err := deckService.GetPile(clone.DeckId, "hand")
+	assert.NoError(t, err)
+	assert.Equal(t, clone.DeckId, pile.DeckId)
+	assert.Equal(t, 3, pile.Remaining)
+
+	// Test case: the clone starts its own history, which rebuilds it
+	history, err := deckService.GetDeckHistory(clone.DeckId, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, history.Events, 1)
+	assert.Equal(t, "clone", history.Events[0].Type)
+	assert.Equal(t, "bob", history.Events[0].Actor)
+	assert.JSONEq(t, fmt.Sprintf(`{"parent": "%s", "parent_version": 3}`, id), string(history.Events[0].Payload))
+	state, err := deckService.GetDeckAt(clone.DeckId, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, cardCodes(parent.Cards), cardCodes(state.Deck.Cards))
+	assert.Equal(t, 3, state.Piles[0].Remaining)
+
+	// Test case: the clone and its parent change independently
+	_, err = deckService.DrawCards(clone.DeckId, 1)
+	assert.NoError(t, err)
+	unchanged, err := deckService.GetDeckById(id)
+	assert.NoError(t, err)
+	assert.Equal(t, parent.Remaining, unchanged.Remaining)
+
+	// Test case: clones are listed by their parent
+	other, err := deckService.CloneDeck(id, model.CloneDeckRequest{Owner: "carol", TTL: time.Hour})
+	assert.NoError(t, err)
+	assert.Equal(t, "carol", other.Owner)
+	assert.NotNil(t, other.ExpiresAt)
+	list, err := deckService.ListDecks(model.ListDecksRequest{ParentId: id})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{clone.DeckId, other.DeckId}, summaryIds(list.Decks))
+
+	// Test case: Invalid ttl
+	_, err = deckService.CloneDeck(id, model.CloneDeckRequest{TTL: -time.Hour})
+	assert.EqualError(t, err, "ttl must be positive and at most 720h0m0s")
+
+	// Test case: Unknown deck
+	_, err = deckService.CloneDeck("unknown", model.CloneDeckRequest{})
+	assert.Equal(t, http.StatusNotFound, err.(*customErr.Error).Kind())
+}
+
+func TestSnapshots(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewSeededSource("snapshots"))
+	created, err := deckService.CreateDeck(model.CreateDeckRequest{Shuffled: true})
+	assert.NoError(t, err)
+	id := created.DeckId
+	_, err = deckService.CreatePile(id, "hand")
+	assert.NoError(t, err)
+	_, err = deckService.MoveCards(id, "hand", model.MoveCardsRequest{Count: 2})
+	assert.NoError(t, err)
+	saved, err := deckService.GetDeckById(id)
+	assert.NoError(t, err)
+
+	// Test case: SaveSnapshot
+	snapshot, err := deckService.WithActor("alice").SaveSnapshot(id, "start")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "start", snapshot.Name)
+	assert.Equal(t, 2, snapshot.Version)
+	assert.Equal(t, "alice", snapshot.Actor)
+
+	_, err = deckService.DrawCards(id, 5)
+	assert.NoError(t, err)
+	_, err = deckService.DrawFromPile(id, "hand", 1)
+	assert.NoError(t, err)
+	_, err = deckService.CreatePile(id, "board")
+	assert.NoError(t, err)
+	_, err = deckService.MoveCards(id, "board", model.MoveCardsRequest{Count: 3})
+	assert.NoError(t, err)
+	_, err = deckService.ShuffleDeck(id, false)
+	assert.NoError(t, err)
+
+	// Test case: RestoreSnapshot puts back the cards in their order, the pile created since stays empty
+	state, err := deckService.RestoreSnapshot(id, "start")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "restore", state.Event.Type)
+	assert.Equal(t, 9, state.Event.Number)
+	assert.Equal(t, 8, state.Deck.Version)
+	assert.Equal(t, cardCodes(saved.Cards), cardCodes(state.Deck.Cards))
+	assert.Empty(t, state.Deck.Drawn)
+	assert.Len(t, state.Piles, 2)
+	assert.Equal(t, 2, state.Piles[0].Remaining)
+	assert.Empty(t, state.Piles[1].Cards)
+	opened, err := deckService.GetDeckById(id)
+	assert.NoError(t, err)
+	assert.Equal(t, saved.Cards, opened.Cards)
+	assert.Equal(t, 8, opened.Version)
+
+	// Test case: the restore is on the history and can be undone
+	at, err := deckService.GetDeckAt(id, 9)
+	assert.NoError(t, err)
+	assert.Equal(t, cardCodes(saved.Cards), cardCodes(at.Deck.Cards))
+	undone, err := deckService.Undo(id, model.UndoRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "restore", undone.Event.Type)
+	assert.Len(t, undone.Deck.Drawn, 6)
+
+	// Test case: saving again replaces the snapshot of the same name
+	_, err = deckService.SaveSnapshot(id, "start")
+	assert.NoError(t, err)
+	_, err = deckService.SaveSnapshot(id, "later")
+	assert.NoError(t, err)
+	list, err := deckService.ListSnapshots(id)
+	assert.NoError(t, err)
+	assert.Len(t, list.Snapshots, 2)
+	assert.Equal(t, 9, list.Snapshots[0].Version)
+
+	// Test case: DeleteSnapshot
+	assert.NoError(t, deckService.DeleteSnapshot(id, "later"))
+	err = deckService.DeleteSnapshot(id, "later")
+	assert.Equal(t, http.StatusNotFound, err.(*customErr.Error).Kind())
+	_, err = deckService.RestoreSnapshot(id, "later")
+	assert.EqualError(t, err, "snapshot later wasn't found")
+
+	// Test case: Invalid snapshot name
+	_, err = deckService.SaveSnapshot(id, "not valid")
+	assert.EqualError(t, err, "snapshot name must be 1 - 50 letters, digits, - or _")
+
+	// Test case: a deck has a limited number of snapshots
+	for i := len(list.Snapshots) - 1; i < maxSnapshots; i++ {
+		_, err = deckService.SaveSnapshot(id, fmt.Sprintf("snapshot-%d", i))
+		assert.NoError(t, err)
+	}
+	_, err = deckService.SaveSnapshot(id, "one-more")
+	assert.EqualError(t, err, fmt.Sprintf("a deck can have at most %d snapshots", maxSnapshots))
+	_, err = deckService.SaveSnapshot(id, "start")
+	assert.NoError(t, err)
+}
+
+func TestSaveSnapshotsConcurrently(t *testing.T) {
+	deckService := NewDeckService(newMockRepo(), NewCryptoSource())
+	created, err := deckService.CreateDeck(model.CreateDeckRequest{})
+	assert.NoError(t, err)
+	id := created.DeckId
+	for i := 1; i < maxSnapshots; i++ {
+		_, err = deckService.SaveSnapshot(id, fmt.Sprintf("snapshot-%d", i))
+		assert.NoError(t, err)
+	}
+
+	// Test case: of the saves racing for the last free place only one gets it
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = deckService.SaveSnapshot(id, fmt.Sprintf("racing-%d", i))
+		}(i)
+	}
+	wg.Wait()
+
+	saved := 0
+	for _, err := range errs {
+		if err == nil {
+			saved++
+		} else {
+			assert.Equal(t, http.StatusBadRequest, err.(*customErr.Error).Kind())
+		}
+	}
+	assert.Equal(t, 1, saved)
+	list, err := deckService.ListSnapshots(id)
+	assert.NoError(t, err)
+	assert.Len(t, list.Snapshots, maxSnapshots)
+}
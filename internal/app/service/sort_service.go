@@ -0,0 +1,84 @@
+package service
+
+import (
+	"fmt"
+	customErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/repo"
+	"net/http"
+	"sort"
+)
+
+// SortCards orders the given cards, like a hand being sorted before it's played
+func (s *deckService) SortCards(codes []string, req model.SortRequest) ([]model.Card, error) {
+	if len(codes) == 0 {
+		return nil, customErr.New(http.StatusBadRequest, "cards must be given")
+	}
+	if err := validateCodes(codes); err != nil {
+		return nil, err
+	}
+	sorted, err := sortCodes(codes, req)
+	if err != nil {
+		return nil, err
+	}
+	return toRankedCards(sorted, req.AceHigh)
+}
+
+// SortPile orders the cards of the pile from its top
+func (s *deckService) SortPile(id, name string, req model.SortRequest) (*model.PileResponse, error) {
+	deck, pile, err := s.getPile(id, name)
+	if err != nil {
+		return nil, err
+	}
+	if pile.Cards, err = sortCodes(pile.Cards, req); err != nil {
+		return nil, err
+	}
+	err = s.saveDeck(*deck, repo.SortPileEvent, map[string]interface{}{"pile": name, "by": req.By, "ace_high": req.AceHigh})
+	if err != nil {
+		return nil, err
+	}
+	return toPileResponse(*pile, req.AceHigh)
+}
+
+// sortCodes returns the codes ordered by suit then rank or by rank then suit. The sort is stable, so copies of a card
+// keep their order.
+func sortCodes(codes []string, req model.SortRequest) ([]string, error) {
+	by := req.By
+	if len(by) == 0 {
+		by = model.BySuit
+	}
+	if by != model.BySuit && by != model.ByRank {
+		return nil, customErr.New(http.StatusBadRequest, fmt.Sprintf("cards can be sorted by %s or %s", model.BySuit, model.ByRank))
+	}
+	suits := make(map[repo.SuitCode]int, len(repo.SequentialSuits))
+	for i, suit := range repo.SequentialSuits {
+		suits[suit] = i
+	}
+	rank := repo.Rank
+	if req.AceHigh {
+		rank = repo.AceHighRank
+	}
+	// key orders jokers after every other card and by their code
+	key := func(code string) [3]int {
+		if _, joker := repo.Jokers[repo.CardCode(code)]; joker {
+			return [3]int{1, 0, int(code[1])}
+		}
+		suit, value := suits[repo.SuitCode(code[len(code)-1:])], rank(repo.CardCode(code[:len(code)-1]))
+		if by == model.ByRank {
+			return [3]int{0, value, suit}
+		}
+		return [3]int{0, suit, value}
+	}
+
+	sorted := append([]string{}, codes...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := key(sorted[i]), key(sorted[j])
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return false
+	})
+	return sorted, nil
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/repo"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSortCards(t *testing.T) {
+	deckService := NewDeckService(newMockRepo(), NewCryptoSource())
+	hand := []string{"X1", "KH", "2S", "AH", "10D", "2H", "AS"}
+
+	// Test case: by suit then rank, aces low by default and jokers last
+	cards, err := deckService.SortCards(hand, model.SortRequest{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"AS", "2S", "10D", "AH", "2H", "KH", "X1"}, cardCodes(cards))
+	assert.Equal(t, 1, cards[0].Rank)
+
+	// Test case: by rank then suit with aces high
+	cards, err = deckService.SortCards(hand, model.SortRequest{By: model.ByRank, AceHigh: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2S", "2H", "10D", "KH", "AS", "AH", "X1"}, cardCodes(cards))
+	assert.Equal(t, 14, cards[4].Rank)
+	assert.Equal(t, 0, cards[6].Rank)
+
+	// Test case: invalid requests
+	cards, err = deckService.SortCards(hand, model.SortRequest{By: "color"})
+
+	assert.EqualError(t, err, "cards can be sorted by suit or rank")
+	assert.Nil(t, cards)
+	cards, err = deckService.SortCards([]string{"1S"}, model.SortRequest{})
+
+	assert.EqualError(t, err, "contains invalid card code")
+	assert.Nil(t, cards)
+}
+
+func TestSortPile(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+	deckID := "existing_deck_id"
+	mockRepo.putDeck(repo.Deck{
+		Id:    deckID,
+		Piles: []repo.Pile{{DeckId: deckID, Name: "hand", Cards: []string{"QC", "AD", "3C", "QD"}}},
+	})
+
+	// Test case: the pile keeps its new order
+	res, err := deckService.SortPile(deckID, "hand", model.SortRequest{By: model.ByRank, AceHigh: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"3C", "QD", "QC", "AD"}, cardCodes(res.Cards))
+	assert.Equal(t, []string{"3C", "QD", "QC", "AD"}, []string(mockRepo.deck(deckID).Piles[0].Cards))
+	assert.Equal(t, 14, res.Cards[3].Rank)
+
+	// Test case: unknown pile
+	res, err = deckService.SortPile(deckID, "board", model.SortRequest{})
+
+	assert.EqualError(t, err, "pile board wasn't found")
+	assert.Nil(t, res)
+}
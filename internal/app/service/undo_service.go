@@ -0,0 +1,256 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	customErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/repo"
+	"net/http"
+	"strings"
+)
+
+// DefaultUndoDepth is the number of operations of a deck that can be undone in a row, unless configured otherwise
+const DefaultUndoDepth = 10
+
+// undoPayload tells which event an undo reverted, the deck is restored as it was after the Restores event
+type undoPayload struct {
+	Event    int `json:"event"`
+	Restores int `json:"restores"`
+}
+
+// undoEventsPage is the number of events an undo loads at a time, going back from the latest one
+const undoEventsPage = 20
+
+// Undo reverts the last operation on the deck which wasn't undone yet, restoring the exact order of its cards and
+// piles. Undoing again goes further back, up to the undo depth, 0 turns undo off. Piles created since stay, without
+// cards. The undo is saved like any other operation, so it fails with a conflict when the deck changed while it was
+// being undone.
+func (s *deckService) Undo(id string, req model.UndoRequest) (*model.DeckStateResponse, error) {
+	deck, err := s.getDeck(id)
+	if err != nil {
+		return nil, err
+	}
+	if s.undoDepth == 0 {
+		return nil, customErr.New(http.StatusBadRequest, "undo is turned off")
+	}
+	if req.Version != nil && *req.Version != deck.Version {
+		return nil, customErr.New(http.StatusConflict,
+			fmt.Sprintf("deck is at version %d, not %d, it was modified by another request", deck.Version, *req.Version))
+	}
+	history := &backHistory{s: s, deck: *deck}
+	latest := deck.Version + 1
+	undone := 0
+	for n := latest; n >= 1; n-- {
+		e, err := history.event(n)
+		if err != nil {
+			return nil, err
+		}
+		if e.Type != repo.UndoEvent {
+			break
+		}
+		undone++
+	}
+	if undone >= s.undoDepth {
+		return nil, customErr.New(http.StatusBadRequest,
+			fmt.Sprintf("only the last %d operations can be undone", s.undoDepth))
+	}
+	// undone operations are skipped, the state after an undo is the state after the event it restored
+	target := latest
+	for {
+		e, err := history.event(target)
+		if err != nil {
+			return nil, err
+		}
+		if e.Type.StartsHistory() {
+			return nil, customErr.New(http.StatusBadRequest, "there is no operation to undo")
+		}
+		if e.Type != repo.UndoEvent {
+			break
+		}
+		var payload undoPayload
+		if err = json.Unmarshal([]byte(e.Payload), &payload); err != nil {
+			return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't decode deck event", err)
+		}
+		target = payload.Restores
+		if target < 1 {
+			return nil, customErr.New(http.StatusBadRequest, "there is no operation to undo")
+		}
+	}
+
+	restored, err := history.stateAfter(target - 1)
+	if err != nil {
+		return nil, err
+	}
+	updated := restoreState(*deck, restored)
+	err = s.saveDeck(updated, repo.UndoEvent, map[string]interface{}{"event": target, "restores": target - 1})
+	if err != nil {
+		return nil, err
+	}
+	updated.Version++
+	undoneEvent, err := history.event(target)
+	if err != nil {
+		return nil, err
+	}
+	return toDeckStateResponse(updated, undoneEvent, s.aceHigh)
+}
+
+// backHistory walks the history of a deck back from its latest event, loading the events a page at a time so an undo
+// only reads the events it needs
+type backHistory struct {
+	s    *deckService
+	deck repo.Deck
+	// events are the events loaded so far, up to the latest one
+	events []repo.DeckEvent
+}
+
+// event returns the event with the given number, loading the pages of events before the loaded ones when needed
+func (h *backHistory) event(number int) (repo.DeckEvent, error) {
+	for len(h.events) == 0 || number < h.events[0].Number {
+		first := h.deck.Version + 2
+		if len(h.events) > 0 {
+			first = h.events[0].Number
+		}
+		after := first - 1 - undoEventsPage
+		if after < 0 {
+			after = 0
+		}
+		page, err := h.s.repo.GetDeckEvents(h.deck.Id, after, first-1-after)
+		if err != nil {
+			return repo.DeckEvent{}, customErr.Wrap(http.StatusInternalServerError,
+				"couldn't get deck history from the database", err)
+		}
+		if number < 1 || len(page) != first-1-after {
+			// decks created before their history was recorded start with an import of their state back then
+			return repo.DeckEvent{}, customErr.New(http.StatusNotFound,
+				fmt.Sprintf("event %d of deck %s wasn't recorded", number, h.deck.Id))
+		}
+		h.events = append(page, h.events...)
+	}
+	return h.events[number-h.events[0].Number], nil
+}
+
+// changes decodes the changes of the event with the given number
+func (h *backHistory) changes(number int) (repo.DeckEvent, repo.DeckChanges, error) {
+	var changes repo.DeckChanges
+	e, err := h.event(number)
+	if err != nil {
+		return e, changes, err
+	}
+	if err = json.Unmarshal([]byte(e.Changes), &changes); err != nil {
+		return e, changes, customErr.Wrap(http.StatusInternalServerError, "couldn't decode deck event", err)
+	}
+	return e, changes, nil
+}
+
+// stateAfter rebuilds the deck as it was right after the event with the given number. The fields changed since then
+// get back the value of their last change up to that event, the history is read back only as far as that, at most to
+// the event it starts with, which holds the whole state of the deck.
+func (h *backHistory) stateAfter(number int) (repo.Deck, error) {
+	missing := map[string]bool{}
+	for n := h.deck.Version + 1; n > number; n-- {
+		_, changes, err := h.changes(n)
+		if err != nil {
+			return repo.Deck{}, err
+		}
+		for _, field := range changedFields(changes) {
+			missing[field] = true
+		}
+	}
+	var found repo.DeckChanges
+	for n := number; len(missing) > 0; n-- {
+		e, changes, err := h.changes(n)
+		if err != nil {
+			return repo.Deck{}, err
+		}
+		fields := changedFields(changes)
+		if e.Type.StartsHistory() {
+			// the fields the deck started with empty aren't in the changes
+			fields = fields[:0]
+			for field := range missing {
+				fields = append(fields, field)
+			}
+		}
+		for _, field := range fields {
+			if missing[field] {
+				copyField(&found, changes, field)
+				delete(missing, field)
+			}
+		}
+	}
+	state := h.deck
+	state.Piles = append([]repo.Pile{}, h.deck.Piles...)
+	found.Apply(&state, h.deck.UpdatedAt)
+	return state, nil
+}
+
+// changedFields names the fields of the deck the changes set, a pile by its name after "pile:"
+func changedFields(changes repo.DeckChanges) []string {
+	var fields []string
+	if changes.Cards != nil {
+		fields = append(fields, "cards")
+	}
+	if changes.Drawn != nil {
+		fields = append(fields, "drawn")
+	}
+	if changes.Discarded != nil {
+		fields = append(fields, "discarded")
+	}
+	if changes.Remaining != nil {
+		fields = append(fields, "remaining")
+	}
+	if changes.Size != nil {
+		fields = append(fields, "size")
+	}
+	if changes.Shuffled != nil {
+		fields = append(fields, "shuffled")
+	}
+	if changes.ShuffledAt != nil || changes.ClearShuffledAt {
+		fields = append(fields, "shuffled_at")
+	}
+	for name := range changes.Piles {
+		fields = append(fields, "pile:"+name)
+	}
+	return fields
+}
+
+// copyField copies the field of the changes from into to, its empty value when from doesn't set it
+func copyField(to *repo.DeckChanges, from repo.DeckChanges, field string) {
+	empty := 0
+	switch field {
+	case "cards":
+		to.Cards = orEmptyCards(from.Cards)
+	case "drawn":
+		to.Drawn = orEmptyCards(from.Drawn)
+	case "discarded":
+		to.Discarded = orEmptyCards(from.Discarded)
+	case "remaining":
+		to.Remaining = from.Remaining
+		if to.Remaining == nil {
+			to.Remaining = &empty
+		}
+	case "size":
+		to.Size = from.Size
+		if to.Size == nil {
+			to.Size = &empty
+		}
+	case "shuffled":
+		shuffled := from.Shuffled != nil && *from.Shuffled
+		to.Shuffled = &shuffled
+	case "shuffled_at":
+		to.ShuffledAt, to.ClearShuffledAt = from.ShuffledAt, from.ShuffledAt == nil
+	default:
+		if to.Piles == nil {
+			to.Piles = make(map[string][]string)
+		}
+		name := strings.TrimPrefix(field, "pile:")
+		to.Piles[name] = append([]string{}, from.Piles[name]...)
+	}
+}
+
+func orEmptyCards(cards *[]string) *[]string {
+	if cards == nil {
+		return &[]string{}
+	}
+	return cards
+}
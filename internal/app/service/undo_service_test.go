@@ -0,0 +1,208 @@
+package service
+
+import (
+	customErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestUndo(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewSeededSource("undo"))
+	created, err := deckService.CreateDeck(model.CreateDeckRequest{Shuffled: true, Seed: "undo"})
+	assert.NoError(t, err)
+	id := created.DeckId
+	initial, err := deckService.GetDeckById(id)
+	assert.NoError(t, err)
+
+	// Test case: nothing was done to a new deck
+	state, err := deckService.Undo(id, model.UndoRequest{})
+
+	assert.EqualError(t, err, "there is no operation to undo")
+	assert.Nil(t, state)
+
+	_, err = deckService.DrawCards(id, 5)
+	assert.NoError(t, err)
+	drawn, err := deckService.GetDeckById(id)
+	assert.NoError(t, err)
+	_, err = deckService.CreatePile(id, "hand")
+	assert.NoError(t, err)
+	_, err = deckService.MoveCards(id, "hand", model.MoveCardsRequest{Count: 3})
+	assert.NoError(t, err)
+	_, err = deckService.ShuffleDeck(id, true)
+	assert.NoError(t, err)
+
+	// Test case: undoing a shuffle restores the exact order, with the cards back in their pile
+	state, err = deckService.Undo(id, model.UndoRequest{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "shuffle", state.Event.Type)
+	assert.Equal(t, 44, state.Deck.Remaining)
+	assert.Equal(t, cardCodes(drawn.Cards[3:]), cardCodes(state.Deck.Cards))
+	assert.Equal(t, cardCodes(drawn.Cards[:3]), cardCodes(state.Piles[0].Cards))
+	assert.Equal(t, cardCodes(state.Deck.Cards), []string(mockRepo.deck(id).Cards))
+	assert.Equal(t, 5, state.Deck.Version)
+
+	// Test case: undoing again goes further back, the pile stays without cards
+	state, err = deckService.Undo(id, model.UndoRequest{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "move_cards", state.Event.Type)
+	assert.Equal(t, cardCodes(drawn.Cards), cardCodes(state.Deck.Cards))
+	assert.Empty(t, state.Piles[0].Cards)
+	state, err = deckService.Undo(id, model.UndoRequest{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "create_pile", state.Event.Type)
+	state, err = deckService.Undo(id, model.UndoRequest{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "draw", state.Event.Type)
+	assert.Equal(t, cardCodes(initial.Cards), cardCodes(state.Deck.Cards))
+	assert.Empty(t, state.Deck.Drawn)
+	assert.Equal(t, 52, mockRepo.deck(id).Remaining)
+
+	// Test case: the undos are recorded and the deck can't go back further than its creation
+	state, err = deckService.Undo(id, model.UndoRequest{})
+
+	assert.EqualError(t, err, "there is no operation to undo")
+	assert.Nil(t, state)
+	history, err := deckService.GetDeckHistory(id, 5, 0)
+	assert.NoError(t, err)
+	assert.Len(t, history.Events, 4)
+	assert.Equal(t, "undo", history.Events[0].Type)
+	assert.JSONEq(t, `{"event":5,"restores":4}`, string(history.Events[0].Payload))
+}
+
+func TestUndoAfterNewOperation(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+	created, err := deckService.CreateDeck(model.CreateDeckRequest{})
+	assert.NoError(t, err)
+	id := created.DeckId
+	_, err = deckService.DrawCards(id, 1)
+	assert.NoError(t, err)
+	_, err = deckService.DrawCards(id, 2)
+	assert.NoError(t, err)
+	_, err = deckService.Undo(id, model.UndoRequest{})
+	assert.NoError(t, err)
+	_, err = deckService.CutDeck(id, 10)
+	assert.NoError(t, err)
+
+	// Test case: an operation after an undo is undone first, then the one before the undone one
+	state, err := deckService.Undo(id, model.UndoRequest{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "cut", state.Event.Type)
+	assert.Equal(t, "2S", state.Deck.Cards[0].Code)
+	assert.Equal(t, 51, state.Deck.Remaining)
+	state, err = deckService.Undo(id, model.UndoRequest{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "draw", state.Event.Type)
+	assert.Equal(t, 2, state.Event.Number)
+	assert.Equal(t, 52, state.Deck.Remaining)
+
+	// Test case: undone operations aren't undone twice
+	state, err = deckService.Undo(id, model.UndoRequest{})
+
+	assert.EqualError(t, err, "there is no operation to undo")
+	assert.Nil(t, state)
+
+	// Test case: undoing the first shuffle of the deck clears its shuffle time, in the history too
+	_, err = deckService.ShuffleDeck(id, true)
+	assert.NoError(t, err)
+	state, err = deckService.Undo(id, model.UndoRequest{})
+
+	assert.NoError(t, err)
+	assert.Nil(t, state.Deck.ShuffledAt)
+	assert.Nil(t, mockRepo.deck(id).ShuffledAt)
+	replayed, err := deckService.GetDeckAt(id, state.Deck.Version+1)
+	assert.NoError(t, err)
+	assert.Nil(t, replayed.Deck.ShuffledAt)
+}
+
+func TestUndoDepthAndVersion(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource()).WithUndoDepth(2)
+	created, err := deckService.CreateDeck(model.CreateDeckRequest{})
+	assert.NoError(t, err)
+	id := created.DeckId
+	for i := 0; i < 3; i++ {
+		_, err = deckService.DrawCards(id, 1)
+		assert.NoError(t, err)
+	}
+
+	// Test case: an undo bound to an older version doesn't undo somebody else's draw
+	version := 2
+	state, err := deckService.Undo(id, model.UndoRequest{Version: &version})
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, err.(*customErr.Error).Kind())
+	assert.Nil(t, state)
+
+	// Test case: only depth operations can be undone in a row
+	version = 3
+	_, err = deckService.Undo(id, model.UndoRequest{Version: &version})
+	assert.NoError(t, err)
+	_, err = deckService.Undo(id, model.UndoRequest{})
+	assert.NoError(t, err)
+	state, err = deckService.Undo(id, model.UndoRequest{})
+
+	assert.EqualError(t, err, "only the last 2 operations can be undone")
+	assert.Nil(t, state)
+	assert.Equal(t, 51, mockRepo.deck(id).Remaining)
+
+	// Test case: undo is turned off with a depth of 0
+	state, err = deckService.WithUndoDepth(0).Undo(id, model.UndoRequest{})
+
+	assert.EqualError(t, err, "undo is turned off")
+	assert.Equal(t, http.StatusBadRequest, err.(*customErr.Error).Kind())
+	assert.Nil(t, state)
+
+	// Test case: an undo racing with a draw fails with a conflict instead of losing the draw
+	conflictRepo := &conflictingRepo{MockRepo: mockRepo}
+	_, err = NewDeckService(conflictRepo, NewCryptoSource()).Undo(id, model.UndoRequest{})
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, err.(*customErr.Error).Kind())
+	assert.Equal(t, 51, mockRepo.deck(id).Remaining)
+}
+
+func TestUndoReadsHistoryBack(t *testing.T) {
+	mockRepo := newMockRepo()
+	deckService := NewDeckService(mockRepo, NewCryptoSource())
+	created, err := deckService.CreateDeck(model.CreateDeckRequest{})
+	assert.NoError(t, err)
+	id := created.DeckId
+	_, err = deckService.CreatePile(id, "hand")
+	assert.NoError(t, err)
+	_, err = deckService.MoveCards(id, "hand", model.MoveCardsRequest{Count: 1})
+	assert.NoError(t, err)
+	for i := 0; i < 2*undoEventsPage; i++ {
+		_, err = deckService.DrawCards(id, 1)
+		assert.NoError(t, err)
+	}
+
+	// Test case: undoing a draw reads the last page of the history only
+	mockRepo.EventsLoaded = 0
+	state, err := deckService.Undo(id, model.UndoRequest{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "draw", state.Event.Type)
+	assert.Equal(t, 52-1-2*undoEventsPage+1, state.Deck.Remaining)
+	assert.Equal(t, undoEventsPage, mockRepo.EventsLoaded)
+
+	// Test case: a pile last changed long ago gets back its cards of back then
+	_, err = deckService.MoveCards(id, "hand", model.MoveCardsRequest{Count: 2})
+	assert.NoError(t, err)
+	state, err = deckService.Undo(id, model.UndoRequest{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "move_cards", state.Event.Type)
+	assert.Equal(t, []string{"AS"}, cardCodes(state.Piles[0].Cards))
+	assert.Equal(t, 52-1-2*undoEventsPage+1, state.Deck.Remaining)
+	assert.Equal(t, []string{"AS"}, []string(mockRepo.deck(id).Piles[0].Cards))
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	customErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/repo"
+	"github.com/deck/internal/app/war"
+	"github.com/golang/glog"
+	"github.com/google/uuid"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultWarRounds = 1000
+	maxWarRounds     = 10000
+	// maxWarRoundsPlayed is the most rounds a request plays, a longer game is finished over several requests
+	maxWarRoundsPlayed = 500
+)
+
+type WarService interface {
+	CreateGame(req model.CreateWarRequest) (*model.WarGameResponse, error)
+	GetGame(id string) (*model.WarGameResponse, error)
+	GetRound(id string, number int) (*model.WarRoundResponse, error)
+	PlayRounds(id string, count int, finish bool) (*model.WarGameResponse, error)
+}
+
+type warService struct {
+	repo  repo.GameRepo
+	decks DeckService
+}
+
+func NewWarService(repo repo.GameRepo, decks DeckService) WarService {
+	return &warService{repo: repo, decks: decks}
+}
+
+// CreateGame shuffles a new deck and splits it between the two players' piles
+func (s *warService) CreateGame(req model.CreateWarRequest) (*model.WarGameResponse, error) {
+	maxRounds := req.MaxRounds
+	if maxRounds == 0 {
+		maxRounds = defaultWarRounds
+	}
+	if maxRounds < 1 || maxRounds > maxWarRounds {
+		return nil, customErr.New(http.StatusBadRequest, fmt.Sprintf("max rounds must be between 1 - %d", maxWarRounds))
+	}
+	// the game is the actor of the operations on its deck
+	id := uuid.New().String()
+	decks := s.decks.WithActor(id)
+	deck, err := decks.CreateDeck(model.CreateDeckRequest{Shuffled: true, Seed: req.Seed})
+	if err != nil {
+		return nil, err
+	}
+	// the deck of a game that wasn't saved would be left behind
+	fail := func(err error) error {
+		if deleteErr := decks.DeleteDeck(deck.DeckId); deleteErr != nil {
+			glog.Errorf("couldn't delete deck with id %s: %s", deck.DeckId, deleteErr)
+		}
+		return err
+	}
+	for _, player := range war.Players {
+		for _, name := range []string{player, war.WonPile(player)} {
+			if _, err = decks.CreatePile(deck.DeckId, name); err != nil {
+				return nil, fail(err)
+			}
+		}
+	}
+	state, err := war.Deal(warPiles{decks: decks, deckId: deck.DeckId}, deck.Remaining, maxRounds)
+	if err != nil {
+		return nil, fail(toGameError(err, war.ErrGameOver))
+	}
+
+	stateJson, err := json.Marshal(state)
+	if err != nil {
+		return nil, fail(customErr.Wrap(http.StatusInternalServerError, "couldn't encode game", err))
+	}
+	now := time.Now().UTC()
+	game := repo.Game{
+		Id:        id,
+		GameType:  repo.WarGame,
+		DeckId:    deck.DeckId,
+		State:     string(stateJson),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err = s.repo.CreateGame(game); err != nil {
+		return nil, fail(customErr.Wrap(http.StatusInternalServerError, "couldn't save game", err))
+	}
+	return toWarResponse(game, state, nil)
+}
+
+func (s *warService) GetGame(id string) (*model.WarGameResponse, error) {
+	game, state, err := s.getGame(id)
+	if err != nil {
+		return nil, err
+	}
+	return toWarResponse(*game, state, nil)
+}
+
+// GetRound returns a round played before, numbered from 1. Only the last rounds of a game are kept.
+func (s *warService) GetRound(id string, number int) (*model.WarRoundResponse, error) {
+	_, state, err := s.getGame(id)
+	if err != nil {
+		return nil, err
+	}
+	if number < 1 || number > state.Played {
+		return nil, customErr.New(http.StatusNotFound, fmt.Sprintf("round %d wasn't played", number))
+	}
+	round, found := state.Round(number)
+	if !found {
+		return nil, customErr.New(http.StatusNotFound,
+			fmt.Sprintf("round %d isn't kept anymore, only the last %d rounds are", number, war.KeptRounds))
+	}
+	return toWarRoundResponse(*round)
+}
+
+// PlayRounds plays count rounds, or with finish every round until the game is over, at most maxWarRoundsPlayed of
+// them. The game ends early when a player runs out of cards. Every round is played on the piles of the game's deck
+// with the deck service, the way a client playing through the pile endpoints would.
+func (s *warService) PlayRounds(id string, count int, finish bool) (*model.WarGameResponse, error) {
+	if finish {
+		count = maxWarRoundsPlayed
+	}
+	if count < 1 || count > maxWarRoundsPlayed {
+		return nil, customErr.New(http.StatusBadRequest, fmt.Sprintf("count must be between 1 - %d", maxWarRoundsPlayed))
+	}
+	game, state, err := s.getGame(id)
+	if err != nil {
+		return nil, err
+	}
+	if state.Finished {
+		return nil, toGameError(war.ErrGameOver, war.ErrGameOver)
+	}
+
+	piles := warPiles{decks: s.decks.WithActor(game.Id), deckId: game.DeckId}
+	if err = state.Sync(piles); err != nil {
+		return nil, err
+	}
+	var rounds []war.Round
+	for !state.Finished && len(rounds) < count {
+		round, err := state.PlayRound(piles)
+		if err != nil {
+			return nil, toGameError(err, war.ErrGameOver)
+		}
+		rounds = append(rounds, *round)
+	}
+
+	stateJson, err := json.Marshal(state)
+	if err != nil {
+		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't encode game", err)
+	}
+	game.State = string(stateJson)
+	err = s.repo.UpdateGame(*game)
+	if err == repo.ErrVersionConflict {
+		return nil, customErr.Wrap(http.StatusConflict, "game was modified by another request, please retry", err)
+	}
+	if err != nil {
+		return nil, customErr.Wrap(http.StatusInternalServerError, "couldn't update game", err)
+	}
+	game.Version++
+	return toWarResponse(*game, state, rounds)
+}
+
+func (s *warService) getGame(id string) (*repo.Game, *war.Game, error) {
+	game, err := s.repo.GetGameById(id)
+	if err == sql.ErrNoRows {
+		return nil, nil, customErr.New(http.StatusNotFound, fmt.Sprintf("game with id %s wasn't found", id))
+	}
+	if err != nil {
+		return nil, nil, customErr.Wrap(http.StatusInternalServerError, "couldn't get game from the database", err)
+	}
+	if game.GameType != repo.WarGame {
+		return nil, nil, customErr.New(http.StatusNotFound, fmt.Sprintf("war game with id %s wasn't found", id))
+	}
+	var state war.Game
+	if err = json.Unmarshal([]byte(game.State), &state); err != nil {
+		return nil, nil, customErr.Wrap(http.StatusInternalServerError, "couldn't decode game", err)
+	}
+	return game, &state, nil
+}
+
+// warPiles plays war with the piles of the game's deck, every move goes through the deck service like a client's would
+type warPiles struct {
+	decks  DeckService
+	deckId string
+}
+
+func (p warPiles) Draw(pile string, count int) ([]string, error) {
+	cards, err := p.decks.DrawFromPile(p.deckId, pile, count)
+	if err != nil {
+		return nil, err
+	}
+	return toCodes(cards), nil
+}
+
+// Put returns the drawn cards to the deck and moves them from there onto the pile
+func (p warPiles) Put(pile string, cards []string) error {
+	if _, err := p.decks.ReturnCards(p.deckId, model.ReturnCardsRequest{Cards: cards, Position: model.Top}); err != nil {
+		return err
+	}
+	_, err := p.decks.MoveCards(p.deckId, pile, model.MoveCardsRequest{Cards: cards})
+	return err
+}
+
+func (p warPiles) Shuffle(pile string) error {
+	_, err := p.decks.ShufflePile(p.deckId, pile)
+	return err
+}
+
+func (p warPiles) Move(from, to string, count int) error {
+	if count == 0 {
+		return nil
+	}
+	_, err := p.decks.MoveCards(p.deckId, to, model.MoveCardsRequest{From: from, Count: count})
+	return err
+}
+
+func (p warPiles) Count(pile string) (int, error) {
+	res, err := p.decks.GetPile(p.deckId, pile)
+	if err != nil {
+		return 0, err
+	}
+	return res.Remaining, nil
+}
+
+func toWarResponse(game repo.Game, state *war.Game, rounds []war.Round) (*model.WarGameResponse, error) {
+	played := make([]model.WarRoundResponse, len(rounds))
+	for i, r := range rounds {
+		round, err := toWarRoundResponse(r)
+		if err != nil {
+			return nil, err
+		}
+		played[i] = *round
+	}
+	return &model.WarGameResponse{
+		GameId:    game.Id,
+		DeckId:    game.DeckId,
+		MaxRounds: state.MaxRounds,
+		Round:     state.Played,
+		Counts:    warCounts([2]int{state.Count(0), state.Count(1)}),
+		Finished:  state.Finished,
+		Winner:    state.Winner,
+		Rounds:    played,
+	}, nil
+}
+
+func toWarRoundResponse(round war.Round) (*model.WarRoundResponse, error) {
+	cards := make(map[string][]model.Card, len(war.Players))
+	for p, player := range war.Players {
+		turned, err := toCards(round.Cards[p])
+		if err != nil {
+			return nil, err
+		}
+		cards[player] = turned
+	}
+	return &model.WarRoundResponse{
+		Number:   round.Number,
+		Cards:    cards,
+		FaceDown: round.FaceDown,
+		Wars:     round.Wars,
+		Winner:   round.Winner,
+		Won:      round.Won,
+		Counts:   warCounts(round.Counts),
+	}, nil
+}
+
+func warCounts(counts [2]int) map[string]int {
+	return map[string]int{war.Players[0]: counts[0], war.Players[1]: counts[1]}
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	customErr "github.com/deck/internal/app/error"
+	"github.com/deck/internal/app/model"
+	"github.com/deck/internal/app/repo"
+	"github.com/deck/internal/app/war"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+// warState decodes the stored state of the game
+func warState(t *testing.T, gameRepo *MockGameRepo, id string) war.Game {
+	var state war.Game
+	assert.NoError(t, json.Unmarshal([]byte(gameRepo.game(id).State), &state))
+	return state
+}
+
+// pileCards are the cards in each pile of the deck
+func pileCards(deck repo.Deck) map[string][]string {
+	cards := map[string][]string{}
+	for _, pile := range deck.Piles {
+		cards[pile.Name] = pile.Cards
+	}
+	return cards
+}
+
+// assertPiles checks that the players' piles hold the cards the game counts, all 52 of them
+func assertPiles(t *testing.T, deck repo.Deck, state war.Game) {
+	piles := pileCards(deck)
+	total := 0
+	for p, player := range war.Players {
+		assert.Len(t, piles[player], state.Hands[p])
+		assert.Len(t, piles[war.WonPile(player)], state.Won[p])
+		total += state.Count(p)
+	}
+	assert.Equal(t, 52, total)
+}
+
+func TestCreateWarGame(t *testing.T) {
+	decks, mockRepo, gameRepo := newGameDecks()
+	warService := NewWarService(gameRepo, decks)
+
+	// Test case: a shuffled deck is dealt onto the players' piles
+	game, err := warService.CreateGame(model.CreateWarRequest{Seed: "replay"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, game.MaxRounds)
+	assert.Equal(t, map[string]int{"player1": 26, "player2": 26}, game.Counts)
+	assert.Empty(t, game.Rounds)
+	deck := mockRepo.deck(game.DeckId)
+	assert.Equal(t, 0, deck.Remaining)
+	assert.Len(t, deck.Piles, 4)
+	state := warState(t, gameRepo, game.GameId)
+	assertPiles(t, deck, state)
+	assert.Equal(t, repo.WarGame, gameRepo.game(game.GameId).GameType)
+	history, err := decks.GetDeckHistory(game.DeckId, 0, 0)
+	assert.NoError(t, err)
+	for _, e := range history.Events {
+		assert.Equal(t, game.GameId, e.Actor)
+	}
+
+	// Test case: the same seed deals the same game
+	other, err := warService.CreateGame(model.CreateWarRequest{Seed: "replay"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, pileCards(deck), pileCards(mockRepo.deck(other.DeckId)))
+
+	// Test case: the deck of a game that couldn't be saved is deleted
+	gameRepo.GameError = errors.New("database is down")
+	game, err = warService.CreateGame(model.CreateWarRequest{})
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, err.(*customErr.Error).Kind())
+	assert.Nil(t, game)
+	gameRepo.GameError = nil
+	decksLeft, err := mockRepo.ListDecks(repo.DeckFilter{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, decksLeft, 2)
+
+	// Test case: invalid round cap
+	game, err = warService.CreateGame(model.CreateWarRequest{MaxRounds: 10001})
+
+	assert.EqualError(t, err, "max rounds must be between 1 - 10000")
+	assert.Nil(t, game)
+
+	// Test case: unknown game
+	game, err = warService.GetGame("unknown")
+
+	assert.EqualError(t, err, "game with id unknown wasn't found")
+	assert.Nil(t, game)
+}
+
+func TestPlayWarRounds(t *testing.T) {
+	decks, mockRepo, gameRepo := newGameDecks()
+	warService := NewWarService(gameRepo, decks)
+	game, err := warService.CreateGame(model.CreateWarRequest{MaxRounds: 2000, Seed: "replay"})
+	assert.NoError(t, err)
+
+	// Test case: play some rounds on the piles, they are saved with the game at once
+	played, err := warService.PlayRounds(game.GameId, 5, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, played.Round)
+	assert.Len(t, played.Rounds, 5)
+	assert.Equal(t, 52, played.Counts["player1"]+played.Counts["player2"])
+	assert.Equal(t, played.Rounds[4].Counts, played.Counts)
+	state := warState(t, gameRepo, game.GameId)
+	assert.Equal(t, played.Counts["player1"], state.Count(0))
+	assertPiles(t, mockRepo.deck(game.DeckId), state)
+	assert.Equal(t, 1, gameRepo.game(game.GameId).Version)
+
+	// Test case: a round is kept with the game
+	round, err := warService.GetRound(game.GameId, 3)
+
+	assert.NoError(t, err)
+	assert.Equal(t, played.Rounds[2], *round)
+	round, err = warService.GetRound(game.GameId, 6)
+
+	assert.EqualError(t, err, "round 6 wasn't played")
+	assert.Nil(t, round)
+
+	// Test case: finishing plays at most 500 rounds a request, only the last rounds are kept
+	played, err = warService.PlayRounds(game.GameId, 0, true)
+
+	assert.NoError(t, err)
+	assert.True(t, played.Finished || played.Round == 505)
+	assert.Equal(t, played.Round-5, len(played.Rounds))
+	if played.Round > war.KeptRounds {
+		round, err = warService.GetRound(game.GameId, 3)
+
+		assert.EqualError(t, err, "round 3 isn't kept anymore, only the last 100 rounds are")
+		assert.Equal(t, http.StatusNotFound, err.(*customErr.Error).Kind())
+		assert.Nil(t, round)
+	}
+	for !played.Finished {
+		played, err = warService.PlayRounds(game.GameId, 0, true)
+		assert.NoError(t, err)
+	}
+	fetched, err := warService.GetGame(game.GameId)
+	assert.NoError(t, err)
+	assert.Equal(t, played.Winner, fetched.Winner)
+	assert.Empty(t, fetched.Rounds)
+	assertPiles(t, mockRepo.deck(game.DeckId), warState(t, gameRepo, game.GameId))
+	assert.LessOrEqual(t, len(warState(t, gameRepo, game.GameId).Rounds), war.KeptRounds)
+
+	// Test case: the game is over
+	played, err = warService.PlayRounds(game.GameId, 1, false)
+
+	assert.EqualError(t, err, "the game is already finished")
+	assert.Equal(t, http.StatusConflict, err.(*customErr.Error).Kind())
+	assert.Nil(t, played)
+
+	// Test case: invalid count
+	played, err = warService.PlayRounds(game.GameId, 0, false)
+
+	assert.EqualError(t, err, "count must be between 1 - 500")
+	assert.Nil(t, played)
+	played, err = warService.PlayRounds(game.GameId, 501, false)
+
+	assert.EqualError(t, err, "count must be between 1 - 500")
+	assert.Nil(t, played)
+}
+
+func TestPlayWarRoundsSeeded(t *testing.T) {
+	decks, mockRepo, gameRepo := newGameDecks()
+	warService := NewWarService(gameRepo, decks)
+
+	// Test case: the same seed plays the same game, however the rounds are split between requests
+	first, err := warService.CreateGame(model.CreateWarRequest{Seed: "replay"})
+	assert.NoError(t, err)
+	second, err := warService.CreateGame(model.CreateWarRequest{Seed: "replay"})
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = warService.PlayRounds(first.GameId, 20, false)
+		assert.NoError(t, err)
+	}
+	_, err = warService.PlayRounds(second.GameId, 60, false)
+	assert.NoError(t, err)
+
+	assert.Equal(t, warState(t, gameRepo, first.GameId), warState(t, gameRepo, second.GameId))
+	assert.Equal(t, pileCards(mockRepo.deck(first.DeckId)), pileCards(mockRepo.deck(second.DeckId)))
+}
+
+func TestPlayWarRoundsConcurrently(t *testing.T) {
+	decks, mockRepo, gameRepo := newGameDecks()
+	warService := NewWarService(gameRepo, decks)
+	game, err := warService.CreateGame(model.CreateWarRequest{})
+	assert.NoError(t, err)
+	state := warState(t, gameRepo, game.GameId)
+
+	// Test case: another request changed the game meanwhile, the game stays as it was
+	conflictRepo := &conflictingGameRepo{MockGameRepo: gameRepo}
+	played, err := NewWarService(conflictRepo, decks).PlayRounds(game.GameId, 1, false)
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, err.(*customErr.Error).Kind())
+	assert.Nil(t, played)
+	assert.Equal(t, state, warState(t, gameRepo, game.GameId))
+
+	// Test case: the next request counts the cards of the piles again, none get lost
+	played, err = warService.PlayRounds(game.GameId, 1, false)
+
+	assert.NoError(t, err)
+	assertPiles(t, mockRepo.deck(game.DeckId), warState(t, gameRepo, game.GameId))
+}
@@ -0,0 +1,254 @@
+package war
+
+import (
+	"errors"
+	"fmt"
+	"github.com/deck/internal/app/repo"
+)
+
+// FaceDown is the number of cards each player puts face down in a war before turning the next card
+const FaceDown = 3
+
+// KeptRounds is the number of the last rounds a game keeps
+const KeptRounds = 100
+
+// Players are the names of the two players, each has a pile to play from and a pile of the cards they won
+var Players = [2]string{"player1", "player2"}
+
+var ErrGameOver = errors.New("the game is already finished")
+
+// Piles holds the cards of the game. The empty pile name stands for the deck itself.
+type Piles interface {
+	// Draw takes count cards from the top of the pile
+	Draw(pile string, count int) ([]string, error)
+	// Put places drawn cards on the top of the pile
+	Put(pile string, cards []string) error
+	Shuffle(pile string) error
+	// Move moves count cards from the top of one pile to the top of another
+	Move(from, to string, count int) error
+	// Count is the number of cards in the pile
+	Count(pile string) (int, error)
+}
+
+// Round is one battle, and the wars that followed while the turned cards tied. Cards are the face up cards of each
+// player, Counts the cards each player holds after the round.
+type Round struct {
+	Number   int         `json:"number"`
+	Cards    [2][]string `json:"cards"`
+	FaceDown int         `json:"face_down"`
+	Wars     int         `json:"wars"`
+	Winner   string      `json:"winner"`
+	Won      int         `json:"won"`
+	Counts   [2]int      `json:"counts"`
+}
+
+// Game is a game of war between two players. Each plays from their Hand pile, once it's empty the cards they won are
+// shuffled into it. The game ends when a player runs out of cards or after MaxRounds, the one with more cards wins
+// then. Winner is empty for a draw. Hands and Won count the cards in the piles, Played counts the rounds and Rounds
+// keeps the last KeptRounds of them.
+type Game struct {
+	MaxRounds int     `json:"max_rounds"`
+	Hands     [2]int  `json:"hands"`
+	Won       [2]int  `json:"won"`
+	Played    int     `json:"played"`
+	Rounds    []Round `json:"rounds"`
+	Finished  bool    `json:"finished"`
+	Winner    string  `json:"winner"`
+}
+
+// WonPile is the name of the pile a player collects the cards they won on
+func WonPile(player string) string {
+	return player + "_won"
+}
+
+// Deal splits the cards of the deck between the two players, an odd card stays in the deck
+func Deal(piles Piles, cards int, maxRounds int) (*Game, error) {
+	if maxRounds <= 0 {
+		return nil, fmt.Errorf("max rounds must be positive")
+	}
+	if cards < 2 {
+		return nil, fmt.Errorf("war needs at least 2 cards, got %d", cards)
+	}
+	g := &Game{MaxRounds: maxRounds}
+	for p, player := range Players {
+		if err := piles.Move("", player, cards/2); err != nil {
+			return nil, err
+		}
+		g.Hands[p] = cards / 2
+	}
+	return g, nil
+}
+
+// Sync counts the cards in the players' piles again. The piles are the truth, a round played on them by a request
+// which couldn't save the game is counted then.
+func (g *Game) Sync(piles Piles) error {
+	for p, player := range Players {
+		hand, err := piles.Count(player)
+		if err != nil {
+			return err
+		}
+		won, err := piles.Count(WonPile(player))
+		if err != nil {
+			return err
+		}
+		g.Hands[p], g.Won[p] = hand, won
+	}
+	return nil
+}
+
+// Count is the number of cards the player holds
+func (g *Game) Count(player int) int {
+	return g.Hands[player] + g.Won[player]
+}
+
+// PlayRound turns the top card of both players, the higher card takes both. On a tie every player puts FaceDown
+// cards face down and turns the next one, until somebody wins. A player with too few cards for the war keeps their
+// last card to turn.
+func (g *Game) PlayRound(piles Piles) (*Round, error) {
+	if g.Finished {
+		return nil, ErrGameOver
+	}
+	round := Round{Number: g.Played + 1}
+	var pot []string
+	winner := -1
+	for winner < 0 {
+		var up [2]string
+		out := 0
+		for p := range Players {
+			if g.Count(p) == 0 {
+				out++
+				winner = 1 - p
+				continue
+			}
+			cards, err := g.take(piles, p, 1)
+			if err != nil {
+				return nil, err
+			}
+			up[p] = cards[0]
+		}
+		if out > 0 {
+			// a player can't turn a card during a war and loses the game
+			if out == 2 {
+				winner = -1
+			}
+			for p := range Players {
+				if len(up[p]) > 0 {
+					pot = append(pot, up[p])
+					round.Cards[p] = append(round.Cards[p], up[p])
+				}
+			}
+			break
+		}
+		for p := range Players {
+			pot = append(pot, up[p])
+			round.Cards[p] = append(round.Cards[p], up[p])
+		}
+
+		first, second := rank(up[0]), rank(up[1])
+		if first > second {
+			winner = 0
+		} else if second > first {
+			winner = 1
+		} else {
+			round.Wars++
+			for p := range Players {
+				count := FaceDown
+				if g.Count(p)-1 < count {
+					count = g.Count(p) - 1
+				}
+				if count <= 0 {
+					continue
+				}
+				cards, err := g.take(piles, p, count)
+				if err != nil {
+					return nil, err
+				}
+				pot = append(pot, cards...)
+				round.FaceDown += count
+			}
+		}
+	}
+
+	if winner >= 0 {
+		if err := piles.Put(WonPile(Players[winner]), pot); err != nil {
+			return nil, err
+		}
+		g.Won[winner] += len(pot)
+		round.Winner = Players[winner]
+		round.Won = len(pot)
+	}
+	round.Counts = [2]int{g.Count(0), g.Count(1)}
+	g.Played++
+	g.Rounds = append(g.Rounds, round)
+	if len(g.Rounds) > KeptRounds {
+		g.Rounds = g.Rounds[len(g.Rounds)-KeptRounds:]
+	}
+
+	switch {
+	case winner < 0:
+		g.finish("")
+	case g.Count(1-winner) == 0:
+		g.finish(Players[winner])
+	case g.Played >= g.MaxRounds:
+		g.finish(g.leader())
+	}
+	return &round, nil
+}
+
+// Round returns a round kept by the game, numbered from 1
+func (g *Game) Round(number int) (*Round, bool) {
+	first := g.Played - len(g.Rounds) + 1
+	if number < first || number > g.Played {
+		return nil, false
+	}
+	return &g.Rounds[number-first], true
+}
+
+// take draws count cards of the player, when their hand runs out the cards they won are shuffled into it
+func (g *Game) take(piles Piles, player int, count int) ([]string, error) {
+	name := Players[player]
+	var taken []string
+	for len(taken) < count {
+		if g.Hands[player] == 0 {
+			if err := piles.Shuffle(WonPile(name)); err != nil {
+				return nil, err
+			}
+			if err := piles.Move(WonPile(name), name, g.Won[player]); err != nil {
+				return nil, err
+			}
+			g.Hands[player], g.Won[player] = g.Won[player], 0
+		}
+		n := count - len(taken)
+		if n > g.Hands[player] {
+			n = g.Hands[player]
+		}
+		cards, err := piles.Draw(name, n)
+		if err != nil {
+			return nil, err
+		}
+		g.Hands[player] -= n
+		taken = append(taken, cards...)
+	}
+	return taken, nil
+}
+
+func (g *Game) finish(winner string) {
+	g.Finished = true
+	g.Winner = winner
+}
+
+// leader is the player holding more cards, empty when they hold as many
+func (g *Game) leader() string {
+	switch {
+	case g.Count(0) > g.Count(1):
+		return Players[0]
+	case g.Count(1) > g.Count(0):
+		return Players[1]
+	}
+	return ""
+}
+
+// rank orders the cards by value with the ace high, suits don't matter
+func rank(code string) int {
+	return repo.AceHighRank(repo.CardCode(code[:len(code)-1]))
+}
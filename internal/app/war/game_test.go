@@ -0,0 +1,186 @@
+package war
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// table keeps the piles in memory, the deck is the empty pile name. Shuffling keeps the order to make games predictable.
+type table struct {
+	piles    map[string][]string
+	shuffled []string
+}
+
+func newTable(deck string) *table {
+	return &table{piles: map[string][]string{"": strings.Split(deck, ",")}}
+}
+
+func (t *table) Draw(pile string, count int) ([]string, error) {
+	if count > len(t.piles[pile]) {
+		return nil, errors.New("not enough cards")
+	}
+	cards := t.piles[pile][:count]
+	t.piles[pile] = t.piles[pile][count:]
+	return cards, nil
+}
+
+func (t *table) Put(pile string, cards []string) error {
+	t.piles[pile] = append(append([]string{}, cards...), t.piles[pile]...)
+	return nil
+}
+
+func (t *table) Shuffle(pile string) error {
+	t.shuffled = append(t.shuffled, pile)
+	return nil
+}
+
+func (t *table) Move(from, to string, count int) error {
+	cards, err := t.Draw(from, count)
+	if err != nil {
+		return err
+	}
+	return t.Put(to, cards)
+}
+
+func (t *table) Count(pile string) (int, error) {
+	return len(t.piles[pile]), nil
+}
+
+func TestDeal(t *testing.T) {
+	piles := newTable("AS,KS,QS,JS,10S")
+
+	// Test case: the deck is split, an odd card stays in the deck
+	game, err := Deal(piles, 5, 100)
+
+	assert.NoError(t, err)
+	assert.Equal(t, [2]int{2, 2}, game.Hands)
+	assert.Equal(t, []string{"AS", "KS"}, piles.piles["player1"])
+	assert.Equal(t, []string{"QS", "JS"}, piles.piles["player2"])
+
+	_, err = Deal(piles, 5, 0)
+	assert.EqualError(t, err, "max rounds must be positive")
+	_, err = Deal(piles, 1, 10)
+	assert.EqualError(t, err, "war needs at least 2 cards, got 1")
+}
+
+func TestPlayRound(t *testing.T) {
+	piles := newTable("AS,2S,KD,3D")
+	game, _ := Deal(piles, 4, 100)
+
+	// Test case: the higher card wins both, aces are high
+	round, err := game.PlayRound(piles)
+	assert.NoError(t, err)
+	assert.Equal(t, &Round{Number: 1, Cards: [2][]string{{"AS"}, {"KD"}}, Winner: "player1", Won: 2,
+		Counts: [2]int{3, 1}}, round)
+	assert.Equal(t, []string{"AS", "KD"}, piles.piles["player1_won"])
+
+	round, err = game.PlayRound(piles)
+	assert.NoError(t, err)
+	assert.Equal(t, "player2", round.Winner)
+	assert.Equal(t, [2]int{0, 0}, game.Hands)
+
+	// Test case: once a hand is empty the won cards are shuffled into it
+	round, err = game.PlayRound(piles)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"player1_won", "player2_won"}, piles.shuffled)
+	assert.Equal(t, [2][]string{{"AS"}, {"2S"}}, round.Cards)
+	assert.False(t, game.Finished)
+
+	// Test case: the player without cards loses
+	round, err = game.PlayRound(piles)
+	assert.NoError(t, err)
+	assert.True(t, game.Finished)
+	assert.Equal(t, "player1", game.Winner)
+	assert.Equal(t, [2]int{4, 0}, round.Counts)
+	assert.Equal(t, 4, game.Played)
+	_, err = game.PlayRound(piles)
+	assert.Equal(t, ErrGameOver, err)
+}
+
+func TestWar(t *testing.T) {
+	piles := newTable("KS,2S,3S,4S,AS,KD,5D,6D,7D,QD")
+	game, _ := Deal(piles, 10, 100)
+
+	// Test case: a tie starts a war, 3 cards face down and the next one up
+	round, err := game.PlayRound(piles)
+	assert.NoError(t, err)
+	assert.Equal(t, &Round{Number: 1, Cards: [2][]string{{"KS", "AS"}, {"KD", "QD"}}, FaceDown: 6, Wars: 1,
+		Winner: "player1", Won: 10, Counts: [2]int{10, 0}}, round)
+	assert.True(t, game.Finished)
+	assert.Equal(t, "player1", game.Winner)
+
+	// Test case: a player short of cards keeps the last one to turn
+	piles = newTable("")
+	piles.piles["player1"] = []string{"KS", "2S"}
+	piles.piles["player2"] = []string{"KD", "5D", "6D", "7D", "QD"}
+	game = &Game{MaxRounds: 100, Hands: [2]int{2, 5}}
+
+	round, err = game.PlayRound(piles)
+	assert.NoError(t, err)
+	assert.Equal(t, &Round{Number: 1, Cards: [2][]string{{"KS", "2S"}, {"KD", "QD"}}, FaceDown: 3, Wars: 1,
+		Winner: "player2", Won: 7, Counts: [2]int{0, 7}}, round)
+	assert.Equal(t, "player2", game.Winner)
+
+	// Test case: a player who can't turn a card in a war loses
+	piles = newTable("")
+	piles.piles["player1"] = []string{"KS"}
+	piles.piles["player2"] = []string{"KD", "5D"}
+	game = &Game{MaxRounds: 100, Hands: [2]int{1, 2}}
+
+	round, err = game.PlayRound(piles)
+	assert.NoError(t, err)
+	assert.Equal(t, &Round{Number: 1, Cards: [2][]string{{"KS"}, {"KD", "5D"}}, Wars: 1, Winner: "player2", Won: 3,
+		Counts: [2]int{0, 3}}, round)
+	assert.Equal(t, "player2", game.Winner)
+}
+
+func TestMaxRounds(t *testing.T) {
+	piles := newTable("AS,2S,KD,3D")
+	game, _ := Deal(piles, 4, 2)
+
+	// Test case: the game stops at the round cap, both players hold as many cards
+	_, err := game.PlayRound(piles)
+	assert.NoError(t, err)
+	_, err = game.PlayRound(piles)
+	assert.NoError(t, err)
+	assert.True(t, game.Finished)
+	assert.Empty(t, game.Winner)
+	assert.Len(t, game.Rounds, 2)
+}
+
+func TestKeptRounds(t *testing.T) {
+	// the players win a round in turn and get their cards back in the same order, the game never ends
+	piles := newTable("AS,2S,2D,AD")
+	game, _ := Deal(piles, 4, 1000)
+	for game.Played < KeptRounds+10 {
+		_, err := game.PlayRound(piles)
+		assert.NoError(t, err)
+	}
+
+	// Test case: only the last rounds are kept
+	assert.Len(t, game.Rounds, KeptRounds)
+	assert.Equal(t, game.Played, game.Rounds[KeptRounds-1].Number)
+	round, found := game.Round(game.Played)
+	assert.True(t, found)
+	assert.Equal(t, game.Played, round.Number)
+	_, found = game.Round(game.Played - KeptRounds)
+	assert.False(t, found)
+	_, found = game.Round(game.Played + 1)
+	assert.False(t, found)
+}
+
+func TestSync(t *testing.T) {
+	piles := newTable("AS,2S,KD,3D")
+	game, _ := Deal(piles, 4, 100)
+	_, err := game.PlayRound(piles)
+	assert.NoError(t, err)
+
+	// Test case: the counts follow the piles, e.g. after a round whose game wasn't saved
+	stale := &Game{MaxRounds: 100, Hands: [2]int{2, 2}}
+	assert.NoError(t, stale.Sync(piles))
+
+	assert.Equal(t, game.Hands, stale.Hands)
+	assert.Equal(t, game.Won, stale.Won)
+}